@@ -7,12 +7,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // часовые пояса ивентов не зависят от tzdata в образе
 
 	"github.com/UnendingLoop/EventBooker/internal/cleaner"
+	"github.com/UnendingLoop/EventBooker/internal/mailer"
+	"github.com/UnendingLoop/EventBooker/internal/model"
 	"github.com/UnendingLoop/EventBooker/internal/mwauthlog"
+	"github.com/UnendingLoop/EventBooker/internal/notifier"
+	"github.com/UnendingLoop/EventBooker/internal/oidc"
 	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/UnendingLoop/EventBooker/internal/repository/ebmemory"
+	"github.com/UnendingLoop/EventBooker/internal/repository/ebpostgres"
 	"github.com/UnendingLoop/EventBooker/internal/service"
 	"github.com/UnendingLoop/EventBooker/internal/transport"
 	"github.com/wb-go/wbf/config"
@@ -29,48 +37,201 @@ func main() {
 	if err := appConfig.LoadEnvFiles("./.env"); err != nil {
 		log.Fatalf("Failed to load envs: %s\nExiting app...", err)
 	}
+	if err := appConfig.DefineFlag("", "demo", "DEMO", false, "run with in-memory storage instead of PostgresQL"); err != nil {
+		log.Fatalf("Failed to define flags: %s\nExiting app...", err)
+	}
+	if err := appConfig.ParseFlags(); err != nil {
+		log.Fatalf("Failed to parse flags: %s\nExiting app...", err)
+	}
 
 	// готовим заранее слушатель прерываний - контекст для всего приложения
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// подключитсья к базе
-	dbConn := repository.ConnectWithRetries(appConfig, 5, 10*time.Second)
-	// накатываем миграцию
-	repository.MigrateWithRetries(dbConn.Master, "./migrations", 10, 15*time.Second)
+	// repo + менеджер транзакций
+	var dbConn *dbpg.DB
+	var repo repository.EBRepo
+	var txm repository.TxManager
+	if appConfig.GetBool("DEMO") {
+		// демо-режим: все данные в памяти и теряются при остановке приложения
+		log.Println("Demo mode is on: using in-memory storage")
+		repo = ebmemory.NewMemoryRepo()
+		txm = ebmemory.NewStore()
+	} else {
+		// подключитсья к базе
+		dbConn = repository.ConnectWithRetries(appConfig, 5, 10*time.Second)
+		// накатываем миграцию
+		repository.MigrateWithRetries(dbConn.Master, "./migrations", 10, 15*time.Second)
 
-	// repo
-	repo := repository.NewPostgresImageRepo(dbConn)
-	// jwt
-	jwtMngr := mwauthlog.NewJWTManager([]byte(appConfig.GetString("SECRET")), time.Hour, "EventBook app")
+		repo = ebpostgres.NewPostgresRepo()
+		txm = ebpostgres.NewTxManager(dbConn)
+	}
+	// jwt: набор ключей JWT_KEYS позволяет сменить ключ подписи без разлогина; без него токены подписываются SECRET
+	jwtKeys, err := mwauthlog.ParseSigningKeys(appConfig.GetString("JWT_KEYS"))
+	if err != nil {
+		log.Fatalf("Failed to parse JWT keys: %s\nExiting app...", err)
+	}
+	jwtActiveKID := appConfig.GetString("JWT_ACTIVE_KID")
+	if len(jwtKeys) == 0 {
+		jwtKeys = map[string][]byte{"default": []byte(appConfig.GetString("SECRET"))}
+		jwtActiveKID = "default"
+	}
+	jwtMngr, err := mwauthlog.NewJWTManager(jwtKeys, jwtActiveKID, time.Hour, "EventBook app")
+	if err != nil {
+		log.Fatalf("Failed to create JWT manager: %s\nExiting app...", err)
+	}
+	// mailer: без MAIL_FILE письма пишутся в лог
+	var mlr service.Mailer = mailer.NewLogMailer()
+	if path := appConfig.GetString("MAIL_FILE"); path != "" {
+		mlr = mailer.NewFileMailer(path)
+	}
+	// окно лимитов логина: в нем считаются запросы по IP/имейлу и неудачные входы, на него же блокируется аккаунт
+	loginWindow := time.Duration(appConfig.GetInt("LOGIN_LOCKOUT_MINUTES")) * time.Minute
+	loginByIP := mwauthlog.RateLimit(mwauthlog.NewRateLimiter(appConfig.GetInt("LOGIN_IP_LIMIT"), loginWindow), mwauthlog.ClientIPKey)
+	loginByAccount := mwauthlog.RateLimit(mwauthlog.NewRateLimiter(appConfig.GetInt("LOGIN_ACCOUNT_LIMIT"), loginWindow), mwauthlog.JSONFieldKey("email"))
 	// service
-	svc := service.NewEBService(repo, dbConn, jwtMngr)
+	svc := service.NewEBService(repo, txm, jwtMngr, notifier.NewLogNotifier(), mlr, service.Options{
+		SessionTTL:           time.Duration(appConfig.GetInt("SESSION_TTL_DAYS")) * 24 * time.Hour,
+		AppURL:               appConfig.GetString("APP_URL"),
+		RequireVerifiedEmail: appConfig.GetBool("REQUIRE_VERIFIED_EMAIL"),
+		MaxLoginFailures:     appConfig.GetInt("LOGIN_MAX_FAILURES"),
+		LoginLockout:         loginWindow,
+		RequireAdminMFA:      appConfig.GetBool("ADMIN_REQUIRE_2FA"),
+		OIDCRoleClaim:        appConfig.GetString("OIDC_ROLE_CLAIM"),
+		OIDCAdminValues:      splitList(appConfig.GetString("OIDC_ADMIN_VALUES")),
+		OIDCOrganizerValues:  splitList(appConfig.GetString("OIDC_ORGANIZER_VALUES")),
+		BookingCloseBefore:   time.Duration(appConfig.GetInt("BOOKING_CLOSE_MINUTES")) * time.Minute,
+		SeriesHorizon:        time.Duration(appConfig.GetInt("SERIES_HORIZON_DAYS")) * 24 * time.Hour,
+	})
+	// вход через OIDC (SSO) - только если задан провайдер; метаданные провайдера загружаются при первом входе
+	if issuer := appConfig.GetString("OIDC_ISSUER"); issuer != "" {
+		redirectURL := appConfig.GetString("OIDC_REDIRECT_URL")
+		if redirectURL == "" {
+			redirectURL = strings.TrimRight(appConfig.GetString("APP_URL"), "/") + "/auth/oidc/callback"
+		}
+		provider, err := oidc.NewProvider(oidc.Config{
+			Issuer:       issuer,
+			ClientID:     appConfig.GetString("OIDC_CLIENT_ID"),
+			ClientSecret: appConfig.GetString("OIDC_CLIENT_SECRET"),
+			RedirectURL:  redirectURL,
+			Scopes:       strings.Fields(appConfig.GetString("OIDC_SCOPES")),
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure OIDC provider: %s\nExiting app...", err)
+		}
+		svc.SetOIDCProvider(provider)
+	}
+	// одноразовый токен из окружения для регистрации первого админа - действует, пока в системе нет админов
+	if err := svc.BootstrapAdmin(ctx, appConfig.GetString("ADMIN_BOOTSTRAP_TOKEN")); err != nil {
+		log.Fatalf("Failed to bootstrap admin invite: %s\nExiting app...", err)
+	}
+	// планировщик точного истечения броней - подключаем до старта сервера, чтобы не пропустить новые брони
+	sched := cleaner.NewBookScheduler(svc)
+	svc.SetBookScheduler(sched)
+	sched.StartBookScheduler(ctx)
 	// handlers
 	handlers := transport.NewEBHandlers(svc)
 	// конфиг сервера
 	mode := appConfig.GetString("GIN_MODE")
 	engine := ginext.New(mode)
+	// по умолчанию gin доверяет X-Forwarded-For от любого клиента, и лимит логина по IP обходится подменой заголовка;
+	// заголовку верим только от перечисленных прокси, без них IP клиента - адрес соединения
+	if err := engine.SetTrustedProxies(splitList(appConfig.GetString("TRUSTED_PROXIES"))); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %s\nExiting app...", err)
+	}
 	engine.Use(
 		mwauthlog.RequestID()) // вставка уникального UID в каждый реквест
 
-	events := engine.Group("/events", mwauthlog.RequireAuth([]byte(appConfig.GetString("SECRET"))))
-	books := engine.Group("/bookings", mwauthlog.RequireAuth([]byte(appConfig.GetString("SECRET"))))
+	requireAuth := mwauthlog.RequireAuth(jwtMngr, svc, nil)   // подпись токена + проверка, что сессия не отозвана
+	requireClient := mwauthlog.RequireAuth(jwtMngr, svc, svc) // то же или API-ключ в заголовке Authorization: Bearer
+	adminMFA := mwauthlog.RequireMFA()
+	if appConfig.GetBool("ADMIN_REQUIRE_2FA") {
+		adminMFA = mwauthlog.RequireMFA(model.RoleAdmin) // админ управляет чем-либо только из сессии, прошедшей 2FA
+	}
+	// права ролей; что организатор управляет именно своим ивентом, проверяет сервис
+	createEvents := mwauthlog.RequirePermission(model.PermEventsCreate)
+	manageEvents := mwauthlog.RequirePermission(model.PermEventsManage)
+	events := engine.Group("/events", requireClient)
+	books := engine.Group("/bookings", requireClient)
+	apiKeys := engine.Group("/api-keys", requireAuth) // ключами управляют только из сессии, не другим ключом
+	invites := engine.Group("/invites", requireAuth, mwauthlog.RequirePermission(model.PermInvitesCreate), adminMFA)
+	admin := engine.Group("/admin", requireAuth, mwauthlog.RequirePermission(model.PermUsersManage), adminMFA)
+	orgs := engine.Group("/orgs", requireAuth) // организации и участники - только из сессии
+	categories := engine.Group("/categories", requireClient)
+	series := engine.Group("/series", requireClient)
 	auth := engine.Group("/auth")
 
 	engine.GET("/ping", handlers.SimplePinger)
 	engine.Static("/ui", "./internal/web") // UI админа/юзера - функциональность и контент зависит от роли
 
-	auth.POST("/signup", handlers.SignUpUser) // регистрация пользователя
-	auth.POST("/login", handlers.LoginUser)   // авторизация
+	auth.POST("/signup", handlers.SignUpUser)                                   // регистрация пользователя
+	auth.POST("/login", loginByIP, loginByAccount, handlers.LoginUser)          // авторизация с лимитами по IP и по имейлу
+	auth.POST("/refresh", handlers.RefreshSession)                              // новая пара токенов по refresh-токену
+	auth.POST("/switch-org", handlers.SwitchOrganization)                       // перевод сессии в другую организацию пользователя
+	auth.POST("/logout", handlers.Logout)                                       // выход: отзыв текущей сессии
+	auth.POST("/logout-all", requireAuth, handlers.LogoutAll)                   // выход на всех устройствах
+	auth.POST("/verify-email", handlers.VerifyEmail)                            // подтверждение имейла по токену из письма
+	auth.POST("/verify-email/resend", requireAuth, handlers.ResendVerification) // повторное письмо подтверждения
+	auth.POST("/password/forgot", handlers.ForgotPassword)                      // письмо со ссылкой сброса пароля
+	auth.POST("/password/reset", handlers.ResetPassword)                        // новый пароль по токену из письма
+	auth.POST("/2fa/verify", loginByIP, handlers.VerifyMFA)                     // второй шаг входа: код 2FA или код восстановления
+	auth.POST("/2fa/setup", requireAuth, handlers.SetupTOTP)                    // новый секрет TOTP и otpauth-ссылка
+	auth.POST("/2fa/enable", requireAuth, handlers.EnableTOTP)                  // включение 2FA первым кодом, выдача кодов восстановления
+	auth.POST("/2fa/disable", requireAuth, loginByIP, handlers.DisableTOTP)     // выключение 2FA по коду
+	auth.GET("/oidc/login", loginByIP, handlers.OIDCLogin)                      // SSO: редирект к провайдеру OIDC
+	auth.GET("/oidc/callback", loginByIP, handlers.OIDCCallback)                // SSO: возврат от провайдера, вход или создание пользователя
+
+	// scopes ограничивают только запросы по API-ключу, сессии они не касаются
+	eventsRead := mwauthlog.RequireScope(model.ScopeEventsRead)
+	eventsWrite := mwauthlog.RequireScope(model.ScopeEventsWrite)
+	booksRead := mwauthlog.RequireScope(model.ScopeBookingsRead)
+	booksWrite := mwauthlog.RequireScope(model.ScopeBookingsWrite)
+
+	events.POST("", eventsWrite, createEvents, adminMFA, handlers.CreateEvent)                                   // создание ивента - админ или организатор, создатель становится владельцем
+	events.GET("", eventsRead, handlers.GetEvents)                                                               // список ивентов; организатору - ещё и свои неактуальные
+	events.GET("/search", eventsRead, handlers.SearchEvents)                                                     // полнотекстовый поиск по названию и описанию с ранжированием
+	events.GET("/:id", eventsRead, handlers.GetEvent)                                                            // ивент со статистикой броней; тем, кто им управляет, - с бронями и соорганизаторами
+	events.PATCH("/:id", eventsWrite, manageEvents, adminMFA, handlers.UpdateEvent)                              // изменение ивента - админ, владелец или соорганизатор; ?scope=future - и следующих вхождений его серии
+	events.POST("/:id/cancel", eventsWrite, manageEvents, adminMFA, handlers.CancelEvent)                        // отмена ивента с отменой всех броней - админ, владелец или соорганизатор
+	events.DELETE("/:id", eventsWrite, manageEvents, adminMFA, handlers.DeleteEvent)                             // удаление ивента - админ или владелец
+	events.POST("/:id/organizers", eventsWrite, manageEvents, adminMFA, handlers.AddEventOrganizer)              // назначение соорганизатора - админ или владелец
+	events.DELETE("/:id/organizers/:userId", eventsWrite, manageEvents, adminMFA, handlers.RemoveEventOrganizer) // снятие соорганизатора - админ, владелец или он сам
+	events.POST("/:id/waitlist", booksWrite, handlers.JoinWaitlist)                                              // встать в очередь ожидания на распроданный ивент
+	events.GET("/:id/waitlist", booksRead, handlers.GetWaitlistPosition)                                         // своя позиция в очереди ожидания
+	events.DELETE("/:id/waitlist", booksWrite, handlers.LeaveWaitlist)                                           // выйти из очереди ожидания
+
+	series.POST("", eventsWrite, createEvents, adminMFA, handlers.CreateSeries) // повторяющийся ивент по правилу RRULE, вхождения создаются заранее
+	series.GET("/:id", eventsRead, handlers.GetSeries)                          // серия с вхождениями; отмена одного вхождения - POST /events/:id/cancel
+
+	editCategories := mwauthlog.RequirePermission(model.PermCategoriesEdit)
+	categories.GET("", eventsRead, handlers.GetCategories)                                    // категории организации для навигации по ивентам
+	categories.POST("", eventsWrite, editCategories, adminMFA, handlers.CreateCategory)       // новая категория - только админ
+	categories.PATCH("/:id", eventsWrite, editCategories, adminMFA, handlers.UpdateCategory)  // переименование категории - только админ
+	categories.DELETE("/:id", eventsWrite, editCategories, adminMFA, handlers.DeleteCategory) // удаление категории, ивенты остаются без неё - только админ
+
+	invites.POST("", handlers.CreateInvite) // приглашение на регистрацию с ролью - только админ
+
+	admin.GET("/users", handlers.GetUsers)                  // постраничный поиск участников текущей организации: ?q=&limit=&offset=
+	admin.GET("/users/:id", handlers.GetUser)               // пользователь с историей броней
+	admin.PATCH("/users/:id/role", handlers.ChangeUserRole) // смена роли в организации, сессии пользователя в ней отзываются
+	admin.POST("/users/:id/disable", handlers.DisableUser)  // блокировка аккаунта с отзывом сессий
+	admin.POST("/users/:id/enable", handlers.EnableUser)    // разблокировка аккаунта
+	admin.DELETE("/users/:id", handlers.DeleteUser)         // удаление с возвратом мест активных броней
 
-	events.POST("", mwauthlog.RequireRole("admin"), handlers.CreateEvent)       // создание ивента - только админ
-	events.GET("", handlers.GetEvents)                                          // список всех ивентов
-	events.DELETE("/:id", mwauthlog.RequireRole("admin"), handlers.DeleteEvent) // удаление ивента - только админ
+	orgs.GET("", handlers.GetOrganizations)                                                                                 // свои организации с ролью в каждой, текущая помечена
+	orgs.POST("", mwauthlog.RequirePermission(model.PermOrgsCreate), adminMFA, handlers.CreateOrganization)                 // новая организация, создатель - её админ
+	orgs.POST("/join", handlers.JoinOrganization)                                                                           // вступление в организацию по приглашению
+	orgs.POST("/members", mwauthlog.RequirePermission(model.PermUsersManage), adminMFA, handlers.InviteOrgMember)           // приглашение в текущую организацию по имейлу, принимает сам пользователь
+	orgs.DELETE("/members/:userId", mwauthlog.RequirePermission(model.PermUsersManage), adminMFA, handlers.RemoveOrgMember) // исключение из текущей организации
 
-	books.POST("", handlers.BookEvent)               // создание бронирования
-	books.POST("/:id/confirm", handlers.ConfirmBook) // подтверждение бронирования
-	books.GET("/my", handlers.GetUserBooks)          // все брони по одному пользователю
-	books.DELETE("/:id", handlers.CancelBook)        // отмена брони
+	books.POST("", booksWrite, handlers.BookEvent)               // создание бронирования
+	books.POST("/:id/confirm", booksWrite, handlers.ConfirmBook) // подтверждение бронирования
+	books.GET("/my", booksRead, handlers.GetUserBooks)           // все брони по одному пользователю
+	books.DELETE("/:id", booksWrite, handlers.CancelBook)        // отмена брони
+
+	apiKeys.POST("", handlers.CreateAPIKey)       // новый ключ со scopes, сам ключ показывается один раз
+	apiKeys.GET("", handlers.GetAPIKeys)          // свои ключи с датами последнего использования
+	apiKeys.DELETE("/:id", handlers.RevokeAPIKey) // отзыв ключа
 
 	srv := &http.Server{
 		Addr:    ":" + appConfig.GetString("APP_PORT"),
@@ -92,9 +253,18 @@ func main() {
 		}
 	}()
 
-	// cleaner
+	// cleaner - страховка для BookScheduler, поэтому редкий полный проход
 	clb := cleaner.NewBookCleaner(svc)
-	clb.StartBookCleaner(ctx, 30)
+	clb.StartBookCleaner(ctx, 300)
+	// purger - окончательное удаление старых истекших/отмененных броней
+	bpr := cleaner.NewBookPurger(svc, time.Duration(appConfig.GetInt("BOOKS_RETENTION_DAYS"))*24*time.Hour)
+	bpr.StartBookPurger(ctx, 3600)
+	// sweeper
+	evs := cleaner.NewEventSweeper(svc)
+	evs.StartEventSweeper(ctx, 60)
+	// planner - вхождения серий ивентов на горизонт SERIES_HORIZON_DAYS
+	spl := cleaner.NewSeriesPlanner(svc)
+	spl.StartSeriesPlanner(ctx, 3600)
 
 	// слушаем контекст прерываний для запуска Graceful Shutdown
 	<-ctx.Done()
@@ -114,9 +284,23 @@ func shutdown(dbConn *dbpg.DB, srv *http.Server) {
 	}
 
 	// Closing DB connection
+	if dbConn == nil { // демо-режим - закрывать нечего
+		return
+	}
 	if err := dbConn.Master.Close(); err != nil {
 		log.Println("Failed to close DB-conn correctly:", err)
 	} else {
 		log.Println("DBconn is closed.")
 	}
 }
+
+// splitList - список значений из конфига через запятую
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+// Command oidcstub runs a minimal OpenID Connect provider for local development and testing of SSO login.
+// It signs in anyone who submits its login form: the email, verification flag and groups are taken from the form.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID   = "stub-1"
+	codeTTL = time.Minute
+	idTTL   = 5 * time.Minute
+)
+
+type stub struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*grant
+}
+
+// grant - выданный код авторизации, ждущий обмена на токены
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      jwt.MapClaims
+	expiresAt   time.Time
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>OIDC stub login</title></head>
+<body>
+<h2>OIDC stub: sign in as</h2>
+<form method="post" action="authorize">
+{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<p><input name="email" placeholder="email" required></p>
+<p><input name="name" placeholder="given name"> <input name="surname" placeholder="family name"></p>
+<p><input name="groups" placeholder="groups, comma separated"></p>
+<p><label><input type="checkbox" name="email_verified" value="true" checked> email verified</label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body></html>`))
+
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer URL, must match OIDC_ISSUER of the app")
+	clientID := flag.String("client-id", "eventbooker", "accepted client id")
+	clientSecret := flag.String("client-secret", "", "client secret; empty - public client with PKCE only")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+	s := &stub{issuer: strings.TrimRight(*issuer, "/"), clientID: *clientID, clientSecret: *clientSecret, key: key, codes: make(map[string]*grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /authorize", s.authorizeForm)
+	mux.HandleFunc("POST /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+
+	log.Printf("OIDC stub running on %s with issuer %s and client id %q", *addr, s.issuer, s.clientID)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Fatalf("OIDC stub stopped: %v", err)
+	}
+}
+
+func (s *stub) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *stub) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": keyID,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+// authorizeForm проверяет запрос клиента и показывает форму входа, передавая параметры запроса дальше скрытыми полями
+func (s *stub) authorizeForm(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if msg := s.checkAuthRequest(q); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	params := map[string]string{}
+	for _, k := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		params[k] = q.Get(k)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := loginPage.Execute(w, params); err != nil {
+		log.Printf("Failed to render login page: %v", err)
+	}
+}
+
+// authorize выдает код авторизации для пользователя из формы и возвращает браузер клиенту
+func (s *stub) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	f := r.PostForm
+	f.Set("response_type", "code")
+	f.Set("code_challenge_method", "S256")
+	if msg := s.checkAuthRequest(f); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(f.Get("email")))
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+
+	// sub постоянен для имейла - повторный вход попадает в ту же учетную запись
+	sum := sha256.Sum256([]byte(email))
+	claims := jwt.MapClaims{
+		"sub":            "stub-" + hex.EncodeToString(sum[:8]),
+		"email":          email,
+		"email_verified": f.Get("email_verified") == "true",
+	}
+	if v := strings.TrimSpace(f.Get("name")); v != "" {
+		claims["given_name"] = v
+	}
+	if v := strings.TrimSpace(f.Get("surname")); v != "" {
+		claims["family_name"] = v
+	}
+	var groups []string
+	for _, g := range strings.Split(f.Get("groups"), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	if len(groups) > 0 {
+		claims["groups"] = groups
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = &grant{
+		redirectURI: f.Get("redirect_uri"),
+		challenge:   f.Get("code_challenge"),
+		nonce:       f.Get("nonce"),
+		claims:      claims,
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	back, _ := url.Parse(f.Get("redirect_uri"))
+	q := back.Query()
+	q.Set("code", code)
+	q.Set("state", f.Get("state"))
+	back.RawQuery = q.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token обменивает одноразовый код на ID-токен, проверяя клиента, redirect_uri и PKCE verifier
+func (s *stub) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	f := r.PostForm
+
+	clientID, secret, hasBasic := r.BasicAuth()
+	if hasBasic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = f.Get("client_id"), f.Get("client_secret")
+	}
+	if clientID != s.clientID || subtle.ConstantTimeCompare([]byte(secret), []byte(s.clientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if f.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.codes[f.Get("code")]
+	delete(s.codes, f.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(f.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(g.expiresAt):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown or expired code"})
+		return
+	case g.redirectURI != f.Get("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": s.issuer,
+		"aud": s.clientID,
+		"iat": now.Unix(),
+		"exp": now.Add(idTTL).Unix(),
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+// checkAuthRequest - пустая строка, если запрос авторизации корректен
+func (s *stub) checkAuthRequest(q url.Values) string {
+	switch {
+	case q.Get("response_type") != "code":
+		return "only response_type=code is supported"
+	case q.Get("client_id") != s.clientID:
+		return "unknown client_id"
+	case q.Get("redirect_uri") == "":
+		return "redirect_uri is required"
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		return "PKCE with code_challenge_method=S256 is required"
+	}
+	if _, err := url.Parse(q.Get("redirect_uri")); err != nil {
+		return "invalid redirect_uri"
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("Failed to read random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
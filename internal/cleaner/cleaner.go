@@ -1,4 +1,4 @@
-// Package cleaner provides a struct BookCleaner with only method StartBookCleaner to periodically check and cancel expired bookings in DB
+// Package cleaner provides background workers: BookScheduler and BookCleaner expire unconfirmed bookings, EventSweeper marks past events as expired, BookPurger removes old bookings from DB
 package cleaner
 
 import (
@@ -0,0 +1,58 @@
+package cleaner
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// BookPurger периодически окончательно удаляет из БД истекшие и отмененные брони старше срока хранения
+type BookPurger struct {
+	psvc      PurgerService
+	retention time.Duration
+}
+
+type PurgerService interface {
+	PurgeOldBooks(ctx context.Context, retention time.Duration) error
+}
+
+func NewBookPurger(svc PurgerService, retention time.Duration) *BookPurger {
+	if retention <= 0 {
+		log.Println("Invalid retention provided for BookPurger. Using default value: 90 days")
+		retention = 90 * 24 * time.Hour
+	}
+	return &BookPurger{psvc: svc, retention: retention}
+}
+
+func (bp *BookPurger) StartBookPurger(ctx context.Context, interval int) {
+	if interval <= 0 {
+		log.Println("Invalid interval provided for running BookPurger. Using default value: 3600 seconds")
+		interval = 3600
+	}
+	tckr := time.NewTicker(time.Duration(interval) * time.Second)
+
+	go func() {
+		defer tckr.Stop()
+		for {
+			select {
+			case <-tckr.C:
+				bp.runOnce()
+			case <-ctx.Done():
+				log.Println("BookPurger ctx is cancelled. Finishing work...")
+				return
+			}
+		}
+	}()
+
+	log.Printf("BookPurger started working with retention %v...", bp.retention)
+}
+
+func (bp *BookPurger) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := bp.psvc.PurgeOldBooks(ctx, bp.retention)
+	if err != nil {
+		log.Printf("Failed to purge old bookings: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package cleaner
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+// BookScheduler истекает неподтвержденные брони ровно в момент их дедлайна: держит min-heap дедлайнов
+// и один таймер на ближайший из них. BookCleaner при этом остается страховкой на случай сбоев.
+type BookScheduler struct {
+	bsvc SchedulerService
+
+	mu    sync.Mutex
+	queue deadlineQueue
+	wake  chan struct{} // сигнал циклу, что в очереди появился более ранний дедлайн
+}
+
+type SchedulerService interface {
+	ExpireBook(ctx context.Context, orgID int, bid int) error
+	GetPendingBooks(ctx context.Context) ([]*model.Book, error)
+}
+
+type deadlineItem struct {
+	orgID    int // бронь ищется в своей организации
+	bookID   int
+	deadline time.Time
+}
+
+type deadlineQueue []deadlineItem
+
+func (q deadlineQueue) Len() int           { return len(q) }
+func (q deadlineQueue) Less(i, j int) bool { return q[i].deadline.Before(q[j].deadline) }
+func (q deadlineQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *deadlineQueue) Push(x any)        { *q = append(*q, x.(deadlineItem)) }
+func (q *deadlineQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+func NewBookScheduler(svc SchedulerService) *BookScheduler {
+	return &BookScheduler{bsvc: svc, wake: make(chan struct{}, 1)}
+}
+
+// Schedule ставит бронь организации orgID в очередь на истечение; безопасен для вызова из любых горутин
+func (bs *BookScheduler) Schedule(orgID int, bid int, deadline time.Time) {
+	bs.mu.Lock()
+	heap.Push(&bs.queue, deadlineItem{orgID: orgID, bookID: bid, deadline: deadline})
+	bs.mu.Unlock()
+
+	select {
+	case bs.wake <- struct{}{}:
+	default: // цикл уже разбужен
+	}
+}
+
+// StartBookScheduler восстанавливает очередь из неподтвержденных броней в БД и запускает цикл истечения
+func (bs *BookScheduler) StartBookScheduler(ctx context.Context) {
+	bs.rehydrate(ctx)
+
+	go func() {
+		timer := time.NewTimer(time.Hour)
+		defer timer.Stop()
+		for {
+			bs.expireDue()
+
+			if next, ok := bs.next(); ok {
+				timer.Reset(time.Until(next))
+			} else {
+				timer.Stop()
+			}
+
+			select {
+			case <-timer.C:
+			case <-bs.wake:
+			case <-ctx.Done():
+				log.Println("BookScheduler ctx is cancelled. Finishing work...")
+				return
+			}
+		}
+	}()
+
+	log.Println("BookScheduler started working...")
+}
+
+func (bs *BookScheduler) rehydrate(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	books, err := bs.bsvc.GetPendingBooks(ctx)
+	if err != nil {
+		log.Printf("Failed to load pending bookings into BookScheduler: %v", err)
+		return
+	}
+	for _, b := range books {
+		if b.ConfirmDeadline != nil {
+			bs.Schedule(b.OrgID, b.ID, *b.ConfirmDeadline)
+		}
+	}
+	log.Printf("BookScheduler loaded %d pending bookings", len(books))
+}
+
+func (bs *BookScheduler) next() (time.Time, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.queue.Len() == 0 {
+		return time.Time{}, false
+	}
+	return bs.queue[0].deadline, true
+}
+
+// expireDue достает из очереди все наступившие дедлайны и истекает соответствующие брони
+func (bs *BookScheduler) expireDue() {
+	now := time.Now()
+	due := make([]deadlineItem, 0)
+
+	bs.mu.Lock()
+	for bs.queue.Len() > 0 && !bs.queue[0].deadline.After(now) {
+		due = append(due, heap.Pop(&bs.queue).(deadlineItem))
+	}
+	bs.mu.Unlock()
+
+	for _, item := range due {
+		bs.runOnce(item)
+	}
+}
+
+func (bs *BookScheduler) runOnce(item deadlineItem) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := bs.bsvc.ExpireBook(ctx, item.orgID, item.bookID)
+	if err != nil {
+		log.Printf("Failed to expire booking %d: %v", item.bookID, err)
+	}
+}
@@ -0,0 +1,55 @@
+package cleaner
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// SeriesPlanner периодически создает вхождения повторяющихся ивентов на горизонт планирования
+type SeriesPlanner struct {
+	esvc SeriesPlannerService
+}
+
+type SeriesPlannerService interface {
+	MaterializeSeries(ctx context.Context) error
+}
+
+func NewSeriesPlanner(svc SeriesPlannerService) *SeriesPlanner {
+	return &SeriesPlanner{esvc: svc}
+}
+
+func (sp *SeriesPlanner) StartSeriesPlanner(ctx context.Context, interval int) {
+	if interval <= 0 {
+		log.Println("Invalid interval provided for running SeriesPlanner. Using default value: 3600 seconds")
+		interval = 3600
+	}
+	tckr := time.NewTicker(time.Duration(interval) * time.Second)
+
+	go func() {
+		defer tckr.Stop()
+		// горизонт сдвинулся, пока приложение было остановлено - не ждем первого тика
+		sp.runOnce()
+		for {
+			select {
+			case <-tckr.C:
+				sp.runOnce()
+			case <-ctx.Done():
+				log.Println("SeriesPlanner ctx is cancelled. Finishing work...")
+				return
+			}
+		}
+	}()
+
+	log.Println("SeriesPlanner started working...")
+}
+
+func (sp *SeriesPlanner) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := sp.esvc.MaterializeSeries(ctx)
+	if err != nil {
+		log.Printf("Failed to materialize event series: %v", err)
+	}
+}
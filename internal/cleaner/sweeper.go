@@ -0,0 +1,55 @@
+package cleaner
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// EventSweeper периодически переводит наступившие ивенты в статус expired
+type EventSweeper struct {
+	esvc SweeperService
+}
+
+type SweeperService interface {
+	ExpirePastEvents(ctx context.Context) error
+}
+
+func NewEventSweeper(svc SweeperService) *EventSweeper {
+	return &EventSweeper{esvc: svc}
+}
+
+func (es *EventSweeper) StartEventSweeper(ctx context.Context, interval int) {
+	if interval <= 0 {
+		log.Println("Invalid interval provided for running EventSweeper. Using default value: 60 seconds")
+		interval = 60
+	}
+	tckr := time.NewTicker(time.Duration(interval) * time.Second)
+
+	go func() {
+		defer tckr.Stop()
+		// ивенты могли наступить, пока приложение было остановлено - не ждем первого тика
+		es.runOnce()
+		for {
+			select {
+			case <-tckr.C:
+				es.runOnce()
+			case <-ctx.Done():
+				log.Println("EventSweeper ctx is cancelled. Finishing work...")
+				return
+			}
+		}
+	}()
+
+	log.Println("EventSweeper started working...")
+}
+
+func (es *EventSweeper) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := es.esvc.ExpirePastEvents(ctx)
+	if err != nil {
+		log.Printf("Failed to expire past events: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+// Package mailer provides Mailer implementations for local use: LogMailer writes mails to the app log,
+// FileMailer appends them to a file. Both serve as stubs until an SMTP/API mailer is plugged in
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (lm *LogMailer) Send(ctx context.Context, m *model.Mail) error {
+	log.Printf("RID %q Mail to %q: %s - %s", model.RequestIDFromCtx(ctx), m.To, m.Subject, m.Text)
+	return nil
+}
+
+// FileMailer дописывает письма в файл - удобно, чтобы забирать ссылки из писем при локальной разработке
+type FileMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+func (fm *FileMailer) Send(ctx context.Context, m *model.Mail) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	f, err := os.OpenFile(fm.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC1123Z), m.To, m.Subject, m.Text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
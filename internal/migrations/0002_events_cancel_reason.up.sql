@@ -0,0 +1,2 @@
+ALTER TABLE events
+ADD COLUMN IF NOT EXISTS cancel_reason TEXT NOT NULL DEFAULT '';
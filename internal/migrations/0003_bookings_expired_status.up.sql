@@ -0,0 +1,14 @@
+ALTER TABLE bookings
+DROP CONSTRAINT IF EXISTS bookings_status_check;
+
+ALTER TABLE bookings
+ADD CONSTRAINT bookings_status_check CHECK (
+    status IN (
+        'created',
+        'confirmed',
+        'cancelled',
+        'expired'
+    )
+);
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS expired_at TIMESTAMPTZ;
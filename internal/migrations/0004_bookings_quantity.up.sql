@@ -0,0 +1,5 @@
+ALTER TABLE bookings
+ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0);
+
+ALTER TABLE events
+ADD COLUMN IF NOT EXISTS max_per_book INT NOT NULL DEFAULT 1 CHECK (max_per_book > 0);
@@ -0,0 +1,14 @@
+-- Сессии пользователей: refresh-токен хранится только в виде SHA-256 хэша
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    token_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    CONSTRAINT fk_sessions_users FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_sessions_token_hash ON sessions (token_hash);
+
+CREATE INDEX idx_sessions_user ON sessions (user_id);
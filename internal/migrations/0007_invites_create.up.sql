@@ -0,0 +1,16 @@
+-- Приглашения на регистрацию с повышенной ролью: одноразовые, с ограниченным сроком жизни; токен хранится только в виде SHA-256 хэша
+CREATE TABLE IF NOT EXISTS invites (
+    id SERIAL PRIMARY KEY,
+    token_hash TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'user')),
+    email TEXT NOT NULL DEFAULT '', -- пустой - приглашение не привязано к имейлу
+    created_by INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_by INT,
+    used_at TIMESTAMPTZ,
+    CONSTRAINT fk_invites_created_by FOREIGN KEY (created_by) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_invites_used_by FOREIGN KEY (used_by) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX idx_invites_token_hash ON invites (token_hash);
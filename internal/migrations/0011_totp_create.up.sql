@@ -0,0 +1,19 @@
+-- TOTP 2FA: секрет выставляется при настройке, totp_enabled_at - после подтверждения первым кодом;
+-- totp_last_step - шаг последнего принятого кода, чтобы один код нельзя было использовать дважды
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- сессия, открытая после проверки второго фактора
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT false;
+
+-- Одноразовые коды восстановления на случай потери аутентификатора; хранится только SHA-256 хэш
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    CONSTRAINT fk_recovery_codes_users FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_recovery_codes_user ON recovery_codes (user_id);
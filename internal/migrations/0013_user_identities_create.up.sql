@@ -0,0 +1,14 @@
+-- Учетные записи внешних провайдеров входа (OIDC): пользователь находится по паре издатель + sub
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_user_identities_users FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_user_identities_subject ON user_identities (issuer, subject);
+
+CREATE INDEX idx_user_identities_user ON user_identities (user_id);
@@ -0,0 +1,24 @@
+-- Роль организатора: создает ивенты и управляет своими
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE users
+ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'organizer', 'user'));
+
+-- Владелец ивента; ивенты удаленного пользователя остаются под управлением админов и соорганизаторов
+ALTER TABLE events
+ADD COLUMN IF NOT EXISTS owner_id INT,
+ADD CONSTRAINT fk_events_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX idx_events_owner ON events (owner_id);
+
+-- Соорганизаторы ивента
+CREATE TABLE IF NOT EXISTS event_organizers (
+    event_id INT NOT NULL,
+    user_id INT NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_id, user_id),
+    CONSTRAINT fk_event_organizers_events FOREIGN KEY (event_id) REFERENCES events (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_event_organizers_users FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_event_organizers_user ON event_organizers (user_id);
@@ -0,0 +1,5 @@
+-- Приглашения с ролью организатора
+ALTER TABLE invites DROP CONSTRAINT IF EXISTS invites_role_check;
+
+ALTER TABLE invites
+ADD CONSTRAINT invites_role_check CHECK (role IN ('admin', 'organizer', 'user'));
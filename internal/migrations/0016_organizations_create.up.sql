@@ -0,0 +1,70 @@
+-- Организации (площадки): у каждой свои ивенты, брони, приглашения и участники
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_organizations_slug ON organizations (slug);
+
+-- Организация по умолчанию: в неё переезжают все существующие данные и регистрируются пользователи без указания организации
+INSERT INTO organizations (name, slug) VALUES ('Default', 'default');
+
+-- Участники организаций; роль теперь своя в каждой организации
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'organizer', 'user')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id),
+    CONSTRAINT fk_org_members_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_org_members_users FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_org_members_user ON org_members (user_id);
+
+INSERT INTO org_members (org_id, user_id, role, created_at)
+SELECT o.id, u.id, u.role, u.created_at
+FROM users u
+CROSS JOIN organizations o
+WHERE o.slug = 'default';
+
+ALTER TABLE users DROP COLUMN role;
+
+-- Данные организаций
+ALTER TABLE events ADD COLUMN IF NOT EXISTS org_id INT;
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS org_id INT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS org_id INT;
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS org_id INT;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS org_id INT;
+
+UPDATE events SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+UPDATE bookings SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+UPDATE sessions SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+UPDATE invites SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+UPDATE api_keys SET org_id = (SELECT id FROM organizations WHERE slug = 'default');
+
+ALTER TABLE events
+ALTER COLUMN org_id SET NOT NULL,
+ADD CONSTRAINT fk_events_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE bookings
+ALTER COLUMN org_id SET NOT NULL,
+ADD CONSTRAINT fk_bookings_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE sessions
+ALTER COLUMN org_id SET NOT NULL,
+ADD CONSTRAINT fk_sessions_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE invites
+ALTER COLUMN org_id SET NOT NULL,
+ADD CONSTRAINT fk_invites_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE api_keys
+ALTER COLUMN org_id SET NOT NULL,
+ADD CONSTRAINT fk_api_keys_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE;
+
+CREATE INDEX idx_events_org ON events (org_id);
+
+CREATE INDEX idx_bookings_org_user ON bookings (org_id, user_id);
@@ -0,0 +1,6 @@
+-- Keyset-пагинация списков: сортировки по дате ивента и по порядку создания идут по индексу
+CREATE INDEX idx_events_org_date ON events (org_id, event_date, id);
+
+CREATE INDEX idx_bookings_org_user_id ON bookings (org_id, user_id, id);
+
+DROP INDEX IF EXISTS idx_bookings_org_user;
@@ -0,0 +1,10 @@
+-- Полнотекстовый поиск ивентов. Конфигурация russian стеммит кириллицу русским словарем,
+-- а латиницу - английским, поэтому одна колонка покрывает оба языка контента.
+-- Совпадения в названии (вес A) ранжируются выше совпадений в описании (вес B)
+ALTER TABLE events ADD COLUMN IF NOT EXISTS search_vector tsvector
+GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('russian', COALESCE(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_events_search ON events USING GIN (search_vector);
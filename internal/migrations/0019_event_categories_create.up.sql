@@ -0,0 +1,38 @@
+-- Метаданные ивента для посетителей: площадка, контакт организатора и обложка
+ALTER TABLE events
+ADD COLUMN IF NOT EXISTS venue_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS venue_address TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS contact TEXT NOT NULL DEFAULT '',
+ADD COLUMN IF NOT EXISTS cover_url TEXT NOT NULL DEFAULT '';
+
+-- Категории ведут админы организации; ивент может входить в несколько категорий
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    org_id INT NOT NULL,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_categories_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_categories_org_slug ON categories (org_id, slug);
+
+CREATE TABLE IF NOT EXISTS event_categories (
+    event_id INT NOT NULL,
+    category_id INT NOT NULL,
+    PRIMARY KEY (event_id, category_id),
+    CONSTRAINT fk_event_categories_events FOREIGN KEY (event_id) REFERENCES events (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_event_categories_categories FOREIGN KEY (category_id) REFERENCES categories (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_event_categories_category ON event_categories (category_id);
+
+-- Теги - свободные метки, которые задает тот, кто управляет ивентом
+CREATE TABLE IF NOT EXISTS event_tags (
+    event_id INT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (event_id, tag),
+    CONSTRAINT fk_event_tags_events FOREIGN KEY (event_id) REFERENCES events (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX idx_event_tags_tag ON event_tags (tag);
@@ -0,0 +1,9 @@
+-- Время ивента: event_date становится моментом начала, появляются необязательное окончание
+-- и часовой пояс IANA, в котором ивент показывается и в котором задается время без смещения.
+-- У существующих ивентов начало остается прежним - конец дня их даты по UTC
+ALTER TABLE events RENAME COLUMN event_date TO starts_at;
+
+ALTER TABLE events
+ADD COLUMN IF NOT EXISTS ends_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC',
+ADD CONSTRAINT events_ends_after_start CHECK (ends_at IS NULL OR ends_at > starts_at);
@@ -0,0 +1,36 @@
+-- Серии повторяющихся ивентов: шаблон ивента и правило повторения (подмножество RRULE).
+-- Вхождения - обычные ивенты с series_id, их заранее создает планировщик; generated_until - начало
+-- последнего созданного вхождения, поэтому отмененные и удаленные вхождения не создаются заново
+CREATE TABLE IF NOT EXISTS event_series (
+    id SERIAL PRIMARY KEY,
+    org_id INT NOT NULL,
+    owner_id INT,
+    rrule TEXT NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    bookwindow INT NOT NULL,
+    total_seats INT NOT NULL,
+    max_per_book INT NOT NULL,
+    venue_name TEXT NOT NULL DEFAULT '',
+    venue_address TEXT NOT NULL DEFAULT '',
+    contact TEXT NOT NULL DEFAULT '',
+    cover_url TEXT NOT NULL DEFAULT '',
+    categories TEXT[] NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    generated_until TIMESTAMPTZ,
+    finished BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT fk_event_series_organizations FOREIGN KEY (org_id) REFERENCES organizations (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_event_series_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX idx_event_series_pending ON event_series (generated_until) WHERE NOT finished;
+
+ALTER TABLE events
+ADD COLUMN IF NOT EXISTS series_id INT,
+ADD CONSTRAINT fk_events_series FOREIGN KEY (series_id) REFERENCES event_series (id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX idx_events_series ON events (series_id, starts_at);
@@ -0,0 +1,4 @@
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
+-- время отмены старых броней неизвестно: срок хранения для них отсчитывается от миграции
+UPDATE bookings SET cancelled_at = now() WHERE status = 'cancelled' AND cancelled_at IS NULL;
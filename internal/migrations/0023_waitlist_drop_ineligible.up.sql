@@ -0,0 +1,11 @@
+-- заблокированные пользователи и бывшие участники организаций раньше оставались в очередях ожидания
+DELETE FROM waitlist w
+USING users u
+WHERE u.id = w.user_id AND u.disabled_at IS NOT NULL;
+
+DELETE FROM waitlist w
+USING events e
+WHERE e.id = w.event_id
+    AND NOT EXISTS (
+        SELECT 1 FROM org_members m WHERE m.org_id = e.org_id AND m.user_id = w.user_id
+    );
@@ -4,37 +4,110 @@ import "errors"
 
 var (
 	// 404
-	ErrUserNotFound  = errors.New("requested user id not found")
-	ErrBookNotFound  = errors.New("requested booking id not found")
-	ErrEventNotFound = errors.New("requested event id not found")
+	ErrUserNotFound      = errors.New("requested user id not found")
+	ErrBookNotFound      = errors.New("requested booking id not found")
+	ErrEventNotFound     = errors.New("requested event id not found")
+	ErrNotWaitlisted     = errors.New("you are not in the waitlist for this event")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrInviteNotFound    = errors.New("invitation not found")
+	ErrUserTokenNotFound = errors.New("token not found")
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrIdentityNotFound  = errors.New("linked identity not found")
+	ErrOIDCDisabled      = errors.New("single sign-on is not configured")
+	ErrOrganizerNotFound = errors.New("user is not a co-organizer of this event")
+	ErrOrgNotFound       = errors.New("organization not found")
+	ErrNotOrgMember      = errors.New("user is not a member of this organization")
+	ErrCategoryNotFound  = errors.New("category not found")
+	ErrSeriesNotFound    = errors.New("event series not found")
+
+	// 401
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired, log in again")
+	ErrSessionRevoked      = errors.New("session is revoked or expired, log in again")
+	ErrInvalidMFAToken     = errors.New("two-factor login is invalid or expired, log in again")
+	ErrInvalidAPIKey       = errors.New("API key is invalid, expired or revoked")
+	ErrInvalidOIDCState    = errors.New("single sign-on request is invalid or expired, start over")
+	ErrOIDCLoginFailed     = errors.New("identity provider login failed")
 
 	// 400
 	ErrInvalidToken       = errors.New("invalid auth-token provided")
 	ErrInvalidCredentials = errors.New("email or password is incorrect")
 
-	ErrIncorrectEmail     = errors.New("incorrect email provided")
-	ErrIncorrectPhone     = errors.New("incorrect telephone number provided")
-	ErrIncorrectEventID   = errors.New("incorrect event id provided")
-	ErrIncorrectBookID    = errors.New("incorrect booking id provided")
-	ErrIncorrectUserID    = errors.New("incorrect user id provided")
-	ErrIncorrectUserRole  = errors.New("incorrect user role is provided")
-	ErrIncorrectEventTime = errors.New("event date cannot be in the past")
-	ErrEmptyEventInfo     = errors.New("incomplete data provided to create event")
-	ErrEmptyBookInfo      = errors.New("incomplete data provided to book event")
-	ErrEmptyEmail         = errors.New("empty email provided")
+	ErrIncorrectEmail      = errors.New("incorrect email provided")
+	ErrIncorrectPhone      = errors.New("incorrect telephone number provided")
+	ErrIncorrectEventID    = errors.New("incorrect event id provided")
+	ErrIncorrectBookID     = errors.New("incorrect booking id provided")
+	ErrIncorrectUserID     = errors.New("incorrect user id provided")
+	ErrIncorrectUserRole   = errors.New("incorrect user role is provided")
+	ErrIncorrectEventTime  = errors.New("event must start in the future and end after its start")
+	ErrEmptyEventInfo      = errors.New("incomplete data provided to create event")
+	ErrEmptyBookInfo       = errors.New("incomplete data provided to book event")
+	ErrEmptyEmail          = errors.New("empty email provided")
+	ErrEmptyEventUpdate    = errors.New("no event fields provided to update")
+	ErrEmptyCancelReason   = errors.New("cancellation reason must be provided")
+	ErrIncorrectQuantity   = errors.New("booking quantity must be positive")
+	ErrTooManySeatsPerBook = errors.New("requested quantity exceeds the maximum seats per booking for this event")
+	ErrInvalidInvite       = errors.New("invitation is invalid, expired or already used")
+	ErrIncorrectInviteTTL  = errors.New("invitation lifetime must be between 1 hour and 30 days")
+	ErrIncorrectPagination = errors.New("limit must be between 1 and 100, offset must not be negative")
+	ErrIncorrectCursor     = errors.New("page cursor is invalid or was issued for another sort order")
+	ErrIncorrectListFilter = errors.New("unknown status or sort order, or dates are not in YYYY-MM-DD format or out of order")
+	ErrIncorrectSearch     = errors.New("search query must be from 1 to 200 characters")
+	ErrIncorrectEventMeta  = errors.New("venue, address and contact must be up to 200 characters, cover URL - an absolute http(s) URL, up to 10 categories and 20 tags of up to 50 characters")
+	ErrIncorrectCategory   = errors.New("category name must be up to 100 characters, slug - 2 to 50 lowercase letters, digits and dashes")
+	ErrIncorrectTimeZone   = errors.New("unknown event time zone, use an IANA name like Europe/Moscow")
+	ErrIncorrectRRule      = errors.New("recurrence rule must be like FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10: FREQ WEEKLY or MONTHLY, optional INTERVAL, BYDAY for WEEKLY, BYMONTHDAY for MONTHLY, COUNT up to 500 or UNTIL")
+	ErrIncorrectScope      = errors.New("update scope must be this or future")
+	ErrSeriesTimeUpdate    = errors.New("future occurrences keep their dates: only the start time of day and the end can be changed for them, not the date or time zone")
+	ErrInvalidUserToken    = errors.New("token is invalid, expired or already used")
+	ErrEmptyPassword       = errors.New("empty password provided")
+	ErrPasswordTooLong     = errors.New("password must not be longer than 72 bytes")
+	ErrInvalidMFACode      = errors.New("invalid two-factor code")
+	ErrIncorrectAPIKey     = errors.New("API key must have a name of up to 100 characters and at least one known scope")
+	ErrIncorrectAPIKeyTTL  = errors.New("API key lifetime must be between 1 and 365 days")
+	ErrOIDCEmailMissing    = errors.New("identity provider did not share an email address")
+	ErrIncorrectOrg        = errors.New("organization name must be up to 100 characters, slug - 2 to 50 lowercase letters, digits and dashes")
 
 	// 403
-	ErrAccessDenied = errors.New("you don't have enough permissions to complete this operation")
+	ErrAccessDenied     = errors.New("you don't have enough permissions to complete this operation")
+	ErrUserDisabled     = errors.New("user account is disabled")
+	ErrEmailNotVerified = errors.New("confirm your email before booking")
+	ErrMFARequired      = errors.New("two-factor authentication is required for this account")
+	ErrScopeDenied      = errors.New("API key does not have the scope required for this operation")
+	ErrNoOrgMembership  = errors.New("account is not a member of any organization")
+
+	// 413
+	ErrRequestTooLarge = errors.New("request body is too large")
+
+	// 429
+	ErrTooManyAttempts = errors.New("too many attempts, try again later")
 
 	// 500
 	ErrCommon500 = errors.New("something went wrong. Try again later")
 
 	// 409
-	ErrBookIsConfirmed   = errors.New("requested booking is already confirmed")
-	ErrNoSeatsAvailable  = errors.New("no more seats to book for this event")
-	ErrExpiredEvent      = errors.New("the event you are trying to book has expired")
-	ErrExpiredBook       = errors.New("requested booking confirmation deadline has expired")
-	ErrBookIsCancelled   = errors.New("requested booking is already cancelled")
-	ErrEventBusy         = errors.New("requested event not available for deletion. Remove confirmed bookings first")
-	ErrUserAlreadyExists = errors.New("user with such email already exists")
+	ErrBookIsConfirmed      = errors.New("requested booking is already confirmed")
+	ErrNoSeatsAvailable     = errors.New("no more seats to book for this event")
+	ErrExpiredEvent         = errors.New("the event you are trying to book has expired")
+	ErrBookingClosed        = errors.New("booking for this event is closed")
+	ErrNotInSeries          = errors.New("event is not an occurrence of a series")
+	ErrExpiredBook          = errors.New("requested booking confirmation deadline has expired")
+	ErrBookIsCancelled      = errors.New("requested booking is already cancelled")
+	ErrEventBusy            = errors.New("requested event not available for deletion. Remove confirmed bookings first")
+	ErrUserAlreadyExists    = errors.New("user with such email already exists")
+	ErrEventNotEditable     = errors.New("only actual events can be updated")
+	ErrSeatsBelowBooked     = errors.New("total seats cannot be less than seats held by active bookings")
+	ErrEventIsCancelled     = errors.New("requested event is already cancelled")
+	ErrAlreadyWaitlisted    = errors.New("you are already in the waitlist for this event")
+	ErrSeatsAvailable       = errors.New("seats are available for this event, book them directly")
+	ErrInviteExists         = errors.New("invitation with such token already exists")
+	ErrSelfModification     = errors.New("admins cannot change role, disable or delete their own account")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrMFANotSetUp          = errors.New("two-factor authentication is not set up, start the setup first")
+	ErrOIDCEmailNotVerified = errors.New("identity provider has not verified this email, it cannot be linked to an existing account")
+	ErrAlreadyOrganizer     = errors.New("user already organizes this event")
+	ErrNotOrganizerRole     = errors.New("only users with the organizer role can co-organize events")
+	ErrOrgSlugTaken         = errors.New("organization with such slug already exists")
+	ErrAlreadyOrgMember     = errors.New("user is already a member of this organization")
+	ErrUserInOtherOrgs      = errors.New("user belongs to other organizations as well, remove them from this one instead")
+	ErrCategoryExists       = errors.New("category with such slug already exists")
 )
@@ -10,70 +10,390 @@ import (
 )
 
 const (
-	RoleAdmin = "admin"
-	RoleUser  = "user"
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer" // создает ивенты и управляет своими
+	RoleUser      = "user"
+
+	DefaultOrgSlug = "default" // организация по умолчанию: создается миграцией, в неё регистрируются без указания организации
 
 	BookStatusCreated   = "created"
 	BookStatusConfirmed = "confirmed"
 	BookStatusCancelled = "cancelled"
+	BookStatusExpired   = "expired"
 
 	EventStatusActual    = "actual"
 	EventStatusExpired   = "expired"
 	EventStatusCancelled = "cancelled"
+
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+
+	ScopeEventsRead    = "events:read"
+	ScopeEventsWrite   = "events:write"
+	ScopeBookingsRead  = "bookings:read"
+	ScopeBookingsWrite = "bookings:write"
+
+	// сортировки списков ивентов и броней; минус - по убыванию
+	SortCreated     = "created"
+	SortCreatedDesc = "-created"
+	SortDate        = "date" // по дате ивента, только для ивентов
+	SortDateDesc    = "-date"
+
+	// область изменения вхождения серии: только оно или оно и все следующие
+	UpdateScopeThis   = "this"
+	UpdateScopeFuture = "future"
 )
 
+// APIKeyScopes - все права, которые можно выдать API-ключу
+var APIKeyScopes = []string{ScopeEventsRead, ScopeEventsWrite, ScopeBookingsRead, ScopeBookingsWrite}
+
 type (
 	Event struct {
-		ID         int        `json:"id,omitempty"`
-		Title      string     `json:"title"`
-		Descr      string     `json:"descr,omitempty"`
-		Created    *time.Time `json:"created,omitempty"`
-		Status     string     `json:"status,omitempty"`
-		EventDate  CustomTime `json:"eventdate"`
-		TotalSeats int        `json:"total"`           // общее кол-во мест у события для бронирования
-		AvailSeats int        `json:"avail,omitempty"` // доступное кол-во мест у события для бронирования
-		BookWindow int        `json:"period"`          // период жизни неподтвержденной брони в секундах
+		ID            int         `json:"id,omitempty"`
+		Title         string      `json:"title"`
+		Descr         string      `json:"descr,omitempty"`
+		Created       *time.Time  `json:"created,omitempty"`
+		Status        string      `json:"status,omitempty"`
+		StartsAt      CustomTime  `json:"starts_at"`
+		EndsAt        *CustomTime `json:"ends_at,omitempty"`
+		TimeZone      string      `json:"timezone,omitempty"`          // IANA-имя часового пояса ивента, по умолчанию UTC
+		EventDate     string      `json:"eventdate,omitempty"`         // устаревшее: дата начала в часовом поясе ивента; на входе - если нет starts_at
+		BookingCloses *time.Time  `json:"booking_closes_at,omitempty"` // начало минус BOOKING_CLOSE_MINUTES; только в ответах
+		TotalSeats    int         `json:"total"`                       // общее кол-во мест у события для бронирования
+		AvailSeats    int         `json:"avail,omitempty"`             // доступное кол-во мест у события для бронирования
+		BookWindow    int         `json:"period"`                      // период жизни неподтвержденной брони в секундах
+		MaxPerBook    int         `json:"max_per_book"`                // максимум мест в одной брони
+		CancelReason  string      `json:"cancel_reason,omitempty"`
+		OwnerID       int         `json:"owner_id,omitempty"` // создатель ивента; 0 - владелец удален, ивентом управляют админы и соорганизаторы
+		OrgID         int         `json:"org_id,omitempty"`
+		Venue         string      `json:"venue,omitempty"`
+		Address       string      `json:"address,omitempty"`
+		Contact       string      `json:"contact,omitempty"` // контакт организатора для посетителей: имейл, телефон или ссылка
+		CoverURL      string      `json:"cover_url,omitempty"`
+		Categories    []string    `json:"categories,omitempty"` // slug категорий организации
+		Tags          []string    `json:"tags,omitempty"`
+		SeriesID      int         `json:"series_id,omitempty"` // серия, вхождением которой является ивент
+	}
+	// EventSeries - повторяющийся ивент: шаблон и правило повторения. Вхождения - обычные ивенты с series_id,
+	// их заранее создает планировщик серий; брони всегда относятся к конкретному вхождению
+	EventSeries struct {
+		ID             int        `json:"id"`
+		OrgID          int        `json:"org_id,omitempty"`
+		OwnerID        int        `json:"owner_id,omitempty"` // 0 - владелец удален, серией управляют админы
+		RRule          string     `json:"rrule"`
+		Template       Event      `json:"template"`                  // starts_at и ends_at - первого вхождения
+		GeneratedUntil *time.Time `json:"generated_until,omitempty"` // начало последнего созданного вхождения
+		Finished       bool       `json:"finished"`                  // все вхождения уже созданы
+		Created        *time.Time `json:"created,omitempty"`
+	}
+	// SeriesInfo - серия с её вхождениями
+	SeriesInfo struct {
+		EventSeries
+		Occurrences []*Event `json:"occurrences"`
+	}
+	// Category - рубрика ивентов организации; её ведут админы, ивенты ссылаются на неё по slug
+	Category struct {
+		ID      int        `json:"id"`
+		OrgID   int        `json:"-"`
+		Name    string     `json:"name"`
+		Slug    string     `json:"slug"`
+		Created *time.Time `json:"created_at,omitempty"`
+	}
+	// EventOrganizer - соорганизатор ивента: управляет им наравне с владельцем, кроме удаления и назначения соорганизаторов
+	EventOrganizer struct {
+		EventID int        `json:"eventid"`
+		UserID  int        `json:"userid"`
+		Email   string     `json:"email"`
+		Added   *time.Time `json:"added_at,omitempty"`
+	}
+	// EventFilter - выборка списка ивентов: без AllStatuses только актуальные,
+	// но ивенты, которыми управляет ManagedBy (владелец или соорганизатор), попадают в список в любом статусе;
+	// остальные поля - фильтры и страница, которые задает клиент
+	EventFilter struct {
+		OrgID       int
+		AllStatuses bool
+		ManagedBy   int
+		Status      string     // только ивенты в этом статусе, поверх видимости
+		From        *time.Time // дата ивента не раньше, включительно
+		To          *time.Time // дата ивента раньше, не включительно
+		Query       string     // подстрока названия без учета регистра
+		HasSeats    bool       // только со свободными местами
+		Category    string     // slug категории
+		Tag         string
+		Sort        string
+		Cursor      string   // курсор от клиента, сервис раскладывает его в After
+		After       *PageKey // ключ последнего ивента предыдущей страницы
+		Limit       int
+	}
+	// EventSearch - полнотекстовый поиск ивентов; видимость по роли та же, что у EventFilter
+	EventSearch struct {
+		OrgID       int
+		AllStatuses bool
+		ManagedBy   int
+		Query       string // запрос в синтаксисе веб-поиска: слова, "фраза", -исключение, or
+		Limit       int
+		Offset      int
+	}
+	// EventSearchResult - найденный ивент с релевантностью и фрагментами, где совпадения обрамлены <mark></mark>
+	EventSearchResult struct {
+		Event
+		Rank           float64 `json:"rank"`
+		TitleHighlight string  `json:"title_highlight"`
+		Snippet        string  `json:"snippet,omitempty"` // фрагмент описания вокруг совпадений
+	}
+	// BookFilter - выборка броней пользователя; Limit 0 - без ограничения (история броней для админа)
+	BookFilter struct {
+		OrgID  int
+		UserID int
+		Status string
+		From   *time.Time // создана не раньше, включительно
+		To     *time.Time // создана раньше, не включительно
+		Sort   string
+		Cursor string
+		After  *PageKey
+		Limit  int
+	}
+	// PageKey - ключ keyset-пагинации: поля сортировки последнего элемента страницы, id различает равные даты
+	PageKey struct {
+		ID   int
+		Date time.Time
 	}
 	Book struct {
 		ID              int        `json:"id,omitempty"`
 		EventID         int        `json:"eventid"`
 		UserID          int        `json:"userid,omitempty"`
 		Status          string     `json:"status,omitempty"`
+		Quantity        int        `json:"quantity"` // количество мест в брони
 		Created         *time.Time `json:"created_at,omitempty"`
 		ConfirmDeadline *time.Time `json:"confirm_deadline,omitempty"`
+		ExpiredAt       *time.Time `json:"expired_at,omitempty"`
+		CancelledAt     *time.Time `json:"cancelled_at,omitempty"`
+		OrgID           int        `json:"org_id,omitempty"` // организация ивента
 	}
-	User struct {
+	// EventUpdate - частичное обновление ивента: nil-поля не меняются
+	EventUpdate struct {
+		Title      *string     `json:"title,omitempty"`
+		Descr      *string     `json:"descr,omitempty"`
+		StartsAt   *CustomTime `json:"starts_at,omitempty"`
+		EndsAt     *CustomTime `json:"ends_at,omitempty"` // пустая строка убирает окончание
+		TimeZone   *string     `json:"timezone,omitempty"`
+		EventDate  *string     `json:"eventdate,omitempty"` // устаревшее: дата начала, если нет starts_at
+		TotalSeats *int        `json:"total,omitempty"`
+		BookWindow *int        `json:"period,omitempty"` // применяется только к новым броням
+		MaxPerBook *int        `json:"max_per_book,omitempty"`
+		Venue      *string     `json:"venue,omitempty"`
+		Address    *string     `json:"address,omitempty"`
+		Contact    *string     `json:"contact,omitempty"`
+		CoverURL   *string     `json:"cover_url,omitempty"`
+		Categories *[]string   `json:"categories,omitempty"` // заменяет весь набор; пустой список убирает ивент из всех категорий
+		Tags       *[]string   `json:"tags,omitempty"`
+	}
+	// EventInfo - ивент вместе с живой статистикой по броням; списки броней и соорганизаторов заполняются только для тех, кто управляет ивентом
+	EventInfo struct {
+		Event
+		Stats      BookStats         `json:"bookings"`
+		Books      []*BookWithUser   `json:"bookings_list,omitempty"`
+		Organizers []*EventOrganizer `json:"organizers,omitempty"`
+	}
+	BookStats struct {
+		Created    int        `json:"created"`
+		Confirmed  int        `json:"confirmed"`
+		Cancelled  int        `json:"cancelled"`
+		Expired    int        `json:"expired"`
+		NextExpiry *time.Time `json:"next_expiry,omitempty"` // ближайший дедлайн среди неподтвержденных броней
+	}
+	BookWithUser struct {
+		Book
+		Email string `json:"email"`
+	}
+	// WaitlistEntry - место пользователя в очереди ожидания на ивент без свободных мест
+	WaitlistEntry struct {
 		ID       int        `json:"id,omitempty"`
-		Role     string     `json:"role,omitempty"`
-		Created  *time.Time `json:"created,omitempty"`
-		Name     string     `json:"name,omitempty"`
-		Surname  string     `json:"surname,omitempty"`
-		Tel      string     `json:"tel,omitempty"`
-		Email    string     `json:"email"`
-		PassHash string     `json:"password"`
+		EventID  int        `json:"eventid"`
+		UserID   int        `json:"userid,omitempty"`
+		Quantity int        `json:"quantity"`
+		Created  *time.Time `json:"created_at,omitempty"`
+		Position int        `json:"position,omitempty"` // позиция в очереди, начиная с 1
+		Email    string     `json:"-"`
+	}
+	// Notification - уведомление пользователя о событии с его бронями
+	Notification struct {
+		UserID  int
+		Email   string
+		Subject string
+		Text    string
+	}
+	User struct {
+		ID              int        `json:"id,omitempty"`
+		Role            string     `json:"role,omitempty"` // роль в текущей организации; заполняется только там, где организация известна
+		Created         *time.Time `json:"created,omitempty"`
+		Name            string     `json:"name,omitempty"`
+		Surname         string     `json:"surname,omitempty"`
+		Tel             string     `json:"tel,omitempty"`
+		Email           string     `json:"email"`
+		PassHash        string     `json:"password"`
+		DisabledAt      *time.Time `json:"disabled_at,omitempty"` // заблокированный пользователь не может войти, его сессии отозваны
+		EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+		LockedUntil     *time.Time `json:"locked_until,omitempty"` // временная блокировка входа после серии неудачных попыток
+		// счетчик неудачных входов меняют только RegisterLoginFailure/ResetLoginFailures; хранилище Postgres его не читает
+		FailedLogins  int        `json:"-"`
+		FailedLoginAt *time.Time `json:"-"`
+		// TOTP 2FA: секрет появляется при настройке, TOTPEnabledAt - после подтверждения первым кодом
+		TOTPSecret    string     `json:"-"`
+		TOTPEnabledAt *time.Time `json:"totp_enabled_at,omitempty"`
+		TOTPLastStep  int64      `json:"-"` // шаг последнего принятого кода - защита от повторного использования
+	}
+	// TOTPSetup - секрет для приложения-аутентификатора, показывается пользователю один раз при настройке 2FA
+	TOTPSetup struct {
+		Secret string
+		URI    string
+	}
+	// RecoveryCode - одноразовый код входа на случай потери аутентификатора
+	RecoveryCode struct {
+		ID       int
+		UserID   int
+		CodeHash string
+		UsedAt   *time.Time
+	}
+	// UserToken - одноразовый токен из письма: подтверждение имейла или сброс пароля
+	UserToken struct {
+		ID        int
+		UserID    int
+		Purpose   string
+		TokenHash string
+		Created   *time.Time
+		ExpiresAt time.Time
+		UsedAt    *time.Time
+	}
+	// Mail - письмо пользователю
+	Mail struct {
+		To      string
+		Subject string
+		Text    string
+	}
+	// UserFilter - параметры постраничного поиска пользователей для админа
+	UserFilter struct {
+		OrgID  int    // только участники организации, с их ролью в ней
+		Query  string // подстрока имейла, имени или фамилии, без учета регистра
+		Limit  int
+		Offset int
+	}
+	// UserInfo - пользователь вместе с историей его броней, только для админа
+	UserInfo struct {
+		User  *User
+		Books []*Book
+	}
+
+	// Session - серверная сессия пользователя, к которой привязаны refresh-токен и выданные по нему access-токены
+	Session struct {
+		ID        int
+		UserID    int
+		TokenHash string // SHA-256 от refresh-токена, сам токен не хранится
+		Created   *time.Time
+		ExpiresAt time.Time
+		RevokedAt *time.Time
+		MFA       bool // сессия открыта после проверки второго фактора
+		OrgID     int  // текущая организация сессии, меняется через SwitchOrganization
+	}
+	// Invite - одноразовое приглашение на регистрацию с заданной ролью, выдается админом
+	Invite struct {
+		ID        int        `json:"id,omitempty"`
+		Token     string     `json:"token,omitempty"` // возвращается только при создании, в БД хранится хэш
+		TokenHash string     `json:"-"`
+		Role      string     `json:"role"`
+		Email     string     `json:"email,omitempty"` // если задан - приглашение действует только для этого имейла
+		CreatedBy int        `json:"created_by,omitempty"`
+		Created   *time.Time `json:"created_at,omitempty"`
+		ExpiresAt time.Time  `json:"expires_at"`
+		UsedBy    int        `json:"used_by,omitempty"`
+		UsedAt    *time.Time `json:"used_at,omitempty"`
+		OrgID     int        `json:"org_id,omitempty"` // организация, участником которой станет зарегистрированный
+	}
+	// APIKey - персональный ключ машинного клиента с ограниченным набором прав; роль берется у владельца в организации ключа
+	APIKey struct {
+		ID         int        `json:"id"`
+		UserID     int        `json:"-"`
+		OrgID      int        `json:"org_id"`
+		Name       string     `json:"name"`
+		Key        string     `json:"key,omitempty"` // возвращается только при создании, в БД хранится хэш
+		Prefix     string     `json:"prefix"`        // начало ключа, чтобы его можно было узнать в списке
+		KeyHash    string     `json:"-"`
+		Scopes     []string   `json:"scopes"`
+		MFA        bool       `json:"-"` // ключ выпущен из сессии, прошедшей 2FA
+		Created    *time.Time `json:"created_at,omitempty"`
+		ExpiresAt  time.Time  `json:"expires_at"`
+		LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+		RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	}
+	// Organization - организация (площадка): её ивенты, брони и участники изолированы от других организаций
+	Organization struct {
+		ID      int        `json:"id"`
+		Name    string     `json:"name"`
+		Slug    string     `json:"slug"` // короткое имя для регистрации в организации
+		Created *time.Time `json:"created_at,omitempty"`
+		Role    string     `json:"role,omitempty"`    // роль пользователя в организации - в списке его организаций
+		Current bool       `json:"current,omitempty"` // организация текущей сессии
+	}
+	// UserIdentity - учетная запись внешнего провайдера входа (OIDC), привязанная к пользователю
+	UserIdentity struct {
+		ID      int
+		UserID  int
+		Issuer  string
+		Subject string // sub из ID-токена - постоянный идентификатор у провайдера, в отличие от имейла
+		Email   string // имейл у провайдера на момент привязки
+		Created *time.Time
+	}
+	// AuthTokens - пара токенов, выдаваемая при входе и обновлении сессии
+	AuthTokens struct {
+		Access         string
+		Refresh        string
+		RefreshExpires time.Time
+		MFAToken       string // при включенной 2FA логин выдает только его - пара токенов выдается после проверки кода
 	}
 
+	// CustomTime - время ивента. На выходе - RFC 3339 со смещением, на входе - RFC 3339 либо
+	// время без смещения ("2006-01-02T15:04[:05]") или только дата (начало дня): такие значения - местное
+	// время часового пояса ивента, до привязки к нему через InZone они помечены Wall
 	CustomTime struct {
 		time.Time
+		Wall bool
 	}
 )
 
+// wallLayouts - форматы времени без смещения
+var wallLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", time.DateOnly}
+
 func (ct *CustomTime) UnmarshalJSON(b []byte) error {
 	s := strings.Trim(string(b), `"`)
 	if s == "null" || s == "" {
-		ct.Time = time.Time{}
+		*ct = CustomTime{}
 		return nil
 	}
-	t, err := time.Parse("2006-01-02", s)
-	if err != nil {
-		return err
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		*ct = CustomTime{Time: t}
+		return nil
 	}
-	ct.Time = t.AddDate(0, 0, 1).Add(-1 * time.Millisecond)
-	return nil
+	for _, layout := range wallLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			*ct = CustomTime{Time: t, Wall: true}
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as RFC 3339 time or date", s)
 }
 
-func (ct *CustomTime) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + ct.Time.Format("2006-01-02") + `"`), nil
+func (ct CustomTime) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + ct.Time.Format(time.RFC3339) + `"`), nil
+}
+
+// InZone привязывает время без смещения к часовому поясу loc, а время со смещением переводит в него
+func (ct *CustomTime) InZone(loc *time.Location) {
+	if ct.Wall {
+		ct.Time = time.Date(ct.Year(), ct.Month(), ct.Day(), ct.Hour(), ct.Minute(), ct.Second(), ct.Nanosecond(), loc)
+		ct.Wall = false
+		return
+	}
+	ct.Time = ct.In(loc)
 }
 
 // Scanner для чтения из БД
@@ -0,0 +1,45 @@
+package model
+
+import "slices"
+
+// Права ролей. Роль - своя в каждой организации, и права действуют только внутри текущей организации.
+// Право проверяется по роли, а право на конкретный ивент дополнительно по владельцу:
+// PermEventsManage дает управление только своими ивентами, PermEventsManageAny - любыми.
+const (
+	PermEventsCreate    = "events.create"
+	PermEventsManage    = "events.manage"
+	PermEventsManageAny = "events.manage_any"
+	PermUsersManage     = "users.manage"
+	PermInvitesCreate   = "invites.create"
+	PermOrgsCreate      = "orgs.create"
+	PermCategoriesEdit  = "categories.edit"
+)
+
+var rolePermissions = map[string][]string{
+	RoleAdmin:     {PermEventsCreate, PermEventsManage, PermEventsManageAny, PermUsersManage, PermInvitesCreate, PermOrgsCreate, PermCategoriesEdit},
+	RoleOrganizer: {PermEventsCreate, PermEventsManage},
+	RoleUser:      {},
+}
+
+// Actor - пользователь, от имени которого выполняется операция, и организация, в которой он действует
+type Actor struct {
+	UserID int
+	OrgID  int
+	Role   string // роль в организации OrgID
+}
+
+// ValidRole - известна ли роль приложению
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission - выдано ли роли право perm
+func RoleHasPermission(role string, perm string) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// Can - выдано ли роли пользователя право perm
+func (a Actor) Can(perm string) bool {
+	return RoleHasPermission(a.Role, perm)
+}
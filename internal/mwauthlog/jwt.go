@@ -1,27 +1,91 @@
 package mwauthlog
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// JWTManager - единственное место выпуска и проверки JWT приложения.
+// Токены подписываются активным ключом и несут его id в заголовке kid; проверяются любым из ключей набора,
+// поэтому ключ можно сменить без разлогина: новый ключ сначала добавляется в набор, затем становится активным,
+// а старый удаляется после истечения выпущенных им токенов.
 type JWTManager struct {
-	secret []byte
-	ttl    time.Duration
-	issuer string
+	keys      map[string][]byte
+	activeKID string
+	ttl       time.Duration
+	issuer    string
 }
 
-func NewJWTManager(secret []byte, ttl time.Duration, issuer string) *JWTManager {
-	return &JWTManager{secret: secret, ttl: ttl, issuer: issuer}
+// mfaPendingAudience - аудитория промежуточного токена 2FA: по нему нельзя пройти RequireAuth, только проверку кода
+const (
+	mfaPendingAudience = "mfa_pending"
+	mfaPendingTTL      = 5 * time.Minute
+	oidcStateAudience  = "oidc_state"
+	oidcStateTTL       = 10 * time.Minute
+)
+
+// oidcStateClaims - параметры входа через OIDC, которые должны дожить до возврата пользователя от провайдера
+type oidcStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// signingMethod - все ключи симметричные, токены с другим alg (в том числе none) отклоняются
+var signingMethod = jwt.SigningMethodHS256
+
+func NewJWTManager(keys map[string][]byte, activeKID string, ttl time.Duration, issuer string) (*JWTManager, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no JWT signing keys provided")
+	}
+	for kid, secret := range keys {
+		if kid == "" || len(secret) == 0 {
+			return nil, fmt.Errorf("JWT signing key %q is empty", kid)
+		}
+	}
+	if _, ok := keys[activeKID]; !ok {
+		return nil, fmt.Errorf("active JWT key %q is not among the signing keys", activeKID)
+	}
+	return &JWTManager{keys: keys, activeKID: activeKID, ttl: ttl, issuer: issuer}, nil
+}
+
+// ParseSigningKeys разбирает набор ключей из конфига вида "kid1:secret1;kid2:secret2"
+func ParseSigningKeys(spec string) (map[string][]byte, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" || secret == "" {
+			return nil, fmt.Errorf("invalid JWT key entry %q, expected kid:secret", entry)
+		}
+		if _, dup := keys[kid]; dup {
+			return nil, fmt.Errorf("duplicate JWT key id %q", kid)
+		}
+		keys[kid] = []byte(secret)
+	}
+	return keys, nil
 }
 
-func (j *JWTManager) Generate(uid int, email string, role string) (string, error) {
+// Generate выпускает access-токен, привязанный к серверной сессии sid и её организации orgID (role - роль в ней);
+// mfa - сессия прошла проверку второго фактора
+func (j *JWTManager) Generate(uid int, sid int, orgID int, email string, role string, mfa bool) (string, error) {
 	claims := Claims{
-		UserID: uid,
-		Email:  email,
-		Role:   role,
+		UserID:    uid,
+		SessionID: sid,
+		OrgID:     orgID,
+		Email:     email,
+		Role:      role,
+		MFA:       mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -29,9 +93,83 @@ func (j *JWTManager) Generate(uid int, email string, role string) (string, error
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return j.sign(claims)
+}
+
+// GenerateMFAPending выпускает короткоживущий токен между вводом пароля и кода 2FA
+func (j *JWTManager) GenerateMFAPending(uid int) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(uid),
+		Audience:  jwt.ClaimStrings{mfaPendingAudience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(mfaPendingTTL)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    j.issuer,
+	}
 
-	signed, err := token.SignedString(j.secret)
+	return j.sign(claims)
+}
+
+// GenerateOIDCState упаковывает state, nonce и PKCE verifier входа через OIDC в подписанный токен для cookie
+func (j *JWTManager) GenerateOIDCState(state, nonce, verifier string) (string, error) {
+	claims := oidcStateClaims{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oidcStateAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    j.issuer,
+		},
+	}
+
+	return j.sign(claims)
+}
+
+// ParseOIDCState проверяет токен из GenerateOIDCState и возвращает state, nonce и verifier
+func (j *JWTManager) ParseOIDCState(tokenStr string) (string, string, string, error) {
+	var claims oidcStateClaims
+	if err := j.parse(tokenStr, &claims, jwt.WithAudience(oidcStateAudience)); err != nil {
+		return "", "", "", model.ErrInvalidOIDCState
+	}
+
+	return claims.State, claims.Nonce, claims.Verifier, nil
+}
+
+// Parse проверяет access-токен: алгоритм, подпись ключом из kid, издателя и срок действия
+func (j *JWTManager) Parse(tokenStr string) (*Claims, error) {
+	var claims Claims
+	if err := j.parse(tokenStr, &claims); err != nil {
+		return nil, model.ErrInvalidToken
+	}
+	// токены с аудиторией (промежуточный токен 2FA) не являются access-токенами
+	if len(claims.Audience) > 0 {
+		return nil, model.ErrInvalidToken
+	}
+
+	return &claims, nil
+}
+
+// ParseMFAPending проверяет промежуточный токен 2FA и возвращает id пользователя
+func (j *JWTManager) ParseMFAPending(tokenStr string) (int, error) {
+	var claims jwt.RegisteredClaims
+	if err := j.parse(tokenStr, &claims, jwt.WithAudience(mfaPendingAudience)); err != nil {
+		return 0, model.ErrInvalidMFAToken
+	}
+
+	uid, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, model.ErrInvalidMFAToken
+	}
+
+	return uid, nil
+}
+
+func (j *JWTManager) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(signingMethod, claims)
+	token.Header["kid"] = j.activeKID
+
+	signed, err := token.SignedString(j.keys[j.activeKID])
 	if err != nil {
 		return "", err
 	}
@@ -39,25 +177,34 @@ func (j *JWTManager) Generate(uid int, email string, role string) (string, error
 	return signed, nil
 }
 
-func (j *JWTManager) Parse(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(
-		tokenStr,
-		&Claims{},
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, model.ErrInvalidToken
-			}
-			return j.secret, nil
-		},
+func (j *JWTManager) parse(tokenStr string, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	opts = append(opts,
+		jwt.WithValidMethods([]string{signingMethod.Alg()}),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithExpirationRequired(),
 	)
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, j.keyFunc, opts...)
 	if err != nil {
-		return nil, model.ErrInvalidToken
+		return err
+	}
+	if !token.Valid {
+		return model.ErrInvalidToken
 	}
 
-	claims, ok := token.Claims.(*Claims)
-	if !ok || !token.Valid {
-		return nil, model.ErrInvalidToken
+	return nil
+}
+
+// keyFunc выбирает ключ проверки по kid; токены без kid выпущены до ротации ключей и проверяются активным ключом
+func (j *JWTManager) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return j.keys[j.activeKID], nil
 	}
 
-	return claims, nil
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown JWT key id %q", kid)
+	}
+	return key, nil
 }
@@ -3,9 +3,12 @@ package mwauthlog
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"time"
+	"slices"
+	"strings"
 
+	"github.com/UnendingLoop/EventBooker/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -13,11 +16,24 @@ import (
 
 type (
 	Claims struct {
-		UserID int    `json:"uid"`
-		Email  string `json:"email"`
-		Role   string `json:"role"`
+		UserID    int    `json:"uid"`
+		SessionID int    `json:"sid"`
+		OrgID     int    `json:"org"` // текущая организация сессии; роль действует только в ней
+		Email     string `json:"email"`
+		Role      string `json:"role"`
+		MFA       bool   `json:"mfa,omitempty"` // сессия прошла проверку второго фактора
 		jwt.RegisteredClaims
 	}
+
+	// SessionChecker проверяет, что сессия access-токена не отозвана (logout) и не истекла
+	SessionChecker interface {
+		CheckSession(ctx context.Context, sid int) error
+	}
+
+	// APIKeyChecker проверяет API-ключ из заголовка Authorization и возвращает его вместе с владельцем
+	APIKeyChecker interface {
+		AuthenticateAPIKey(ctx context.Context, rawKey string) (*model.APIKey, *model.User, error)
+	}
 )
 
 func RequestID() gin.HandlerFunc {
@@ -34,58 +50,126 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
-func GenerateToken(userID int, role string, secret []byte) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
-}
-
-func RequireAuth(secret []byte) gin.HandlerFunc {
+// RequireAuth проверяет access-токен через JWTManager (алгоритм, ключ по kid, издатель, срок) и то, что его сессия жива.
+// Если передан apiKeys, запрос без cookie может пройти по API-ключу в заголовке "Authorization: Bearer";
+// для таких запросов в контекст кладутся scopes ключа, и маршруты ограничиваются через RequireScope.
+// Cookie приоритетнее заголовка: браузер с сессией не должен зависеть от того, что UI кладет в Authorization.
+func RequireAuth(jwtManager *JWTManager, sessions SessionChecker, apiKeys APIKeyChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		cookie, err := c.Request.Cookie("access_token")
 		if err != nil {
+			if apiKeys != nil {
+				if rawKey, ok := bearerToken(c.GetHeader("Authorization")); ok {
+					authAPIKey(c, apiKeys, rawKey)
+					return
+				}
+			}
 			c.AbortWithStatus(401)
 			return
 		}
 
-		tokenStr := cookie.Value
-
-		token, err := jwt.ParseWithClaims(
-			tokenStr,
-			&Claims{},
-			func(token *jwt.Token) (any, error) {
-				return secret, nil
-			},
-		)
-
-		if err != nil || !token.Valid {
+		claims, err := jwtManager.Parse(cookie.Value)
+		if err != nil || claims.OrgID < 1 { // токены без организации выпущены до её появления - нужен refresh
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
-		claims := token.Claims.(*Claims)
+		// подпись валидна, но сессия могла быть отозвана раньше истечения токена
+		if err := sessions.CheckSession(c.Request.Context(), claims.SessionID); err != nil {
+			if errors.Is(err, model.ErrSessionRevoked) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
 
 		// прокидываем дальше
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("org_id", claims.OrgID)
 		c.Set("role", claims.Role)
 		c.Set("email", claims.Email)
+		c.Set("mfa", claims.MFA)
+
+		c.Next()
+	}
+}
+
+// authAPIKey - ветка RequireAuth для API-ключей: сессии нет, организация - та, в которой выпущен ключ,
+// роль и имейл берутся у владельца ключа
+func authAPIKey(c *gin.Context, apiKeys APIKeyChecker, rawKey string) {
+	key, user, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), rawKey)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
 
+	c.Set("user_id", user.ID)
+	c.Set("session_id", 0)
+	c.Set("org_id", key.OrgID)
+	c.Set("role", user.Role)
+	c.Set("email", user.Email)
+	c.Set("mfa", key.MFA)
+	c.Set("scopes", key.Scopes)
+
+	c.Next()
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// RequireScope пропускает запросы по API-ключу только с нужным scope; запросы из сессии (cookie) не ограничиваются
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, exists := c.Get("scopes")
+		if !exists {
+			c.Next()
+			return
+		}
+		if scopes, _ := v.([]string); !slices.Contains(scopes, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": model.ErrScopeDenied.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission пропускает запросы, роли которых выдано право perm (model.RoleHasPermission).
+// Право на конкретный ресурс - например, что организатор управляет именно своим ивентом - проверяет сервис по владельцу:
+// здесь отсекаются роли, у которых права нет ни на один ресурс.
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		if r, _ := role.(string); !model.RoleHasPermission(r, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": model.ErrAccessDenied.Error()})
+			return
+		}
 		c.Next()
 	}
 }
 
-func RequireRole(role string) gin.HandlerFunc {
+// RequireMFA требует от запросов с ролями из roles сессию, прошедшую проверку второго фактора; остальные роли проходят.
+// Без ролей пропускает все запросы - так 2FA включается конфигом без перестройки маршрутов.
+func RequireMFA(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		r, exists := c.Get("role")
-		if !exists || r != role {
-			c.AbortWithStatus(http.StatusForbidden)
+		role, _ := c.Get("role")
+		if r, _ := role.(string); !slices.Contains(roles, r) {
+			c.Next()
+			return
+		}
+		if mfa, _ := c.Get("mfa"); mfa != true {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": model.ErrMFARequired.Error()})
 			return
 		}
 		c.Next()
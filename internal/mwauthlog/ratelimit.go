@@ -0,0 +1,145 @@
+package mwauthlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter - ограничитель частоты запросов со скользящим окном: для каждого ключа хранит время
+// последних запросов в пределах окна. Состояние живет в памяти процесса - у каждого инстанса свои счетчики.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	if limit <= 0 {
+		log.Println("Invalid limit provided for RateLimiter. Using default value: 10")
+		limit = 10
+	}
+	if window <= 0 {
+		log.Println("Invalid window provided for RateLimiter. Using default value: 1 minute")
+		window = time.Minute
+	}
+	return &RateLimiter{limit: limit, window: window, hits: make(map[string][]time.Time), lastSweep: time.Now()}
+}
+
+// Allow засчитывает запрос по ключу, если лимит не исчерпан; иначе возвращает, через сколько освободится место в окне.
+// Отклоненные запросы не засчитываются.
+func (rl *RateLimiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+	from := now.Add(-rl.window)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.sweep(now)
+
+	hits := rl.hits[key]
+	i := 0
+	for i < len(hits) && !hits[i].After(from) {
+		i++
+	}
+	hits = hits[i:]
+
+	if len(hits) >= rl.limit {
+		rl.hits[key] = hits
+		return false, hits[0].Sub(from)
+	}
+
+	rl.hits[key] = append(hits, now)
+	return true, 0
+}
+
+// sweep раз в окно удаляет ключи без запросов в окне, чтобы карта не росла бесконечно
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rl.window {
+		return
+	}
+	rl.lastSweep = now
+
+	from := now.Add(-rl.window)
+	for key, hits := range rl.hits {
+		if len(hits) == 0 || !hits[len(hits)-1].After(from) {
+			delete(rl.hits, key)
+		}
+	}
+}
+
+// RateLimit отклоняет запрос с 429 и заголовком Retry-After, если лимит по ключу из keyFn исчерпан.
+// Запросы с пустым ключом не ограничиваются; keyFn может сама отклонить запрос, который нельзя разобрать.
+func RateLimit(rl *RateLimiter, keyFn func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFn(c)
+		if c.IsAborted() {
+			return
+		}
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if ok, wait := rl.Allow(key); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": model.ErrTooManyAttempts.Error()})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ClientIPKey - ключ лимита по IP клиента
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// maxKeyBody - предел тела запроса для JSONFieldKey: лимиты стоят на маршрутах без авторизации,
+// и читать в память тело любого размера нельзя
+const maxKeyBody = 4 << 10
+
+// JSONFieldKey - ключ лимита по строковому полю JSON-тела (например, имейлу при логине) без учета регистра.
+// Тело запроса восстанавливается для хендлера. Тело больше maxKeyBody отклоняется с 413: иначе хендлер получил бы
+// обрезанное, но разбираемое тело, а запрос прошел бы мимо лимита по ключу.
+// Разбирается только первое JSON-значение, как в ShouldBindJSON, чтобы ключ совпадал с тем, что увидит хендлер
+func JSONFieldKey(field string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxKeyBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": model.ErrRequestTooLarge.Error()})
+			} else {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return ""
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var payload map[string]any
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&payload); err != nil {
+			return ""
+		}
+		value, _ := payload[field].(string)
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
@@ -0,0 +1,237 @@
+package mwauthlog
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	window := 200 * time.Millisecond
+	rl := NewRateLimiter(2, window)
+
+	for i := range 2 {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Fatalf("request %d is rejected within limit", i+1)
+		}
+	}
+	ok, wait := rl.Allow("a")
+	if ok {
+		t.Fatal("request over limit is allowed")
+	}
+	if wait <= 0 || wait > window {
+		t.Errorf("wait = %v, want within (0, %v]", wait, window)
+	}
+	if ok, _ := rl.Allow("b"); !ok {
+		t.Error("limit of one key affects another key")
+	}
+
+	// отклоненные запросы не засчитываются: после окна снова доступен весь лимит
+	time.Sleep(window + 20*time.Millisecond)
+	for i := range 2 {
+		if ok, _ := rl.Allow("a"); !ok {
+			t.Fatalf("request %d after window is rejected", i+1)
+		}
+	}
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	window := 300 * time.Millisecond
+	rl := NewRateLimiter(2, window)
+
+	rl.Allow("a")
+	time.Sleep(window / 2)
+	rl.Allow("a")
+	// первый запрос выходит из окна раньше второго - место освобождается только под один запрос
+	time.Sleep(window/2 + 30*time.Millisecond)
+	if ok, _ := rl.Allow("a"); !ok {
+		t.Fatal("request is rejected after the oldest hit left the window")
+	}
+	if ok, _ := rl.Allow("a"); ok {
+		t.Error("request is allowed while the window is full")
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	window := 50 * time.Millisecond
+	rl := NewRateLimiter(1, window)
+	rl.Allow("old")
+	time.Sleep(window + 10*time.Millisecond)
+	rl.Allow("new")
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if _, ok := rl.hits["old"]; ok {
+		t.Error("key without hits in window is not swept")
+	}
+	if _, ok := rl.hits["new"]; !ok {
+		t.Error("active key is swept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	rl := NewRateLimiter(1, time.Minute)
+	engine := gin.New()
+	engine.POST("/login", RateLimit(rl, func(c *gin.Context) string { return c.GetHeader("X-Key") }), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		if key != "" {
+			req.Header.Set("X-Key", key)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send("a"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", w.Code)
+	}
+	w := send("a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+	// пустой ключ не ограничивается
+	for range 3 {
+		if w := send(""); w.Code != http.StatusOK {
+			t.Fatalf("request without key: status %d, want 200", w.Code)
+		}
+	}
+}
+
+func TestJSONFieldKey(t *testing.T) {
+	login := `{"email":"user@test.io","password":"x"}`
+	tests := []struct {
+		name       string
+		body       string
+		wantKey    string
+		wantStatus int // 0 - запрос доходит до хендлера с исходным телом
+	}{
+		{name: "normalized field", body: `{"email":"  User@Test.IO ","password":"x"}`, wantKey: "user@test.io"},
+		{name: "no field", body: `{"password":"x"}`},
+		{name: "field is not a string", body: `{"email":42}`},
+		{name: "not json", body: `email=user@test.io`},
+		{name: "empty body"},
+		{name: "trailing data after json", body: login + ` {"email":"other@test.io"}`, wantKey: "user@test.io"},
+		{name: "body at the cap", body: login + strings.Repeat(" ", maxKeyBody-len(login)), wantKey: "user@test.io"},
+		{name: "json padded over the cap", body: login + strings.Repeat(" ", maxKeyBody), wantStatus: http.StatusRequestEntityTooLarge},
+		{
+			name:       "long field over the cap",
+			body:       `{"email":"user@test.io","pad":"` + strings.Repeat("x", maxKeyBody) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKey, gotBody string
+			called := false
+			engine := gin.New()
+			engine.POST("/login", func(c *gin.Context) {
+				gotKey = JSONFieldKey("email")(c)
+				if c.IsAborted() {
+					return
+				}
+				called = true
+				b, _ := io.ReadAll(c.Request.Body)
+				gotBody = string(b)
+			})
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(tt.body)))
+
+			if tt.wantStatus != 0 {
+				if w.Code != tt.wantStatus || called {
+					t.Fatalf("status %d, handler called %v; want %d without handler", w.Code, called, tt.wantStatus)
+				}
+				return
+			}
+			if gotKey != tt.wantKey {
+				t.Errorf("key = %q, want %q", gotKey, tt.wantKey)
+			}
+			if gotBody != tt.body {
+				t.Errorf("handler body has %d bytes, want %d", len(gotBody), len(tt.body))
+			}
+		})
+	}
+}
+
+// TestRateLimitOversizedBody - тело с добитым пробелами JSON не обходит лимит по аккаунту
+func TestRateLimitOversizedBody(t *testing.T) {
+	engine := gin.New()
+	logins := 0
+	engine.POST("/login", RateLimit(NewRateLimiter(1, time.Minute), JSONFieldKey("email")), func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		logins++
+		c.Status(http.StatusOK)
+	})
+	send := func(body string) int {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		return w.Code
+	}
+
+	login := `{"email":"user@test.io","password":"x"}`
+	if code := send(login); code != http.StatusOK {
+		t.Fatalf("first login: status %d, want 200", code)
+	}
+	if code := send(login + strings.Repeat(" ", 2*maxKeyBody)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("padded login: status %d, want 413", code)
+	}
+	if code := send(login); code != http.StatusTooManyRequests {
+		t.Errorf("second login: status %d, want 429", code)
+	}
+	if logins != 1 {
+		t.Errorf("handler accepted %d logins, want 1", logins)
+	}
+}
+
+func TestClientIPKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{name: "no trusted proxies ignore X-Forwarded-For", remote: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer", proxies: []string{"10.0.0.0/8"}, remote: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "trusted proxy", proxies: []string{"10.0.0.0/8"}, remote: "10.1.2.3:5000", want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			if err := engine.SetTrustedProxies(tt.proxies); err != nil {
+				t.Fatalf("SetTrustedProxies() error = %v", err)
+			}
+			var got string
+			engine.GET("/", func(c *gin.Context) { got = ClientIPKey(c) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("ClientIPKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
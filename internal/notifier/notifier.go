@@ -0,0 +1,20 @@
+// Package notifier provides delivery of user notifications. LogNotifier writes them to the app log and serves as a stub until Email/Telegram delivery is plugged in
+package notifier
+
+import (
+	"context"
+	"log"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (ln *LogNotifier) Notify(ctx context.Context, n *model.Notification) error {
+	log.Printf("RID %q Notification to userID=%d email=%q: %s - %s", model.RequestIDFromCtx(ctx), n.UserID, n.Email, n.Subject, n.Text)
+	return nil
+}
@@ -0,0 +1,404 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://idp.test"
+	testClientID = "eventbooker"
+	testNonce    = "nonce-123"
+)
+
+var (
+	rsaKey   = mustRSAKey()
+	ecKey    = mustECKey()
+	otherKey = mustRSAKey()
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func mustECKey() *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// validClaims - утверждения токена, который verify должен принять
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"aud":            testClientID,
+		"sub":            "user-1",
+		"email":          "user@test.io",
+		"email_verified": "true",
+		"given_name":     "Ivan",
+		"family_name":    "Petrov",
+		"nonce":          testNonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return s
+}
+
+// testProvider - провайдер с уже загруженными ключами, без обращений к сети
+func testProvider(t *testing.T, keys map[string]any) *Provider {
+	t.Helper()
+	p, err := NewProvider(Config{Issuer: testIssuer, ClientID: testClientID, RedirectURL: "http://localhost/cb"})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	p.keys, p.keysAt = keys, time.Now()
+	return p
+}
+
+func TestVerify(t *testing.T) {
+	keys := map[string]any{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+	with := func(mod func(c jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		mod(c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		keys    map[string]any
+		wantErr bool
+	}{
+		{
+			name:  "RS256",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()) },
+		},
+		{
+			name:  "ES256",
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodES256, "ec", ecKey, validClaims()) },
+		},
+		{
+			name:  "no kid with a single key",
+			keys:  map[string]any{"rsa": &rsaKey.PublicKey},
+			token: func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "", rsaKey, validClaims()) },
+		},
+		{
+			name:    "no kid with several keys",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "", rsaKey, validClaims()) },
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rotated", rsaKey, validClaims()) },
+			wantErr: true,
+		},
+		{
+			name:    "signed by another key",
+			token:   func(t *testing.T) string { return sign(t, jwt.SigningMethodRS256, "rsa", otherKey, validClaims()) },
+			wantErr: true,
+		},
+		{
+			name: "HS256 with public key as secret",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "alg none",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType, validClaims())
+			},
+			wantErr: true,
+		},
+		{
+			name: "nonce mismatch",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["nonce"] = "other" }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "no nonce",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { delete(c, "nonce") }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.test" }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["aud"] = "other-client" }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "several audiences with our azp",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) {
+					c["aud"] = []string{"other-client", testClientID}
+					c["azp"] = testClientID
+				}))
+			},
+		},
+		{
+			name: "several audiences without azp",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["aud"] = []string{"other-client", testClientID} }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "several audiences with foreign azp",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) {
+					c["aud"] = []string{"other-client", testClientID}
+					c["azp"] = "other-client"
+				}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired beyond leeway",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * clockLeeway).Unix() }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired within leeway",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-clockLeeway / 2).Unix() }))
+			},
+		},
+		{
+			name: "no exp",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { delete(c, "exp") }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "issued in the future",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { c["iat"] = time.Now().Add(2 * clockLeeway).Unix() }))
+			},
+			wantErr: true,
+		},
+		{
+			name: "no subject",
+			token: func(t *testing.T) string {
+				return sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, with(func(c jwt.MapClaims) { delete(c, "sub") }))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := keys
+			if tt.keys != nil {
+				k = tt.keys
+			}
+			p := testProvider(t, k)
+			id, err := p.verify(context.Background(), &metadata{Issuer: testIssuer}, tt.token(t), testNonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("verify() accepted token, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify() error = %v", err)
+			}
+			if id.Subject != "user-1" || id.Email != "user@test.io" || !id.EmailVerified || id.GivenName != "Ivan" || id.FamilyName != "Petrov" {
+				t.Errorf("verify() identity = %+v", id)
+			}
+		})
+	}
+}
+
+func TestClaimValues(t *testing.T) {
+	id := &Identity{Claims: map[string]any{
+		"groups": []any{"eb-admins", 42, "staff"},
+		"roles":  "eb-admins, staff  guests",
+		"level":  7,
+	}}
+	tests := []struct {
+		claim string
+		want  []string
+	}{
+		{claim: "groups", want: []string{"eb-admins", "staff"}},
+		{claim: "roles", want: []string{"eb-admins", "staff", "guests"}},
+		{claim: "level"},
+		{claim: "missing"},
+	}
+	for _, tt := range tests {
+		got := id.ClaimValues(tt.claim)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("ClaimValues(%q) = %q, want %q", tt.claim, got, tt.want)
+		}
+	}
+}
+
+func b64int(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+
+func TestJSONWebKey(t *testing.T) {
+	rsaJWK := jsonWebKey{Kty: "RSA", N: b64int(rsaKey.N), E: b64int(big.NewInt(int64(rsaKey.E)))}
+	ecJWK := jsonWebKey{Kty: "EC", Crv: "P-256", X: b64int(ecKey.X), Y: b64int(ecKey.Y)}
+
+	pub, err := rsaJWK.publicKey()
+	if err != nil || !rsaKey.PublicKey.Equal(pub) {
+		t.Errorf("RSA publicKey() = %v, %v", pub, err)
+	}
+	pub, err = ecJWK.publicKey()
+	if err != nil || !ecKey.PublicKey.Equal(pub) {
+		t.Errorf("EC publicKey() = %v, %v", pub, err)
+	}
+
+	offCurve := ecJWK
+	offCurve.Y = b64int(new(big.Int).Add(ecKey.Y, big.NewInt(1)))
+	for name, k := range map[string]jsonWebKey{
+		"RSA exponent 1":   {Kty: "RSA", N: rsaJWK.N, E: b64int(big.NewInt(1))},
+		"RSA without n":    {Kty: "RSA", E: rsaJWK.E},
+		"EC off curve":     offCurve,
+		"unknown curve":    {Kty: "EC", Crv: "P-192", X: ecJWK.X, Y: ecJWK.Y},
+		"symmetric key":    {Kty: "oct"},
+		"broken base64":    {Kty: "RSA", N: "***", E: rsaJWK.E},
+		"empty key params": {Kty: "EC", Crv: "P-256"},
+	} {
+		if _, err := k.publicKey(); err == nil {
+			t.Errorf("%s: publicKey() accepted invalid key", name)
+		}
+	}
+}
+
+// TestExchange - полный обмен кода на токены у тестового провайдера: discovery, token endpoint и JWKS
+func TestExchange(t *testing.T) {
+	var srv *httptest.Server
+	var gotForm map[string]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []jsonWebKey{
+			{Kty: "oct", Kid: "hmac"}, // неподдерживаемые ключи пропускаются
+			{Kty: "RSA", Kid: "enc", Use: "enc", N: b64int(otherKey.N), E: "AQAB"},
+			{Kty: "RSA", Kid: "rsa", Use: "sig", N: b64int(rsaKey.N), E: "AQAB"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gotForm = map[string]string{}
+		for k := range r.PostForm {
+			gotForm[k] = r.PostForm.Get(k)
+		}
+		claims := validClaims()
+		claims["iss"] = srv.URL
+		json.NewEncoder(w).Encode(map[string]string{"id_token": sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims)})
+	})
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	p, err := NewProvider(Config{Issuer: srv.URL, ClientID: testClientID, RedirectURL: "http://localhost/cb"})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	authURL, err := p.AuthURL(context.Background(), "state-1", testNonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthURL() error = %v", err)
+	}
+	for _, part := range []string{"code_challenge=" + Challenge(verifier), "code_challenge_method=S256", "nonce=" + testNonce, "scope=openid"} {
+		if !strings.Contains(authURL, part) {
+			t.Errorf("AuthURL() = %q, has no %q", authURL, part)
+		}
+	}
+
+	id, err := p.Exchange(context.Background(), "code-1", verifier, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if id.Subject != "user-1" || id.Issuer != srv.URL {
+		t.Errorf("Exchange() identity = %+v", id)
+	}
+	if gotForm["code"] != "code-1" || gotForm["code_verifier"] != verifier || gotForm["grant_type"] != "authorization_code" {
+		t.Errorf("token request form = %v", gotForm)
+	}
+
+	if _, err := p.Exchange(context.Background(), "code-2", verifier, "other-nonce"); err == nil {
+		t.Error("Exchange() accepted id_token with another nonce")
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://evil.test",
+			"authorization_endpoint": "https://evil.test/authorize",
+			"token_endpoint":         "https://evil.test/token",
+			"jwks_uri":               "https://evil.test/jwks",
+		})
+	}))
+	defer srv.Close()
+
+	p, err := NewProvider(Config{Issuer: srv.URL, ClientID: testClientID, RedirectURL: "http://localhost/cb"})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if _, err := p.AuthURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Error("AuthURL() accepted discovery document of another issuer")
+	}
+}
@@ -0,0 +1,85 @@
+package ebmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateAPIKey(ctx context.Context, exec repository.Executor, key *model.APIKey) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.users[key.UserID]; !ok {
+			return model.ErrUserNotFound // аналог fk_api_keys_users
+		}
+		t.apiKeySeq++
+		key.ID = t.apiKeySeq
+		now := time.Now().UTC()
+		key.Created = &now
+		stored := copyAPIKey(key)
+		stored.Key = "" // как и в БД, хранится только хэш
+		t.apiKeys[key.ID] = stored
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetAPIKeysByUser(ctx context.Context, exec repository.Executor, userID int) ([]*model.APIKey, error) {
+	keys := make([]*model.APIKey, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, k := range t.apiKeys {
+			if k.UserID == userID {
+				keys = append(keys, copyAPIKey(k))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (mr MemoryRepo) GetAPIKeyByHash(ctx context.Context, exec repository.Executor, hash string) (*model.APIKey, error) {
+	var key *model.APIKey
+	err := run(ctx, exec, func(t *tables) error {
+		for _, k := range t.apiKeys {
+			if k.KeyHash == hash {
+				key = copyAPIKey(k)
+				return nil
+			}
+		}
+		return model.ErrAPIKeyNotFound
+	})
+	return key, err
+}
+
+func (mr MemoryRepo) RevokeAPIKey(ctx context.Context, exec repository.Executor, userID int, keyID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		k, ok := t.apiKeys[keyID]
+		if !ok || k.UserID != userID {
+			return model.ErrAPIKeyNotFound
+		}
+		if k.RevokedAt == nil {
+			now := time.Now().UTC()
+			k.RevokedAt = &now
+		}
+		return nil
+	})
+}
+
+func (mr MemoryRepo) TouchAPIKey(ctx context.Context, exec repository.Executor, keyID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		k, ok := t.apiKeys[keyID]
+		if !ok {
+			return nil
+		}
+		now := time.Now().UTC()
+		if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= time.Minute {
+			k.LastUsedAt = &now
+		}
+		return nil
+	})
+}
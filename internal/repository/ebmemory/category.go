@@ -0,0 +1,159 @@
+package ebmemory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateCategory(ctx context.Context, exec repository.Executor, category *model.Category) error {
+	return run(ctx, exec, func(t *tables) error {
+		if t.categoryBySlug(category.OrgID, category.Slug) != nil {
+			return model.ErrCategoryExists // аналог idx_categories_org_slug
+		}
+		t.categorySeq++
+		category.ID = t.categorySeq
+		now := time.Now().UTC()
+		category.Created = &now
+		t.categories[category.ID] = copyCategory(category)
+		return nil
+	})
+}
+
+// GetCategoriesList - категории организации по имени
+func (mr MemoryRepo) GetCategoriesList(ctx context.Context, exec repository.Executor, orgID int) ([]*model.Category, error) {
+	categories := make([]*model.Category, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, c := range t.categories {
+			if c.OrgID == orgID {
+				categories = append(categories, copyCategory(c))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Name != categories[j].Name {
+			return categories[i].Name < categories[j].Name
+		}
+		return categories[i].ID < categories[j].ID
+	})
+	return categories, nil
+}
+
+// UpdateCategory - новый slug переносится и в шаблоны серий, как в Postgres
+func (mr MemoryRepo) UpdateCategory(ctx context.Context, exec repository.Executor, category *model.Category) error {
+	return run(ctx, exec, func(t *tables) error {
+		c, ok := t.categories[category.ID]
+		if !ok || c.OrgID != category.OrgID {
+			return model.ErrCategoryNotFound
+		}
+		if other := t.categoryBySlug(category.OrgID, category.Slug); other != nil && other.ID != c.ID {
+			return model.ErrCategoryExists
+		}
+		for _, s := range t.series {
+			if s.OrgID != c.OrgID {
+				continue
+			}
+			for i, slug := range s.Template.Categories {
+				if slug == c.Slug {
+					s.Template.Categories[i] = category.Slug
+				}
+			}
+		}
+		c.Name, c.Slug = category.Name, category.Slug
+		category.Created = copyTime(c.Created)
+		return nil
+	})
+}
+
+// DeleteCategory - привязки ивентов к категории удаляются каскадно, как в схеме Postgres
+func (mr MemoryRepo) DeleteCategory(ctx context.Context, exec repository.Executor, orgID int, categoryID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		c, ok := t.categories[categoryID]
+		if !ok || c.OrgID != orgID {
+			return model.ErrCategoryNotFound
+		}
+		delete(t.categories, categoryID)
+		for eventID, ids := range t.eventCategories {
+			t.eventCategories[eventID] = slices.DeleteFunc(ids, func(id int) bool { return id == categoryID })
+		}
+		return nil
+	})
+}
+
+// LoadEventLabels - категории и теги ивентов по алфавиту
+func (mr MemoryRepo) LoadEventLabels(ctx context.Context, exec repository.Executor, events []*model.Event) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, e := range events {
+			e.Categories, e.Tags = nil, nil
+			for _, id := range t.eventCategories[e.ID] {
+				if c, ok := t.categories[id]; ok {
+					e.Categories = append(e.Categories, c.Slug)
+				}
+			}
+			slices.Sort(e.Categories)
+			if tags := t.eventTags[e.ID]; len(tags) > 0 {
+				e.Tags = slices.Sorted(slices.Values(tags))
+			}
+		}
+		return nil
+	})
+}
+
+// SetEventCategories заменяет категории ивента; slug ищутся только среди категорий его организации
+func (mr MemoryRepo) SetEventCategories(ctx context.Context, exec repository.Executor, orgID int, eventID int, slugs []string) error {
+	return run(ctx, exec, func(t *tables) error {
+		ids := make([]int, 0, len(slugs))
+		for _, slug := range slugs {
+			c := t.categoryBySlug(orgID, slug)
+			if c == nil {
+				return model.ErrCategoryNotFound
+			}
+			ids = append(ids, c.ID)
+		}
+		if len(ids) == 0 {
+			delete(t.eventCategories, eventID)
+			return nil
+		}
+		t.eventCategories[eventID] = ids
+		return nil
+	})
+}
+
+// SetEventTags заменяет теги ивента
+func (mr MemoryRepo) SetEventTags(ctx context.Context, exec repository.Executor, eventID int, tags []string) error {
+	return run(ctx, exec, func(t *tables) error {
+		if len(tags) == 0 {
+			delete(t.eventTags, eventID)
+			return nil
+		}
+		t.eventTags[eventID] = slices.Clone(tags)
+		return nil
+	})
+}
+
+func (t *tables) categoryBySlug(orgID int, slug string) *model.Category {
+	for _, c := range t.categories {
+		if c.OrgID == orgID && c.Slug == slug {
+			return c
+		}
+	}
+	return nil
+}
+
+// inCategory - входит ли ивент в категорию с этим slug
+func (t *tables) inCategory(e *model.Event, slug string) bool {
+	for _, id := range t.eventCategories[e.ID] {
+		if c, ok := t.categories[id]; ok && c.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
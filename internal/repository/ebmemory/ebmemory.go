@@ -0,0 +1,560 @@
+package ebmemory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// MemoryRepo - реализация EBRepo поверх Store; как и PostgresRepo, сама состояния не хранит и работает с переданным исполнителем
+type MemoryRepo struct{}
+
+func NewMemoryRepo() repository.EBRepo {
+	return &MemoryRepo{}
+}
+
+// CreateEvent - создание ивента админом или организатором, создатель записывается владельцем
+func (mr MemoryRepo) CreateEvent(ctx context.Context, exec repository.Executor, newEvent *model.Event) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.orgs[newEvent.OrgID]; !ok {
+			return model.ErrOrgNotFound // аналог fk_events_organizations
+		}
+		t.eventSeq++
+		newEvent.ID = t.eventSeq
+		if newEvent.Created == nil {
+			now := time.Now().UTC()
+			newEvent.Created = &now
+		}
+		stored := copyEvent(newEvent)
+		stored.Categories, stored.Tags = nil, nil // связи хранятся отдельно, их задают SetEventCategories/SetEventTags
+		t.events[newEvent.ID] = stored
+		return nil
+	})
+}
+
+func (mr MemoryRepo) CreateBook(ctx context.Context, exec repository.Executor, newBook *model.Book) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.events[newBook.EventID]; !ok {
+			return model.ErrEventNotFound // аналог fk_bookings_events
+		}
+		if _, ok := t.users[newBook.UserID]; !ok {
+			return model.ErrUserNotFound // аналог fk_bookings_users
+		}
+		t.bookSeq++
+		newBook.ID = t.bookSeq
+		now := time.Now().UTC()
+		newBook.Created = &now
+		t.books[newBook.ID] = copyBook(newBook)
+		return nil
+	})
+}
+
+func (mr MemoryRepo) CreateUser(ctx context.Context, exec repository.Executor, newUser *model.User) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, u := range t.users {
+			if u.Email == newUser.Email {
+				return model.ErrUserAlreadyExists
+			}
+		}
+		t.userSeq++
+		newUser.ID = t.userSeq
+		now := time.Now().UTC()
+		newUser.Created = &now
+		stored := copyUser(newUser)
+		stored.Role = "" // роль хранится в участии в организации
+		t.users[newUser.ID] = stored
+		return nil
+	})
+}
+
+// DeleteEvent - удаление ивента владельцем или админом; брони, очередь и соорганизаторы ивента удаляются каскадно, как в схеме Postgres
+func (mr MemoryRepo) DeleteEvent(ctx context.Context, exec repository.Executor, eventID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.events[eventID]; !ok {
+			return model.ErrEventNotFound
+		}
+		delete(t.events, eventID)
+		for id, b := range t.books {
+			if b.EventID == eventID {
+				delete(t.books, id)
+			}
+		}
+		for id, w := range t.waitlist {
+			if w.EventID == eventID {
+				delete(t.waitlist, id)
+			}
+		}
+		for id, eo := range t.organizers {
+			if eo.EventID == eventID {
+				delete(t.organizers, id)
+			}
+		}
+		delete(t.eventCategories, eventID)
+		delete(t.eventTags, eventID)
+		return nil
+	})
+}
+
+// MarkBookExpired - эксклюзивно для воркеров BookCleaner/BookScheduler: бронь остается в хранилище для аналитики
+func (mr MemoryRepo) MarkBookExpired(ctx context.Context, exec repository.Executor, bookID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		book, ok := t.books[bookID]
+		if !ok {
+			return model.ErrBookNotFound
+		}
+		now := time.Now().UTC()
+		book.Status = model.BookStatusExpired
+		book.ExpiredAt = &now
+		return nil
+	})
+}
+
+// MarkBookCancelled - отмена брони с отметкой времени, от которой BookPurger считает срок хранения
+func (mr MemoryRepo) MarkBookCancelled(ctx context.Context, exec repository.Executor, bookID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		book, ok := t.books[bookID]
+		if !ok {
+			return model.ErrBookNotFound
+		}
+		now := time.Now().UTC()
+		book.Status = model.BookStatusCancelled
+		book.CancelledAt = &now
+		return nil
+	})
+}
+
+// PurgeBooks - эксклюзивно для воркера BookPurger: окончательное удаление истекших и отмененных броней,
+// истекших или отмененных раньше before
+func (mr MemoryRepo) PurgeBooks(ctx context.Context, exec repository.Executor, before time.Time) (int, error) {
+	purged := 0
+	err := run(ctx, exec, func(t *tables) error {
+		for id, b := range t.books {
+			if b.Status != model.BookStatusExpired && b.Status != model.BookStatusCancelled {
+				continue
+			}
+			stamp := b.ExpiredAt
+			if stamp == nil {
+				stamp = b.CancelledAt
+			}
+			if stamp != nil && stamp.Before(before) {
+				delete(t.books, id)
+				purged++
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+func (mr MemoryRepo) UpdateBookStatus(ctx context.Context, exec repository.Executor, bookID int, newStatus string) error {
+	return run(ctx, exec, func(t *tables) error {
+		book, ok := t.books[bookID]
+		if !ok {
+			return model.ErrBookNotFound
+		}
+		book.Status = newStatus
+		return nil
+	})
+}
+
+// UpdateEvent - обновление редактируемых полей ивента, только для админа
+func (mr MemoryRepo) UpdateEvent(ctx context.Context, exec repository.Executor, event *model.Event) error {
+	return run(ctx, exec, func(t *tables) error {
+		e, ok := t.events[event.ID]
+		if !ok {
+			return model.ErrEventNotFound
+		}
+		e.Title = event.Title
+		e.Descr = event.Descr
+		e.StartsAt = event.StartsAt
+		e.EndsAt = copyCustomTime(event.EndsAt)
+		e.TimeZone = event.TimeZone
+		e.BookWindow = event.BookWindow
+		e.TotalSeats = event.TotalSeats
+		e.AvailSeats = event.AvailSeats
+		e.MaxPerBook = event.MaxPerBook
+		e.Venue = event.Venue
+		e.Address = event.Address
+		e.Contact = event.Contact
+		e.CoverURL = event.CoverURL
+		return nil
+	})
+}
+
+// CancelEvent - отмена ивента админом: все места снова свободны, так как брони отменяются в той же транзакции
+func (mr MemoryRepo) CancelEvent(ctx context.Context, exec repository.Executor, eventID int, reason string) error {
+	return run(ctx, exec, func(t *tables) error {
+		e, ok := t.events[eventID]
+		if !ok {
+			return model.ErrEventNotFound
+		}
+		e.Status = model.EventStatusCancelled
+		e.CancelReason = reason
+		e.AvailSeats = e.TotalSeats
+		return nil
+	})
+}
+
+// CancelBooksByEvent - отмена броней ивента с указанными статусами, возвращает отмененные брони с имейлами пользователей для уведомлений
+func (mr MemoryRepo) CancelBooksByEvent(ctx context.Context, exec repository.Executor, eventID int, statuses []string) ([]*model.BookWithUser, error) {
+	books := make([]*model.BookWithUser, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		now := time.Now().UTC()
+		for _, b := range t.books {
+			if b.EventID != eventID || !slices.Contains(statuses, b.Status) {
+				continue
+			}
+			b.Status = model.BookStatusCancelled
+			b.CancelledAt = &now
+			book := &model.BookWithUser{Book: *copyBook(b)}
+			if u, ok := t.users[b.UserID]; ok {
+				book.Email = u.Email
+			}
+			books = append(books, book)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books, nil
+}
+
+// UpdateEventStatus - эксклюзивно для воркера EventSweeper
+func (mr MemoryRepo) UpdateEventStatus(ctx context.Context, exec repository.Executor, eventID int, newStatus string) error {
+	return run(ctx, exec, func(t *tables) error {
+		e, ok := t.events[eventID]
+		if !ok {
+			return model.ErrEventNotFound
+		}
+		e.Status = newStatus
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetEventByID(ctx context.Context, exec repository.Executor, orgID int, id int) (*model.Event, error) {
+	var event *model.Event
+	err := run(ctx, exec, func(t *tables) error {
+		e, ok := t.events[id]
+		if !ok || e.OrgID != orgID {
+			return model.ErrEventNotFound
+		}
+		event = copyEvent(e)
+		return nil
+	})
+	return event, err
+}
+
+// GetEventByIDNoLock - в in-memory хранилище совпадает с GetEventByID: блокировка берется на уровне транзакции
+func (mr MemoryRepo) GetEventByIDNoLock(ctx context.Context, exec repository.Executor, orgID int, id int) (*model.Event, error) {
+	return mr.GetEventByID(ctx, exec, orgID, id)
+}
+
+// GetEventsList - страница ивентов организации; фильтры и порядок повторяют запрос Postgres
+func (mr MemoryRepo) GetEventsList(ctx context.Context, exec repository.Executor, filter model.EventFilter) ([]*model.Event, error) {
+	events := make([]*model.Event, 0)
+	q := strings.ToLower(filter.Query)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, e := range t.events {
+			if e.OrgID != filter.OrgID {
+				continue
+			}
+			// пользователю - только актуальные ивенты, организатору - ещё и свои в любом статусе
+			if !filter.AllStatuses && e.Status != model.EventStatusActual &&
+				(filter.ManagedBy == 0 || !t.managedBy(e, filter.ManagedBy)) {
+				continue
+			}
+			if (filter.Status != "" && e.Status != filter.Status) ||
+				(filter.From != nil && e.StartsAt.Before(*filter.From)) ||
+				(filter.To != nil && !e.StartsAt.Before(*filter.To)) ||
+				(q != "" && !strings.Contains(strings.ToLower(e.Title), q)) ||
+				(filter.HasSeats && e.AvailSeats < 1) ||
+				(filter.Category != "" && !t.inCategory(e, filter.Category)) ||
+				(filter.Tag != "" && !slices.Contains(t.eventTags[e.ID], filter.Tag)) {
+				continue
+			}
+			events = append(events, copyEvent(e))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := filter.Sort == model.SortDate || filter.Sort == model.SortDateDesc
+	desc := filter.Sort == model.SortCreatedDesc || filter.Sort == model.SortDateDesc
+	// less - порядок ключей (дата, id) в выбранной сортировке; для убывающих сравнение разворачивается
+	less := func(a, b model.PageKey) bool {
+		if byDate && !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date) != desc
+		}
+		return a.ID != b.ID && (a.ID < b.ID) != desc
+	}
+	key := func(e *model.Event) model.PageKey { return model.PageKey{ID: e.ID, Date: e.StartsAt.Time} }
+	sort.Slice(events, func(i, j int) bool { return less(key(events[i]), key(events[j])) })
+
+	start := 0
+	if filter.After != nil {
+		start = sort.Search(len(events), func(i int) bool { return less(*filter.After, key(events[i])) })
+	}
+	return page(events, start, filter.Limit), nil
+}
+
+func (mr MemoryRepo) GetBookByID(ctx context.Context, exec repository.Executor, orgID int, id int) (*model.Book, error) {
+	var book *model.Book
+	err := run(ctx, exec, func(t *tables) error {
+		b, ok := t.books[id]
+		if !ok || b.OrgID != orgID {
+			return model.ErrBookNotFound
+		}
+		book = copyBook(b)
+		return nil
+	})
+	return book, err
+}
+
+func (mr MemoryRepo) GetBookByIDNoLock(ctx context.Context, exec repository.Executor, orgID int, id int) (*model.Book, error) {
+	return mr.GetBookByID(ctx, exec, orgID, id)
+}
+
+// GetBooksListByUser - страница броней пользователя в организации; сортировка только по порядку создания
+func (mr MemoryRepo) GetBooksListByUser(ctx context.Context, exec repository.Executor, filter model.BookFilter) ([]*model.Book, error) {
+	books := make([]*model.Book, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, b := range t.books {
+			if b.UserID != filter.UserID || b.OrgID != filter.OrgID {
+				continue
+			}
+			if (filter.Status != "" && b.Status != filter.Status) ||
+				(filter.From != nil && b.Created.Before(*filter.From)) ||
+				(filter.To != nil && !b.Created.Before(*filter.To)) {
+				continue
+			}
+			books = append(books, copyBook(b))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	desc := filter.Sort == model.SortCreatedDesc
+	sort.Slice(books, func(i, j int) bool { return (books[i].ID < books[j].ID) != desc })
+
+	start := 0
+	if filter.After != nil {
+		start = sort.Search(len(books), func(i int) bool {
+			return books[i].ID != filter.After.ID && (books[i].ID > filter.After.ID) != desc // строго после курсора
+		})
+	}
+	return page(books, start, filter.Limit), nil
+}
+
+// GetBooksListByEvent - все брони ивента вместе с имейлами пользователей, только для тех, кто управляет ивентом
+func (mr MemoryRepo) GetBooksListByEvent(ctx context.Context, exec repository.Executor, eventID int) ([]*model.BookWithUser, error) {
+	books := make([]*model.BookWithUser, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, b := range t.books {
+			if b.EventID != eventID {
+				continue
+			}
+			book := &model.BookWithUser{Book: *copyBook(b)}
+			if u, ok := t.users[b.UserID]; ok {
+				book.Email = u.Email
+			}
+			books = append(books, book)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books, nil
+}
+
+// GetBookStatsByEvent - количество броней ивента по статусам и ближайший дедлайн неподтвержденной брони
+func (mr MemoryRepo) GetBookStatsByEvent(ctx context.Context, exec repository.Executor, eventID int) (*model.BookStats, error) {
+	var stats model.BookStats
+	err := run(ctx, exec, func(t *tables) error {
+		now := time.Now()
+		for _, b := range t.books {
+			if b.EventID != eventID {
+				continue
+			}
+			switch b.Status {
+			case model.BookStatusCreated:
+				stats.Created++
+				if b.ConfirmDeadline != nil && b.ConfirmDeadline.After(now) &&
+					(stats.NextExpiry == nil || b.ConfirmDeadline.Before(*stats.NextExpiry)) {
+					stats.NextExpiry = copyTime(b.ConfirmDeadline)
+				}
+			case model.BookStatusConfirmed:
+				stats.Confirmed++
+			case model.BookStatusCancelled:
+				stats.Cancelled++
+			case model.BookStatusExpired:
+				stats.Expired++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetExpiredBooksList - эксклюзивно для воркера BookCleaner
+func (mr MemoryRepo) GetExpiredBooksList(ctx context.Context, exec repository.Executor) ([]*model.Book, error) {
+	books := make([]*model.Book, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		now := time.Now()
+		for _, b := range t.books {
+			if b.ConfirmDeadline != nil && b.ConfirmDeadline.Before(now) && b.Status == model.BookStatusCreated {
+				books = append(books, copyBook(b))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].EventID != books[j].EventID {
+			return books[i].EventID < books[j].EventID
+		}
+		return books[i].ID < books[j].ID
+	})
+	return books, nil
+}
+
+// GetPendingBooksList - неподтвержденные брони для восстановления очереди планировщика истечения
+func (mr MemoryRepo) GetPendingBooksList(ctx context.Context, exec repository.Executor) ([]*model.Book, error) {
+	books := make([]*model.Book, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, b := range t.books {
+			if b.Status == model.BookStatusCreated && b.ConfirmDeadline != nil {
+				books = append(books, copyBook(b))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(books, func(i, j int) bool { return books[i].ConfirmDeadline.Before(*books[j].ConfirmDeadline) })
+	return books, nil
+}
+
+// GetPastEventsList - эксклюзивно для воркера EventSweeper: актуальные ивенты, которые уже начались
+func (mr MemoryRepo) GetPastEventsList(ctx context.Context, exec repository.Executor) ([]*model.Event, error) {
+	events := make([]*model.Event, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		now := time.Now()
+		for _, e := range t.events {
+			if e.Status == model.EventStatusActual && !e.StartsAt.After(now) {
+				events = append(events, copyEvent(e))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (mr MemoryRepo) GetUserByID(ctx context.Context, exec repository.Executor, id int) (*model.User, error) {
+	var user *model.User
+	err := run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[id]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		user = copyUser(u)
+		return nil
+	})
+	return user, err
+}
+
+func (mr MemoryRepo) GetUserByEmail(ctx context.Context, exec repository.Executor, email string) (*model.User, error) {
+	var user *model.User
+	err := run(ctx, exec, func(t *tables) error {
+		for _, u := range t.users {
+			if u.Email == email {
+				user = copyUser(u)
+				return nil
+			}
+		}
+		return model.ErrUserNotFound
+	})
+	return user, err
+}
+
+// IncreaseAvailSeatsByEventID - возврат n мест ивенту при отмене/истечении брони
+func (mr MemoryRepo) IncreaseAvailSeatsByEventID(ctx context.Context, exec repository.Executor, eventID int, n int) error {
+	return run(ctx, exec, func(t *tables) error {
+		event, ok := t.events[eventID]
+		if !ok {
+			return model.ErrEventNotFound
+		}
+		event.AvailSeats += n
+		return nil
+	})
+}
+
+// DecreaseAvailSeatsByEventID - атомарное списание n мест: если свободных мест меньше n, ничего не меняется
+func (mr MemoryRepo) DecreaseAvailSeatsByEventID(ctx context.Context, exec repository.Executor, eventID int, n int) error {
+	return run(ctx, exec, func(t *tables) error {
+		event, ok := t.events[eventID]
+		if !ok {
+			return model.ErrEventNotFound
+		}
+		if event.AvailSeats < n {
+			return model.ErrNoSeatsAvailable // аналог CHECK (avail_seats >= 0)
+		}
+		event.AvailSeats -= n
+		return nil
+	})
+}
+
+// page - срез отсортированного списка с позиции start; limit 0 - до конца
+func page[T any](items []T, start, limit int) []T {
+	if start >= len(items) {
+		return make([]T, 0)
+	}
+	end := len(items)
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	return items[start:end]
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func copyCustomTime(t *model.CustomTime) *model.CustomTime {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
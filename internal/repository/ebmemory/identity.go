@@ -0,0 +1,43 @@
+package ebmemory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateUserIdentity(ctx context.Context, exec repository.Executor, identity *model.UserIdentity) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.users[identity.UserID]; !ok {
+			return model.ErrUserNotFound // аналог fk_user_identities_users
+		}
+		for _, ui := range t.identities {
+			if ui.Issuer == identity.Issuer && ui.Subject == identity.Subject {
+				return errors.New("identity is already linked") // аналог idx_user_identities_subject
+			}
+		}
+		t.identitySeq++
+		identity.ID = t.identitySeq
+		now := time.Now().UTC()
+		identity.Created = &now
+		t.identities[identity.ID] = copyUserIdentity(identity)
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetUserIdentity(ctx context.Context, exec repository.Executor, issuer string, subject string) (*model.UserIdentity, error) {
+	var identity *model.UserIdentity
+	err := run(ctx, exec, func(t *tables) error {
+		for _, ui := range t.identities {
+			if ui.Issuer == issuer && ui.Subject == subject {
+				identity = copyUserIdentity(ui)
+				return nil
+			}
+		}
+		return model.ErrIdentityNotFound
+	})
+	return identity, err
+}
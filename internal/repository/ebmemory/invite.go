@@ -0,0 +1,66 @@
+package ebmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateInvite(ctx context.Context, exec repository.Executor, invite *model.Invite) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, i := range t.invites {
+			if i.TokenHash == invite.TokenHash {
+				return model.ErrInviteExists // аналог idx_invites_token_hash
+			}
+		}
+		t.inviteSeq++
+		invite.ID = t.inviteSeq
+		now := time.Now().UTC()
+		invite.Created = &now
+		stored := copyInvite(invite)
+		stored.Token = "" // как и в Postgres, хранится только хэш
+		t.invites[invite.ID] = stored
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetInviteByTokenHash(ctx context.Context, exec repository.Executor, hash string) (*model.Invite, error) {
+	var invite *model.Invite
+	err := run(ctx, exec, func(t *tables) error {
+		for _, i := range t.invites {
+			if i.TokenHash == hash {
+				invite = copyInvite(i)
+				return nil
+			}
+		}
+		return model.ErrInviteNotFound
+	})
+	return invite, err
+}
+
+func (mr MemoryRepo) ExtendUnusedInvite(ctx context.Context, exec repository.Executor, hash string, expiresAt time.Time) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, i := range t.invites {
+			if i.TokenHash == hash && i.UsedAt == nil {
+				i.ExpiresAt = expiresAt
+				return nil
+			}
+		}
+		return model.ErrInviteNotFound
+	})
+}
+
+func (mr MemoryRepo) MarkInviteUsed(ctx context.Context, exec repository.Executor, inviteID int, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		i, ok := t.invites[inviteID]
+		if !ok {
+			return model.ErrInviteNotFound
+		}
+		now := time.Now().UTC()
+		i.UsedBy = userID
+		i.UsedAt = &now
+		return nil
+	})
+}
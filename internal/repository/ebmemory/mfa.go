@@ -0,0 +1,92 @@
+package ebmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) SetTOTPSecret(ctx context.Context, exec repository.Executor, userID int, secret string) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		u.TOTPSecret = secret
+		u.TOTPEnabledAt = nil
+		u.TOTPLastStep = 0
+		return nil
+	})
+}
+
+func (mr MemoryRepo) EnableTOTP(ctx context.Context, exec repository.Executor, userID int, step int64) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok || u.TOTPSecret == "" {
+			return model.ErrMFANotSetUp
+		}
+		now := time.Now().UTC()
+		u.TOTPEnabledAt = &now
+		u.TOTPLastStep = step
+		return nil
+	})
+}
+
+func (mr MemoryRepo) DisableTOTP(ctx context.Context, exec repository.Executor, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		u.TOTPSecret = ""
+		u.TOTPEnabledAt = nil
+		u.TOTPLastStep = 0
+		for id, rc := range t.recovery {
+			if rc.UserID == userID {
+				delete(t.recovery, id)
+			}
+		}
+		return nil
+	})
+}
+
+func (mr MemoryRepo) UseTOTPStep(ctx context.Context, exec repository.Executor, userID int, step int64) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok || u.TOTPLastStep >= step {
+			return model.ErrInvalidMFACode
+		}
+		u.TOTPLastStep = step
+		return nil
+	})
+}
+
+func (mr MemoryRepo) ReplaceRecoveryCodes(ctx context.Context, exec repository.Executor, userID int, hashes []string) error {
+	return run(ctx, exec, func(t *tables) error {
+		for id, rc := range t.recovery {
+			if rc.UserID == userID {
+				delete(t.recovery, id)
+			}
+		}
+		for _, hash := range hashes {
+			t.recoverySeq++
+			t.recovery[t.recoverySeq] = &model.RecoveryCode{ID: t.recoverySeq, UserID: userID, CodeHash: hash}
+		}
+		return nil
+	})
+}
+
+func (mr MemoryRepo) UseRecoveryCode(ctx context.Context, exec repository.Executor, userID int, hash string) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, rc := range t.recovery {
+			if rc.UserID == userID && rc.CodeHash == hash && rc.UsedAt == nil {
+				now := time.Now().UTC()
+				rc.UsedAt = &now
+				return nil
+			}
+		}
+		return model.ErrInvalidMFACode
+	})
+}
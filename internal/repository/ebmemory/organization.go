@@ -0,0 +1,169 @@
+package ebmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// orgMember - строка таблицы org_members
+type orgMember struct {
+	OrgID   int
+	UserID  int
+	Role    string
+	Created *time.Time
+}
+
+func (mr MemoryRepo) CreateOrganization(ctx context.Context, exec repository.Executor, org *model.Organization) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, o := range t.orgs {
+			if o.Slug == org.Slug {
+				return model.ErrOrgSlugTaken // аналог idx_organizations_slug
+			}
+		}
+		t.orgSeq++
+		org.ID = t.orgSeq
+		now := time.Now().UTC()
+		org.Created = &now
+		stored := copyOrganization(org)
+		stored.Role, stored.Current = "", false
+		t.orgs[org.ID] = stored
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetOrganizationBySlug(ctx context.Context, exec repository.Executor, slug string) (*model.Organization, error) {
+	var org *model.Organization
+	err := run(ctx, exec, func(t *tables) error {
+		for _, o := range t.orgs {
+			if o.Slug == slug {
+				org = copyOrganization(o)
+				return nil
+			}
+		}
+		return model.ErrOrgNotFound
+	})
+	return org, err
+}
+
+// GetUserOrganizations - организации пользователя с его ролью в каждой, по порядку создания
+func (mr MemoryRepo) GetUserOrganizations(ctx context.Context, exec repository.Executor, userID int) ([]*model.Organization, error) {
+	orgs := make([]*model.Organization, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, m := range t.members {
+			if m.UserID != userID {
+				continue
+			}
+			o, ok := t.orgs[m.OrgID]
+			if !ok {
+				continue
+			}
+			c := copyOrganization(o)
+			c.Role = m.Role
+			orgs = append(orgs, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs, nil
+}
+
+func (mr MemoryRepo) AddOrgMember(ctx context.Context, exec repository.Executor, orgID int, userID int, role string) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.orgs[orgID]; !ok {
+			return model.ErrOrgNotFound // аналог fk_org_members_organizations
+		}
+		if _, ok := t.users[userID]; !ok {
+			return model.ErrUserNotFound // аналог fk_org_members_users
+		}
+		if t.member(orgID, userID) != nil {
+			return model.ErrAlreadyOrgMember // аналог первичного ключа (org_id, user_id)
+		}
+		t.memberSeq++
+		now := time.Now().UTC()
+		t.members[t.memberSeq] = &orgMember{OrgID: orgID, UserID: userID, Role: role, Created: &now}
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetOrgMemberRole(ctx context.Context, exec repository.Executor, orgID int, userID int) (string, error) {
+	var role string
+	err := run(ctx, exec, func(t *tables) error {
+		m := t.member(orgID, userID)
+		if m == nil {
+			return model.ErrNotOrgMember
+		}
+		role = m.Role
+		return nil
+	})
+	return role, err
+}
+
+func (mr MemoryRepo) UpdateOrgMemberRole(ctx context.Context, exec repository.Executor, orgID int, userID int, role string) error {
+	return run(ctx, exec, func(t *tables) error {
+		m := t.member(orgID, userID)
+		if m == nil {
+			return model.ErrNotOrgMember
+		}
+		m.Role = role
+		return nil
+	})
+}
+
+// RemoveOrgMember - исключение из организации; пользователь перестает быть и соорганизатором её ивентов,
+// а его брони остаются - места держатся до отмены или истечения
+func (mr MemoryRepo) RemoveOrgMember(ctx context.Context, exec repository.Executor, orgID int, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		found := false
+		for id, m := range t.members {
+			if m.OrgID == orgID && m.UserID == userID {
+				delete(t.members, id)
+				found = true
+			}
+		}
+		if !found {
+			return model.ErrNotOrgMember
+		}
+		for id, eo := range t.organizers {
+			if e, ok := t.events[eo.EventID]; ok && e.OrgID == orgID && eo.UserID == userID {
+				delete(t.organizers, id)
+			}
+		}
+		for id, w := range t.waitlist {
+			if e, ok := t.events[w.EventID]; ok && e.OrgID == orgID && w.UserID == userID {
+				delete(t.waitlist, id)
+			}
+		}
+		return nil
+	})
+}
+
+// HasOrgMemberWithRole - есть ли в организации хотя бы один участник с ролью (для бутстрапа первого админа)
+func (mr MemoryRepo) HasOrgMemberWithRole(ctx context.Context, exec repository.Executor, orgID int, role string) (bool, error) {
+	found := false
+	err := run(ctx, exec, func(t *tables) error {
+		for _, m := range t.members {
+			if m.OrgID == orgID && m.Role == role {
+				found = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (t *tables) member(orgID, userID int) *orgMember {
+	for _, m := range t.members {
+		if m.OrgID == orgID && m.UserID == userID {
+			return m
+		}
+	}
+	return nil
+}
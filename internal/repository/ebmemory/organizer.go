@@ -0,0 +1,95 @@
+package ebmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) AddEventOrganizer(ctx context.Context, exec repository.Executor, eventID int, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.events[eventID]; !ok {
+			return model.ErrEventNotFound // аналог fk_event_organizers_events
+		}
+		if _, ok := t.users[userID]; !ok {
+			return model.ErrUserNotFound // аналог fk_event_organizers_users
+		}
+		if t.isOrganizer(eventID, userID) {
+			return model.ErrAlreadyOrganizer // аналог первичного ключа (event_id, user_id)
+		}
+		t.organizerSeq++
+		now := time.Now().UTC()
+		t.organizers[t.organizerSeq] = &model.EventOrganizer{EventID: eventID, UserID: userID, Added: &now}
+		return nil
+	})
+}
+
+func (mr MemoryRepo) RemoveEventOrganizer(ctx context.Context, exec repository.Executor, eventID int, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		for id, eo := range t.organizers {
+			if eo.EventID == eventID && eo.UserID == userID {
+				delete(t.organizers, id)
+				return nil
+			}
+		}
+		return model.ErrOrganizerNotFound
+	})
+}
+
+// GetEventOrganizers - соорганизаторы ивента с имейлами в порядке назначения
+func (mr MemoryRepo) GetEventOrganizers(ctx context.Context, exec repository.Executor, eventID int) ([]*model.EventOrganizer, error) {
+	type seqOrganizer struct {
+		seq int
+		eo  *model.EventOrganizer
+	}
+	var found []seqOrganizer
+	err := run(ctx, exec, func(t *tables) error {
+		for id, eo := range t.organizers {
+			if eo.EventID != eventID {
+				continue
+			}
+			c := copyEventOrganizer(eo)
+			if u, ok := t.users[eo.UserID]; ok {
+				c.Email = u.Email
+			}
+			found = append(found, seqOrganizer{seq: id, eo: c})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].seq < found[j].seq })
+	organizers := make([]*model.EventOrganizer, 0, len(found))
+	for _, f := range found {
+		organizers = append(organizers, f.eo)
+	}
+	return organizers, nil
+}
+
+func (mr MemoryRepo) IsEventOrganizer(ctx context.Context, exec repository.Executor, eventID int, userID int) (bool, error) {
+	var found bool
+	err := run(ctx, exec, func(t *tables) error {
+		found = t.isOrganizer(eventID, userID)
+		return nil
+	})
+	return found, err
+}
+
+func (t *tables) isOrganizer(eventID int, userID int) bool {
+	for _, eo := range t.organizers {
+		if eo.EventID == eventID && eo.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// managedBy - владеет ли пользователь ивентом или соорганизует его
+func (t *tables) managedBy(e *model.Event, userID int) bool {
+	return e.OwnerID == userID || t.isOrganizer(e.ID, userID)
+}
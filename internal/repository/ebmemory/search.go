@@ -0,0 +1,118 @@
+package ebmemory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// SearchEvents - упрощенный аналог полнотекстового поиска Postgres для демо-режима: без стемминга,
+// слово запроса совпадает с любой подстрокой без учета регистра; -слово исключает ивент, кавычки и or игнорируются.
+// Вес совпадения в названии - 1, в описании - 0.4, как у весов A и B в ts_rank_cd
+func (mr MemoryRepo) SearchEvents(ctx context.Context, exec repository.Executor, search model.EventSearch) ([]*model.EventSearchResult, error) {
+	include, exclude := searchTerms(search.Query)
+	results := make([]*model.EventSearchResult, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, e := range t.events {
+			if e.OrgID != search.OrgID {
+				continue
+			}
+			if !search.AllStatuses && e.Status != model.EventStatusActual &&
+				(search.ManagedBy == 0 || !t.managedBy(e, search.ManagedBy)) {
+				continue
+			}
+			title, descr := strings.ToLower(e.Title), strings.ToLower(e.Descr)
+			rank, matched := 0.0, len(include) > 0
+			for _, term := range include {
+				inTitle, inDescr := strings.Count(title, term), strings.Count(descr, term)
+				if inTitle+inDescr == 0 {
+					matched = false
+					break
+				}
+				rank += float64(inTitle) + 0.4*float64(inDescr)
+			}
+			for _, term := range exclude {
+				if strings.Contains(title, term) || strings.Contains(descr, term) {
+					matched = false
+				}
+			}
+			if !matched {
+				continue
+			}
+			res := &model.EventSearchResult{Event: *copyEvent(e), Rank: rank, TitleHighlight: highlight(e.Title, include)}
+			if snippet := highlight(e.Descr, include); snippet != e.Descr {
+				res.Snippet = snippet
+			}
+			results = append(results, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if search.Offset >= len(results) {
+		return make([]*model.EventSearchResult, 0), nil
+	}
+	end := min(search.Offset+search.Limit, len(results))
+	return results[search.Offset:end], nil
+}
+
+// searchTerms - слова запроса в нижнем регистре: искомые и исключенные
+func searchTerms(query string) (include, exclude []string) {
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(query, `"`, " "))) {
+		switch {
+		case word == "or":
+		case strings.HasPrefix(word, "-"):
+			if w := strings.TrimPrefix(word, "-"); w != "" {
+				exclude = append(exclude, w)
+			}
+		default:
+			include = append(include, word)
+		}
+	}
+	return include, exclude
+}
+
+// highlight обрамляет вхождения слов в <mark></mark>, сохраняя исходный регистр текста
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) { // смена регистра изменила длину в байтах - позиции не совпадут
+		return text
+	}
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		for from := 0; ; {
+			i := strings.Index(lower[from:], term)
+			if i < 0 {
+				break
+			}
+			for k := from + i; k < from+i+len(term); k++ {
+				marked[k] = true
+			}
+			from += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteByte(text[i])
+		if marked[i] && (i == len(text)-1 || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	return b.String()
+}
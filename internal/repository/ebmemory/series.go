@@ -0,0 +1,95 @@
+package ebmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateSeries(ctx context.Context, exec repository.Executor, series *model.EventSeries) error {
+	return run(ctx, exec, func(t *tables) error {
+		t.seriesSeq++
+		series.ID = t.seriesSeq
+		now := time.Now().UTC()
+		series.Created = &now
+		t.series[series.ID] = copySeries(series)
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetSeriesByID(ctx context.Context, exec repository.Executor, orgID int, id int) (*model.EventSeries, error) {
+	return mr.GetSeriesByIDNoLock(ctx, exec, orgID, id) // блокировку дает сама транзакция хранилища
+}
+
+func (mr MemoryRepo) GetSeriesByIDNoLock(ctx context.Context, exec repository.Executor, orgID int, id int) (*model.EventSeries, error) {
+	var series *model.EventSeries
+	err := run(ctx, exec, func(t *tables) error {
+		s, ok := t.series[id]
+		if !ok || s.OrgID != orgID {
+			return model.ErrSeriesNotFound
+		}
+		series = copySeries(s)
+		return nil
+	})
+	return series, err
+}
+
+// GetPendingSeries - эксклюзивно для планировщика серий: незавершенные серии, вхождения которых созданы не до horizon
+func (mr MemoryRepo) GetPendingSeries(ctx context.Context, exec repository.Executor, horizon time.Time) ([]*model.EventSeries, error) {
+	list := make([]*model.EventSeries, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, s := range t.series {
+			if !s.Finished && (s.GeneratedUntil == nil || s.GeneratedUntil.Before(horizon)) {
+				list = append(list, copySeries(s))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (mr MemoryRepo) UpdateSeries(ctx context.Context, exec repository.Executor, series *model.EventSeries) error {
+	return run(ctx, exec, func(t *tables) error {
+		s, ok := t.series[series.ID]
+		if !ok {
+			return model.ErrSeriesNotFound
+		}
+		// правило, часовой пояс, организация и владелец серии не меняются
+		tmpl := copyEvent(&series.Template)
+		tmpl.TimeZone = s.Template.TimeZone
+		s.Template = *tmpl
+		s.GeneratedUntil = copyTime(series.GeneratedUntil)
+		s.Finished = series.Finished
+		return nil
+	})
+}
+
+// GetSeriesEvents - вхождения серии в любом статусе с началом не раньше from
+func (mr MemoryRepo) GetSeriesEvents(ctx context.Context, exec repository.Executor, seriesID int, from time.Time) ([]*model.Event, error) {
+	events := make([]*model.Event, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, e := range t.events {
+			if e.SeriesID == seriesID && !e.StartsAt.Before(from) {
+				events = append(events, copyEvent(e))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartsAt.Equal(events[j].StartsAt.Time) {
+			return events[i].StartsAt.Before(events[j].StartsAt.Time)
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
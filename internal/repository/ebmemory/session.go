@@ -0,0 +1,112 @@
+package ebmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateSession(ctx context.Context, exec repository.Executor, session *model.Session) error {
+	return run(ctx, exec, func(t *tables) error {
+		t.sessionSeq++
+		session.ID = t.sessionSeq
+		now := time.Now().UTC()
+		session.Created = &now
+		t.sessions[session.ID] = copySession(session)
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetSessionByID(ctx context.Context, exec repository.Executor, sessionID int) (*model.Session, error) {
+	var session *model.Session
+	err := run(ctx, exec, func(t *tables) error {
+		s, ok := t.sessions[sessionID]
+		if !ok {
+			return model.ErrSessionNotFound
+		}
+		session = copySession(s)
+		return nil
+	})
+	return session, err
+}
+
+func (mr MemoryRepo) GetSessionByTokenHash(ctx context.Context, exec repository.Executor, hash string) (*model.Session, error) {
+	var session *model.Session
+	err := run(ctx, exec, func(t *tables) error {
+		for _, s := range t.sessions {
+			if s.TokenHash == hash {
+				session = copySession(s)
+				return nil
+			}
+		}
+		return model.ErrSessionNotFound
+	})
+	return session, err
+}
+
+// RotateSessionToken - замена refresh-токена сессии на новый с продлением срока жизни
+func (mr MemoryRepo) RotateSessionToken(ctx context.Context, exec repository.Executor, sessionID int, hash string, expiresAt time.Time) error {
+	return run(ctx, exec, func(t *tables) error {
+		s, ok := t.sessions[sessionID]
+		if !ok {
+			return model.ErrSessionNotFound
+		}
+		s.TokenHash = hash
+		s.ExpiresAt = expiresAt
+		return nil
+	})
+}
+
+func (mr MemoryRepo) RevokeSession(ctx context.Context, exec repository.Executor, sessionID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		s, ok := t.sessions[sessionID]
+		if !ok {
+			return model.ErrSessionNotFound
+		}
+		if s.RevokedAt == nil {
+			now := time.Now().UTC()
+			s.RevokedAt = &now
+		}
+		return nil
+	})
+}
+
+// RevokeSessionsByUser - "выйти на всех устройствах"
+func (mr MemoryRepo) RevokeSessionsByUser(ctx context.Context, exec repository.Executor, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		now := time.Now().UTC()
+		for _, s := range t.sessions {
+			if s.UserID == userID && s.RevokedAt == nil {
+				s.RevokedAt = copyTime(&now)
+			}
+		}
+		return nil
+	})
+}
+
+// SetSessionOrg - смена текущей организации сессии
+func (mr MemoryRepo) SetSessionOrg(ctx context.Context, exec repository.Executor, sessionID int, orgID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		s, ok := t.sessions[sessionID]
+		if !ok {
+			return model.ErrSessionNotFound
+		}
+		s.OrgID = orgID
+		return nil
+	})
+}
+
+// RevokeOrgSessions - отзыв сессий пользователя в организации: роль в ней изменилась или участие прекращено
+func (mr MemoryRepo) RevokeOrgSessions(ctx context.Context, exec repository.Executor, orgID int, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		now := time.Now().UTC()
+		for _, s := range t.sessions {
+			if s.OrgID == orgID && s.UserID == userID && s.RevokedAt == nil {
+				s.RevokedAt = copyTime(&now)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,364 @@
+// Package ebmemory provides in-memory implementation of EBRepo for unit-tests and demo mode without PostgresQL
+package ebmemory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+var errUnsupportedExecutor = errors.New("executor is not an in-memory store or transaction")
+
+// Store - in-memory хранилище всех таблиц приложения.
+// Любая операция и любая транзакция берут эксклюзивную блокировку хранилища, поэтому транзакции
+// выполняются последовательно - это строже, чем построчный SELECT ... FOR UPDATE в Postgres, но дает те же гарантии от гонок.
+type Store struct {
+	lock chan struct{}
+	data *tables
+}
+
+// Tx - транзакция над Store: работает с копией данных и держит блокировку хранилища до Commit/Rollback
+type Tx struct {
+	store *Store
+	data  *tables
+	done  bool
+}
+
+type tables struct {
+	events     map[int]*model.Event
+	books      map[int]*model.Book
+	users      map[int]*model.User
+	waitlist   map[int]*model.WaitlistEntry
+	sessions   map[int]*model.Session
+	invites    map[int]*model.Invite
+	userTokens map[int]*model.UserToken
+	recovery   map[int]*model.RecoveryCode
+	apiKeys    map[int]*model.APIKey
+	identities map[int]*model.UserIdentity
+	organizers map[int]*model.EventOrganizer
+	orgs       map[int]*model.Organization
+	members    map[int]*orgMember
+	categories map[int]*model.Category
+	series     map[int]*model.EventSeries
+	// связи ивентов с категориями и тегами: id ивента -> id категорий / теги
+	eventCategories map[int][]int
+	eventTags       map[int][]string
+
+	eventSeq     int
+	bookSeq      int
+	userSeq      int
+	waitlistSeq  int
+	sessionSeq   int
+	inviteSeq    int
+	userTokenSeq int
+	recoverySeq  int
+	apiKeySeq    int
+	identitySeq  int
+	organizerSeq int
+	orgSeq       int
+	memberSeq    int
+	categorySeq  int
+	seriesSeq    int
+}
+
+// NewStore - пустое хранилище с организацией по умолчанию, как после миграций Postgres
+func NewStore() *Store {
+	now := time.Now().UTC()
+	return &Store{
+		lock: make(chan struct{}, 1),
+		data: &tables{
+			events:     make(map[int]*model.Event),
+			books:      make(map[int]*model.Book),
+			users:      make(map[int]*model.User),
+			waitlist:   make(map[int]*model.WaitlistEntry),
+			sessions:   make(map[int]*model.Session),
+			invites:    make(map[int]*model.Invite),
+			userTokens: make(map[int]*model.UserToken),
+			recovery:   make(map[int]*model.RecoveryCode),
+			apiKeys:    make(map[int]*model.APIKey),
+			identities: make(map[int]*model.UserIdentity),
+			organizers: make(map[int]*model.EventOrganizer),
+			orgs:       map[int]*model.Organization{1: {ID: 1, Name: "Default", Slug: model.DefaultOrgSlug, Created: &now}},
+			members:    make(map[int]*orgMember),
+			categories: make(map[int]*model.Category),
+			series:     make(map[int]*model.EventSeries),
+			orgSeq:     1,
+
+			eventCategories: make(map[int][]int),
+			eventTags:       make(map[int][]string),
+		},
+	}
+}
+
+func (s *Store) Executor() repository.Executor {
+	return s
+}
+
+// BeginTx ждет освобождения хранилища (или отмены контекста) и открывает транзакцию над копией данных
+func (s *Store) BeginTx(ctx context.Context) (repository.Tx, error) {
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return &Tx{store: s, data: s.data.clone()}, nil
+}
+
+func (s *Store) acquire(ctx context.Context) error {
+	select {
+	case s.lock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Store) release() {
+	<-s.lock
+}
+
+// Commit применяет изменения транзакции к хранилищу и снимает блокировку
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	tx.store.data = tx.data
+	tx.store.release()
+	return nil
+}
+
+// Rollback отбрасывает изменения транзакции и снимает блокировку
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	tx.store.release()
+	return nil
+}
+
+// run выполняет fn над данными исполнителя: внутри открытой транзакции - над её копией,
+// без транзакции - над самим хранилищем под блокировкой (как autocommit-запрос)
+func run(ctx context.Context, exec repository.Executor, fn func(t *tables) error) error {
+	switch e := exec.(type) {
+	case *Tx:
+		if e.done {
+			return sql.ErrTxDone
+		}
+		return fn(e.data)
+	case *Store:
+		if err := e.acquire(ctx); err != nil {
+			return err
+		}
+		defer e.release()
+		return fn(e.data)
+	default:
+		return errUnsupportedExecutor
+	}
+}
+
+func (t *tables) clone() *tables {
+	c := &tables{
+		events:       make(map[int]*model.Event, len(t.events)),
+		books:        make(map[int]*model.Book, len(t.books)),
+		users:        make(map[int]*model.User, len(t.users)),
+		waitlist:     make(map[int]*model.WaitlistEntry, len(t.waitlist)),
+		sessions:     make(map[int]*model.Session, len(t.sessions)),
+		invites:      make(map[int]*model.Invite, len(t.invites)),
+		userTokens:   make(map[int]*model.UserToken, len(t.userTokens)),
+		recovery:     make(map[int]*model.RecoveryCode, len(t.recovery)),
+		apiKeys:      make(map[int]*model.APIKey, len(t.apiKeys)),
+		identities:   make(map[int]*model.UserIdentity, len(t.identities)),
+		organizers:   make(map[int]*model.EventOrganizer, len(t.organizers)),
+		orgs:         make(map[int]*model.Organization, len(t.orgs)),
+		members:      make(map[int]*orgMember, len(t.members)),
+		categories:   make(map[int]*model.Category, len(t.categories)),
+		series:       make(map[int]*model.EventSeries, len(t.series)),
+		eventSeq:     t.eventSeq,
+		bookSeq:      t.bookSeq,
+		userSeq:      t.userSeq,
+		waitlistSeq:  t.waitlistSeq,
+		sessionSeq:   t.sessionSeq,
+		inviteSeq:    t.inviteSeq,
+		userTokenSeq: t.userTokenSeq,
+		recoverySeq:  t.recoverySeq,
+		apiKeySeq:    t.apiKeySeq,
+		identitySeq:  t.identitySeq,
+		organizerSeq: t.organizerSeq,
+		orgSeq:       t.orgSeq,
+		memberSeq:    t.memberSeq,
+		categorySeq:  t.categorySeq,
+		seriesSeq:    t.seriesSeq,
+
+		eventCategories: make(map[int][]int, len(t.eventCategories)),
+		eventTags:       make(map[int][]string, len(t.eventTags)),
+	}
+	for id, e := range t.events {
+		c.events[id] = copyEvent(e)
+	}
+	for id, b := range t.books {
+		c.books[id] = copyBook(b)
+	}
+	for id, u := range t.users {
+		c.users[id] = copyUser(u)
+	}
+	for id, w := range t.waitlist {
+		c.waitlist[id] = copyWaitlistEntry(w)
+	}
+	for id, s := range t.sessions {
+		c.sessions[id] = copySession(s)
+	}
+	for id, i := range t.invites {
+		c.invites[id] = copyInvite(i)
+	}
+	for id, ut := range t.userTokens {
+		c.userTokens[id] = copyUserToken(ut)
+	}
+	for id, rc := range t.recovery {
+		c.recovery[id] = copyRecoveryCode(rc)
+	}
+	for id, k := range t.apiKeys {
+		c.apiKeys[id] = copyAPIKey(k)
+	}
+	for id, ui := range t.identities {
+		c.identities[id] = copyUserIdentity(ui)
+	}
+	for id, eo := range t.organizers {
+		c.organizers[id] = copyEventOrganizer(eo)
+	}
+	for id, o := range t.orgs {
+		c.orgs[id] = copyOrganization(o)
+	}
+	for id, m := range t.members {
+		c.members[id] = copyOrgMember(m)
+	}
+	for id, cat := range t.categories {
+		c.categories[id] = copyCategory(cat)
+	}
+	for id, s := range t.series {
+		c.series[id] = copySeries(s)
+	}
+	for id, ids := range t.eventCategories {
+		c.eventCategories[id] = slices.Clone(ids)
+	}
+	for id, tags := range t.eventTags {
+		c.eventTags[id] = slices.Clone(tags)
+	}
+	return c
+}
+
+// copy-функции нужны, чтобы вызывающий код не мог изменить содержимое хранилища через возвращенные указатели
+
+func copyEvent(e *model.Event) *model.Event {
+	c := *e
+	c.Created = copyTime(e.Created)
+	c.EndsAt = copyCustomTime(e.EndsAt)
+	c.BookingCloses = copyTime(e.BookingCloses)
+	c.Categories = slices.Clone(e.Categories)
+	c.Tags = slices.Clone(e.Tags)
+	return &c
+}
+
+func copyBook(b *model.Book) *model.Book {
+	c := *b
+	c.Created = copyTime(b.Created)
+	c.ConfirmDeadline = copyTime(b.ConfirmDeadline)
+	c.ExpiredAt = copyTime(b.ExpiredAt)
+	c.CancelledAt = copyTime(b.CancelledAt)
+	return &c
+}
+
+func copyUser(u *model.User) *model.User {
+	c := *u
+	c.Created = copyTime(u.Created)
+	c.DisabledAt = copyTime(u.DisabledAt)
+	c.EmailVerifiedAt = copyTime(u.EmailVerifiedAt)
+	c.LockedUntil = copyTime(u.LockedUntil)
+	c.FailedLoginAt = copyTime(u.FailedLoginAt)
+	c.TOTPEnabledAt = copyTime(u.TOTPEnabledAt)
+	return &c
+}
+
+func copyWaitlistEntry(w *model.WaitlistEntry) *model.WaitlistEntry {
+	c := *w
+	c.Created = copyTime(w.Created)
+	return &c
+}
+
+func copySession(s *model.Session) *model.Session {
+	c := *s
+	c.Created = copyTime(s.Created)
+	c.RevokedAt = copyTime(s.RevokedAt)
+	return &c
+}
+
+func copyInvite(i *model.Invite) *model.Invite {
+	c := *i
+	c.Created = copyTime(i.Created)
+	c.UsedAt = copyTime(i.UsedAt)
+	return &c
+}
+
+func copyUserToken(ut *model.UserToken) *model.UserToken {
+	c := *ut
+	c.Created = copyTime(ut.Created)
+	c.UsedAt = copyTime(ut.UsedAt)
+	return &c
+}
+
+func copyRecoveryCode(rc *model.RecoveryCode) *model.RecoveryCode {
+	c := *rc
+	c.UsedAt = copyTime(rc.UsedAt)
+	return &c
+}
+
+func copyAPIKey(k *model.APIKey) *model.APIKey {
+	c := *k
+	c.Scopes = append([]string(nil), k.Scopes...)
+	c.Created = copyTime(k.Created)
+	c.LastUsedAt = copyTime(k.LastUsedAt)
+	c.RevokedAt = copyTime(k.RevokedAt)
+	return &c
+}
+
+func copyUserIdentity(ui *model.UserIdentity) *model.UserIdentity {
+	c := *ui
+	c.Created = copyTime(ui.Created)
+	return &c
+}
+
+func copyEventOrganizer(eo *model.EventOrganizer) *model.EventOrganizer {
+	c := *eo
+	c.Added = copyTime(eo.Added)
+	return &c
+}
+
+func copyOrganization(o *model.Organization) *model.Organization {
+	c := *o
+	c.Created = copyTime(o.Created)
+	return &c
+}
+
+func copyCategory(c *model.Category) *model.Category {
+	cc := *c
+	cc.Created = copyTime(c.Created)
+	return &cc
+}
+
+func copySeries(s *model.EventSeries) *model.EventSeries {
+	c := *s
+	c.Template = *copyEvent(&s.Template)
+	c.GeneratedUntil = copyTime(s.GeneratedUntil)
+	c.Created = copyTime(s.Created)
+	return &c
+}
+
+func copyOrgMember(m *orgMember) *orgMember {
+	c := *m
+	c.Created = copyTime(m.Created)
+	return &c
+}
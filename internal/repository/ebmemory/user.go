@@ -0,0 +1,219 @@
+package ebmemory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// GetUsersList - постраничный поиск участников организации по подстроке имейла/имени/фамилии, только для админа
+func (mr MemoryRepo) GetUsersList(ctx context.Context, exec repository.Executor, filter model.UserFilter) ([]*model.User, int, error) {
+	users := make([]*model.User, 0)
+	q := strings.ToLower(filter.Query)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, u := range t.users {
+			m := t.member(filter.OrgID, u.ID)
+			if m == nil {
+				continue
+			}
+			if q == "" ||
+				strings.Contains(strings.ToLower(u.Email), q) ||
+				strings.Contains(strings.ToLower(u.Name), q) ||
+				strings.Contains(strings.ToLower(u.Surname), q) {
+				c := copyUser(u)
+				c.PassHash = "" // как и в Postgres, хэш пароля в список не попадает
+				c.Role = m.Role
+				users = append(users, c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	total := len(users)
+	if filter.Offset >= total {
+		return make([]*model.User, 0), total, nil
+	}
+	end := min(filter.Offset+filter.Limit, total)
+	return users[filter.Offset:end], total, nil
+}
+
+// SetUserDisabled - блокировка/разблокировка аккаунта; время первой блокировки сохраняется при повторной
+func (mr MemoryRepo) SetUserDisabled(ctx context.Context, exec repository.Executor, userID int, disabled bool) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		switch {
+		case !disabled:
+			u.DisabledAt = nil
+		case u.DisabledAt == nil:
+			now := time.Now().UTC()
+			u.DisabledAt = &now
+		}
+		return nil
+	})
+}
+
+// DeleteUser - брони, очередь ожидания, сессии и участие в организациях удаляются каскадно, как в схеме Postgres;
+// ссылки на него в приглашениях и владельцы его ивентов обнуляются (ON DELETE SET NULL)
+func (mr MemoryRepo) DeleteUser(ctx context.Context, exec repository.Executor, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		if _, ok := t.users[userID]; !ok {
+			return model.ErrUserNotFound
+		}
+		delete(t.users, userID)
+		for id, b := range t.books {
+			if b.UserID == userID {
+				delete(t.books, id)
+			}
+		}
+		for id, w := range t.waitlist {
+			if w.UserID == userID {
+				delete(t.waitlist, id)
+			}
+		}
+		for id, s := range t.sessions {
+			if s.UserID == userID {
+				delete(t.sessions, id)
+			}
+		}
+		for id, ut := range t.userTokens {
+			if ut.UserID == userID {
+				delete(t.userTokens, id)
+			}
+		}
+		for id, rc := range t.recovery {
+			if rc.UserID == userID {
+				delete(t.recovery, id)
+			}
+		}
+		for id, k := range t.apiKeys {
+			if k.UserID == userID {
+				delete(t.apiKeys, id)
+			}
+		}
+		for id, ui := range t.identities {
+			if ui.UserID == userID {
+				delete(t.identities, id)
+			}
+		}
+		for id, eo := range t.organizers {
+			if eo.UserID == userID {
+				delete(t.organizers, id)
+			}
+		}
+		for id, m := range t.members {
+			if m.UserID == userID {
+				delete(t.members, id)
+			}
+		}
+		for _, e := range t.events {
+			if e.OwnerID == userID {
+				e.OwnerID = 0
+			}
+		}
+		for _, s := range t.series {
+			if s.OwnerID == userID {
+				s.OwnerID = 0
+			}
+		}
+		for _, i := range t.invites {
+			if i.CreatedBy == userID {
+				i.CreatedBy = 0
+			}
+			if i.UsedBy == userID {
+				i.UsedBy = 0
+			}
+		}
+		return nil
+	})
+}
+
+// GetActiveBooksByUser - брони пользователя, держащие места (created/confirmed)
+func (mr MemoryRepo) GetActiveBooksByUser(ctx context.Context, exec repository.Executor, userID int) ([]*model.Book, error) {
+	books := make([]*model.Book, 0)
+	err := run(ctx, exec, func(t *tables) error {
+		for _, b := range t.books {
+			if b.UserID == userID && (b.Status == model.BookStatusCreated || b.Status == model.BookStatusConfirmed) {
+				books = append(books, copyBook(b))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books, nil
+}
+
+func (mr MemoryRepo) SetEmailVerified(ctx context.Context, exec repository.Executor, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		if u.EmailVerifiedAt == nil {
+			now := time.Now().UTC()
+			u.EmailVerifiedAt = &now
+		}
+		return nil
+	})
+}
+
+func (mr MemoryRepo) UpdateUserPassword(ctx context.Context, exec repository.Executor, userID int, passHash string) error {
+	return run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		u.PassHash = passHash
+		return nil
+	})
+}
+
+func (mr MemoryRepo) RegisterLoginFailure(ctx context.Context, exec repository.Executor, userID int, since time.Time, maxFailures int, lockUntil time.Time) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := run(ctx, exec, func(t *tables) error {
+		u, ok := t.users[userID]
+		if !ok {
+			return model.ErrUserNotFound
+		}
+		now := time.Now().UTC()
+		if u.FailedLoginAt != nil && u.FailedLoginAt.After(since) {
+			u.FailedLogins++
+		} else {
+			u.FailedLogins = 1
+		}
+		u.FailedLoginAt = &now
+		if u.FailedLogins >= maxFailures {
+			until := lockUntil
+			u.LockedUntil = &until
+		}
+		if u.LockedUntil != nil && u.LockedUntil.After(now) {
+			lockedUntil = copyTime(u.LockedUntil)
+		}
+		return nil
+	})
+	return lockedUntil, err
+}
+
+func (mr MemoryRepo) ResetLoginFailures(ctx context.Context, exec repository.Executor, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		if u, ok := t.users[userID]; ok {
+			u.FailedLogins = 0
+			u.FailedLoginAt = nil
+			u.LockedUntil = nil
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package ebmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateUserToken(ctx context.Context, exec repository.Executor, token *model.UserToken) error {
+	return run(ctx, exec, func(t *tables) error {
+		t.userTokenSeq++
+		token.ID = t.userTokenSeq
+		now := time.Now().UTC()
+		token.Created = &now
+		t.userTokens[token.ID] = copyUserToken(token)
+		return nil
+	})
+}
+
+func (mr MemoryRepo) GetUserTokenByHash(ctx context.Context, exec repository.Executor, hash string, purpose string) (*model.UserToken, error) {
+	var token *model.UserToken
+	err := run(ctx, exec, func(t *tables) error {
+		for _, ut := range t.userTokens {
+			if ut.TokenHash == hash && ut.Purpose == purpose {
+				token = copyUserToken(ut)
+				return nil
+			}
+		}
+		return model.ErrUserTokenNotFound
+	})
+	return token, err
+}
+
+func (mr MemoryRepo) MarkUserTokenUsed(ctx context.Context, exec repository.Executor, tokenID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		ut, ok := t.userTokens[tokenID]
+		if !ok {
+			return model.ErrUserTokenNotFound
+		}
+		now := time.Now().UTC()
+		ut.UsedAt = &now
+		return nil
+	})
+}
+
+// InvalidateUserTokens - гасит все неиспользованные токены пользователя с указанным назначением,
+// чтобы действовала только последняя отправленная ссылка
+func (mr MemoryRepo) InvalidateUserTokens(ctx context.Context, exec repository.Executor, userID int, purpose string) error {
+	return run(ctx, exec, func(t *tables) error {
+		now := time.Now().UTC()
+		for _, ut := range t.userTokens {
+			if ut.UserID == userID && ut.Purpose == purpose && ut.UsedAt == nil {
+				ut.UsedAt = copyTime(&now)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package ebmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (mr MemoryRepo) CreateWaitlistEntry(ctx context.Context, exec repository.Executor, entry *model.WaitlistEntry) error {
+	return run(ctx, exec, func(t *tables) error {
+		for _, w := range t.waitlist {
+			if w.EventID == entry.EventID && w.UserID == entry.UserID {
+				return model.ErrAlreadyWaitlisted // аналог uq_waitlist_event_user
+			}
+		}
+		t.waitlistSeq++
+		entry.ID = t.waitlistSeq
+		now := time.Now().UTC()
+		entry.Created = &now
+		t.waitlist[entry.ID] = copyWaitlistEntry(entry)
+		return nil
+	})
+}
+
+func (mr MemoryRepo) DeleteWaitlistEntry(ctx context.Context, exec repository.Executor, eventID int, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		for id, w := range t.waitlist {
+			if w.EventID == eventID && w.UserID == userID {
+				delete(t.waitlist, id)
+				return nil
+			}
+		}
+		return model.ErrNotWaitlisted
+	})
+}
+
+// DeleteWaitlistByEvent - очистка очереди при отмене/истечении ивента
+func (mr MemoryRepo) DeleteWaitlistByEvent(ctx context.Context, exec repository.Executor, eventID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		for id, w := range t.waitlist {
+			if w.EventID == eventID {
+				delete(t.waitlist, id)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteWaitlistByUser - заблокированный пользователь не сможет подтвердить бронь из очереди, поэтому уходит из всех очередей
+func (mr MemoryRepo) DeleteWaitlistByUser(ctx context.Context, exec repository.Executor, userID int) error {
+	return run(ctx, exec, func(t *tables) error {
+		for id, w := range t.waitlist {
+			if w.UserID == userID {
+				delete(t.waitlist, id)
+			}
+		}
+		return nil
+	})
+}
+
+// GetWaitlistHead - первый в очереди ивента вместе с имейлом для уведомления
+func (mr MemoryRepo) GetWaitlistHead(ctx context.Context, exec repository.Executor, eventID int) (*model.WaitlistEntry, error) {
+	var entry *model.WaitlistEntry
+	err := run(ctx, exec, func(t *tables) error {
+		for _, w := range t.waitlist {
+			if w.EventID == eventID && (entry == nil || w.ID < entry.ID) {
+				entry = copyWaitlistEntry(w)
+			}
+		}
+		if entry == nil {
+			return model.ErrNotWaitlisted
+		}
+		entry.Position = 1
+		if u, ok := t.users[entry.UserID]; ok {
+			entry.Email = u.Email
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// GetWaitlistEntry - запись пользователя в очереди ивента с его текущей позицией
+func (mr MemoryRepo) GetWaitlistEntry(ctx context.Context, exec repository.Executor, eventID int, userID int) (*model.WaitlistEntry, error) {
+	var entry *model.WaitlistEntry
+	err := run(ctx, exec, func(t *tables) error {
+		for _, w := range t.waitlist {
+			if w.EventID == eventID && w.UserID == userID {
+				entry = copyWaitlistEntry(w)
+				break
+			}
+		}
+		if entry == nil {
+			return model.ErrNotWaitlisted
+		}
+		for _, w := range t.waitlist {
+			if w.EventID == eventID && w.ID <= entry.ID {
+				entry.Position++
+			}
+		}
+		return nil
+	})
+	return entry, err
+}
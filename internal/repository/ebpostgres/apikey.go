@@ -0,0 +1,146 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+func (pr PostgresRepo) CreateAPIKey(ctx context.Context, ex repository.Executor, key *model.APIKey) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, mfa, created_at, expires_at, org_id)
+	VALUES (DEFAULT, $1, $2, $3, $4, $5, $6, DEFAULT, $7, $8) RETURNING id, created_at`
+	return exec.QueryRowContext(ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.MFA, key.ExpiresAt, key.OrgID).Scan(&key.ID, &key.Created)
+}
+
+func (pr PostgresRepo) GetAPIKeysByUser(ctx context.Context, ex repository.Executor, userID int) ([]*model.APIKey, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, name, prefix, key_hash, scopes, mfa, created_at, expires_at, last_used_at, revoked_at, org_id
+	FROM api_keys
+	WHERE user_id = $1
+	ORDER BY id`
+
+	rows, err := exec.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	keys := make([]*model.APIKey, 0)
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return keys, nil
+}
+
+func (pr PostgresRepo) GetAPIKeyByHash(ctx context.Context, ex repository.Executor, hash string) (*model.APIKey, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, name, prefix, key_hash, scopes, mfa, created_at, expires_at, last_used_at, revoked_at, org_id
+	FROM api_keys
+	WHERE key_hash = $1`
+
+	key, err := scanAPIKey(exec.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrAPIKeyNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return key, nil
+}
+
+func (pr PostgresRepo) RevokeAPIKey(ctx context.Context, ex repository.Executor, userID int, keyID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE api_keys
+	SET revoked_at = COALESCE(revoked_at, now())
+	WHERE id = $1 AND user_id = $2`
+
+	res, err := exec.ExecContext(ctx, query, keyID, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey обновляет last_used_at не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+func (pr PostgresRepo) TouchAPIKey(ctx context.Context, ex repository.Executor, keyID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE api_keys
+	SET last_used_at = now()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+
+	_, err = exec.ExecContext(ctx, query, keyID)
+	return err // 500
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (*model.APIKey, error) {
+	var key model.APIKey
+
+	err := row.Scan(&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		pq.Array(&key.Scopes),
+		&key.MFA,
+		&key.Created,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,242 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+func (pr PostgresRepo) CreateCategory(ctx context.Context, ex repository.Executor, category *model.Category) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO categories (id, org_id, name, slug, created_at)
+	VALUES (DEFAULT, $1, $2, $3, DEFAULT) RETURNING id, created_at`
+	err = exec.QueryRowContext(ctx, query, category.OrgID, category.Name, category.Slug).Scan(&category.ID, &category.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrCategoryExists // 409
+		}
+		return err
+	}
+	return nil
+}
+
+// GetCategoriesList - категории организации по имени
+func (pr PostgresRepo) GetCategoriesList(ctx context.Context, ex repository.Executor, orgID int) ([]*model.Category, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, org_id, name, slug, created_at
+	FROM categories
+	WHERE org_id = $1
+	ORDER BY name, id`
+	rows, err := exec.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	categories := make([]*model.Category, 0)
+
+	for rows.Next() {
+		var c model.Category
+		if err := rows.Scan(&c.ID, &c.OrgID, &c.Name, &c.Slug, &c.Created); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &c)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return categories, nil
+}
+
+// UpdateCategory - шаблоны серий хранят slug категорий, поэтому новый slug переносится и в них тем же запросом
+func (pr PostgresRepo) UpdateCategory(ctx context.Context, ex repository.Executor, category *model.Category) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `WITH old AS (
+		SELECT slug FROM categories WHERE id = $3 AND org_id = $4 FOR UPDATE
+	), upd AS (
+		UPDATE categories
+		SET name = $1, slug = $2
+		WHERE id = $3 AND org_id = $4
+		RETURNING created_at
+	), series AS (
+		UPDATE event_series s
+		SET categories = array_replace(s.categories, old.slug, $2::text)
+		FROM old
+		WHERE s.org_id = $4 AND old.slug <> $2::text AND old.slug = ANY(s.categories)
+	)
+	SELECT created_at FROM upd`
+	err = exec.QueryRowContext(ctx, query, category.Name, category.Slug, category.ID, category.OrgID).Scan(&category.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Code == uniqueViolation:
+			return model.ErrCategoryExists // 409
+		case errors.Is(err, sql.ErrNoRows):
+			return model.ErrCategoryNotFound
+		default:
+			return err // 500
+		}
+	}
+	return nil
+}
+
+// DeleteCategory - привязки ивентов к категории удаляются каскадно
+func (pr PostgresRepo) DeleteCategory(ctx context.Context, ex repository.Executor, orgID int, categoryID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM categories WHERE id = $1 AND org_id = $2`
+
+	res, err := exec.ExecContext(ctx, query, categoryID, orgID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+// LoadEventLabels - категории и теги ивентов двумя запросами на весь список, по алфавиту
+func (pr PostgresRepo) LoadEventLabels(ctx context.Context, ex repository.Executor, events []*model.Event) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*model.Event, len(events))
+	ids := make([]int64, 0, len(events))
+	for _, e := range events {
+		e.Categories, e.Tags = nil, nil
+		byID[e.ID] = e
+		ids = append(ids, int64(e.ID))
+	}
+
+	query := `SELECT ec.event_id, c.slug
+	FROM event_categories ec
+	JOIN categories c ON c.id = ec.category_id
+	WHERE ec.event_id = ANY($1)
+	ORDER BY c.slug`
+	if err := scanLabels(ctx, exec, query, ids, byID, func(e *model.Event, label string) {
+		e.Categories = append(e.Categories, label)
+	}); err != nil {
+		return err
+	}
+
+	query = `SELECT event_id, tag
+	FROM event_tags
+	WHERE event_id = ANY($1)
+	ORDER BY tag`
+	return scanLabels(ctx, exec, query, ids, byID, func(e *model.Event, label string) {
+		e.Tags = append(e.Tags, label)
+	})
+}
+
+func scanLabels(ctx context.Context, exec Executor, query string, ids []int64, byID map[int]*model.Event, add func(*model.Event, string)) error {
+	rows, err := exec.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		var id int
+		var label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return err
+		}
+		if e, ok := byID[id]; ok {
+			add(e, label)
+		}
+	}
+
+	return rows.Err()
+}
+
+// SetEventCategories заменяет категории ивента; slug ищутся только среди категорий его организации
+func (pr PostgresRepo) SetEventCategories(ctx context.Context, ex repository.Executor, orgID int, eventID int, slugs []string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	if _, err := exec.ExecContext(ctx, `DELETE FROM event_categories WHERE event_id = $1`, eventID); err != nil {
+		return err // 500
+	}
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO event_categories (event_id, category_id)
+	SELECT $1, id FROM categories WHERE org_id = $2 AND slug = ANY($3)`
+	res, err := exec.ExecContext(ctx, query, eventID, orgID, pq.Array(slugs))
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if int(rows) != len(slugs) { // slug уникальны в организации и без повторов - значит, какой-то не найден
+		return model.ErrCategoryNotFound
+	}
+
+	return nil
+}
+
+// SetEventTags заменяет теги ивента
+func (pr PostgresRepo) SetEventTags(ctx context.Context, ex repository.Executor, eventID int, tags []string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	if _, err := exec.ExecContext(ctx, `DELETE FROM event_tags WHERE event_id = $1`, eventID); err != nil {
+		return err // 500
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO event_tags (event_id, tag)
+	SELECT $1, unnest($2::text[])`
+	if _, err := exec.ExecContext(ctx, query, eventID, pq.Array(tags)); err != nil {
+		return err // 500
+	}
+
+	return nil
+}
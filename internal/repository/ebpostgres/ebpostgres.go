@@ -6,8 +6,12 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"time"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+	"github.com/wb-go/wbf/dbpg"
 )
 
 type PostgresRepo struct{}
@@ -19,39 +23,99 @@ type Executor interface {
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
-// CreateEvent - создание ивента доступно только для админа
-func (pr PostgresRepo) CreateEvent(ctx context.Context, exec Executor, newEvent *model.Event) error {
-	query := `INSERT INTO events (id, title, description, status, event_date, created_at, bookwindow, total_seats, avail_seats)
-	VALUES (DEFAULT, $1, $2, $3, $4, DEFAULT, $5, $6, $7) RETURNING id`
-	err := exec.QueryRowContext(ctx, query, newEvent.Title, newEvent.Descr, newEvent.Status, newEvent.EventDate, newEvent.BookWindow, newEvent.TotalSeats, newEvent.AvailSeats).Scan(&newEvent.ID)
+var errUnsupportedExecutor = errors.New("executor is not a postgres connection or transaction")
+
+// uniqueViolation - код ошибки Postgres при нарушении UNIQUE-ограничения
+const uniqueViolation = "23505"
+
+// TxManager выдает сервисному слою подключение к Postgres и открывает транзакции на мастере
+type TxManager struct {
+	db *dbpg.DB
+}
+
+func NewPostgresRepo() repository.EBRepo {
+	return &PostgresRepo{}
+}
+
+func NewTxManager(db *dbpg.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+func (tm *TxManager) Executor() repository.Executor {
+	return tm.db
+}
+
+func (tm *TxManager) BeginTx(ctx context.Context) (repository.Tx, error) {
+	return tm.db.BeginTx(ctx, nil)
+}
+
+// asSQL приводит исполнитель, полученный от сервиса, к интерфейсу для выполнения SQL-запросов
+func asSQL(exec repository.Executor) (Executor, error) {
+	sqlExec, ok := exec.(Executor)
+	if !ok {
+		return nil, errUnsupportedExecutor
+	}
+	return sqlExec, nil
+}
+
+// CreateEvent - создание ивента админом или организатором, создатель записывается владельцем
+func (pr PostgresRepo) CreateEvent(ctx context.Context, ex repository.Executor, newEvent *model.Event) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO events (id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, owner_id, org_id, venue_name, venue_address, contact, cover_url, series_id)
+	VALUES (DEFAULT, $1, $2, $3, $4, $5, $6, DEFAULT, $7, $8, $9, $10, NULLIF($11, 0), $12, $13, $14, $15, $16, NULLIF($17, 0)) RETURNING id`
+	err = exec.QueryRowContext(ctx, query, newEvent.Title, newEvent.Descr, newEvent.Status, newEvent.StartsAt, newEvent.EndsAt, newEvent.TimeZone, newEvent.BookWindow, newEvent.TotalSeats, newEvent.AvailSeats, newEvent.MaxPerBook, newEvent.OwnerID, newEvent.OrgID,
+		newEvent.Venue, newEvent.Address, newEvent.Contact, newEvent.CoverURL, newEvent.SeriesID).Scan(&newEvent.ID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (pr PostgresRepo) CreateBook(ctx context.Context, exec Executor, newBook *model.Book) error {
-	query := `INSERT INTO bookings (id, event_id, user_id, status, created_at, confirm_deadline)
-	VALUES (DEFAULT, $1, $2, $3, DEFAULT, $4) RETURNING id`
-	err := exec.QueryRowContext(ctx, query, newBook.EventID, newBook.UserID, newBook.Status, newBook.ConfirmDeadline).Scan(&newBook.ID)
+func (pr PostgresRepo) CreateBook(ctx context.Context, ex repository.Executor, newBook *model.Book) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO bookings (id, event_id, user_id, status, quantity, created_at, confirm_deadline, org_id)
+	VALUES (DEFAULT, $1, $2, $3, $4, DEFAULT, $5, $6) RETURNING id`
+	err = exec.QueryRowContext(ctx, query, newBook.EventID, newBook.UserID, newBook.Status, newBook.Quantity, newBook.ConfirmDeadline, newBook.OrgID).Scan(&newBook.ID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (pr PostgresRepo) CreateUser(ctx context.Context, exec Executor, newUser *model.User) error {
-	query := `INSERT INTO users (id, created_at, role, name, surname, tel, email, pass_hash)
-	VALUES (DEFAULT, DEFAULT, $1, $2, $3, $4, $5, $6) RETURNING id`
-	err := exec.QueryRowContext(ctx, query, newUser.Role, newUser.Name, newUser.Surname, newUser.Tel, newUser.Email, newUser.PassHash).Scan(&newUser.ID)
+func (pr PostgresRepo) CreateUser(ctx context.Context, ex repository.Executor, newUser *model.User) error {
+	exec, err := asSQL(ex)
 	if err != nil {
 		return err
 	}
+
+	query := `INSERT INTO users (id, created_at, name, surname, tel, email, pass_hash)
+	VALUES (DEFAULT, DEFAULT, $1, $2, $3, $4, $5) RETURNING id`
+	err = exec.QueryRowContext(ctx, query, newUser.Name, newUser.Surname, newUser.Tel, newUser.Email, newUser.PassHash).Scan(&newUser.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrUserAlreadyExists // 409
+		}
+		return err
+	}
 	return nil
 }
 
-// DeleteEvent - удаление ивента только для роли админа
-func (pr PostgresRepo) DeleteEvent(ctx context.Context, exec Executor, eventID int) error {
+// DeleteEvent - удаление ивента владельцем или админом; брони, очередь и соорганизаторы удаляются каскадно
+func (pr PostgresRepo) DeleteEvent(ctx context.Context, ex repository.Executor, eventID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
 	query := `DELETE FROM events
 	WHERE id = $1`
 
@@ -70,26 +134,76 @@ func (pr PostgresRepo) DeleteEvent(ctx context.Context, exec Executor, eventID i
 	return nil
 }
 
-// DeleteBook - эксклюзивно для воркера BookCleaner
-func (pr PostgresRepo) DeleteBook(ctx context.Context, exec Executor, bookID int) error {
-	query := `DELETE FROM bookings
-	WHERE id = $1`
+// MarkBookExpired - эксклюзивно для воркеров BookCleaner/BookScheduler: бронь остается в БД для аналитики
+func (pr PostgresRepo) MarkBookExpired(ctx context.Context, ex repository.Executor, bookID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
 
-	row, err := exec.ExecContext(ctx, query, bookID)
+	query := `UPDATE bookings SET status = $1, expired_at = now() WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, model.BookStatusExpired, bookID)
 	if err != nil {
 		return err // 500
 	}
-	n, err := row.RowsAffected()
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrBookNotFound // 404
+	}
+
+	return nil
+}
+
+// MarkBookCancelled - отмена брони с отметкой времени, от которой BookPurger считает срок хранения
+func (pr PostgresRepo) MarkBookCancelled(ctx context.Context, ex repository.Executor, bookID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE bookings SET status = $1, cancelled_at = now() WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, model.BookStatusCancelled, bookID)
 	if err != nil {
 		return err // 500
 	}
-	if n == 0 {
-		return model.ErrBookNotFound
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrBookNotFound // 404
 	}
+
 	return nil
 }
 
-func (pr PostgresRepo) UpdateBookStatus(ctx context.Context, exec Executor, bookID int, newStatus string) error {
+// PurgeBooks - эксклюзивно для воркера BookPurger: окончательное удаление истекших и отмененных броней,
+// истекших или отмененных раньше before
+func (pr PostgresRepo) PurgeBooks(ctx context.Context, ex repository.Executor, before time.Time) (int, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `DELETE FROM bookings
+	WHERE status IN ($1, $2) AND COALESCE(expired_at, cancelled_at) < $3`
+
+	res, err := exec.ExecContext(ctx, query, model.BookStatusExpired, model.BookStatusCancelled, before)
+	if err != nil {
+		return 0, err // 500
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err // 500
+	}
+	return int(n), nil
+}
+
+func (pr PostgresRepo) UpdateBookStatus(ctx context.Context, ex repository.Executor, bookID int, newStatus string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE bookings SET status=$1 WHERE id = $2`
 
 	res, err := exec.ExecContext(ctx, query, newStatus, bookID)
@@ -104,22 +218,160 @@ func (pr PostgresRepo) UpdateBookStatus(ctx context.Context, exec Executor, book
 	return nil
 }
 
-func (pr PostgresRepo) GetEventByID(ctx context.Context, exec Executor, id int) (*model.Event, error) { // select FOR UPDATE
-	query := `SELECT id, title, description, status, event_date, created_at, bookwindow, total_seats, avail_seats 
+// UpdateEvent - обновление редактируемых полей ивента, только для админа
+func (pr PostgresRepo) UpdateEvent(ctx context.Context, ex repository.Executor, event *model.Event) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE events 
+	SET title = $1, description = $2, starts_at = $3, ends_at = $4, timezone = $5, bookwindow = $6, total_seats = $7, avail_seats = $8, max_per_book = $9,
+	venue_name = $10, venue_address = $11, contact = $12, cover_url = $13 
+	WHERE id = $14`
+
+	res, err := exec.ExecContext(ctx, query, event.Title, event.Descr, event.StartsAt, event.EndsAt, event.TimeZone, event.BookWindow, event.TotalSeats, event.AvailSeats, event.MaxPerBook,
+		event.Venue, event.Address, event.Contact, event.CoverURL, event.ID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrEventNotFound // 404
+	}
+
+	return nil
+}
+
+// CancelEvent - отмена ивента админом: все места снова свободны, так как брони отменяются в той же транзакции
+func (pr PostgresRepo) CancelEvent(ctx context.Context, ex repository.Executor, eventID int, reason string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE events 
+	SET status = $1, cancel_reason = $2, avail_seats = total_seats 
+	WHERE id = $3`
+
+	res, err := exec.ExecContext(ctx, query, model.EventStatusCancelled, reason, eventID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrEventNotFound // 404
+	}
+
+	return nil
+}
+
+// CancelBooksByEvent - отмена броней ивента с указанными статусами, возвращает отмененные брони с имейлами пользователей для уведомлений
+func (pr PostgresRepo) CancelBooksByEvent(ctx context.Context, ex repository.Executor, eventID int, statuses []string) ([]*model.BookWithUser, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `WITH cancelled AS (
+		UPDATE bookings SET status = $1, cancelled_at = now() 
+		WHERE event_id = $2 AND status = ANY($3) 
+		RETURNING id, event_id, user_id, status, quantity, created_at, confirm_deadline, expired_at, cancelled_at
+	)
+	SELECT c.id, c.event_id, c.user_id, c.status, c.quantity, c.created_at, c.confirm_deadline, c.expired_at, c.cancelled_at, u.email 
+	FROM cancelled c 
+	JOIN users u ON u.id = c.user_id 
+	ORDER BY c.id`
+	rows, err := exec.QueryContext(ctx, query, model.BookStatusCancelled, eventID, pq.Array(statuses))
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	books := make([]*model.BookWithUser, 0)
+
+	for rows.Next() {
+		var book model.BookWithUser
+		if err := rows.Scan(&book.ID,
+			&book.EventID,
+			&book.UserID,
+			&book.Status,
+			&book.Quantity,
+			&book.Created,
+			&book.ConfirmDeadline,
+			&book.ExpiredAt,
+			&book.CancelledAt,
+			&book.Email); err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return books, nil
+}
+
+// UpdateEventStatus - эксклюзивно для воркера EventSweeper
+func (pr PostgresRepo) UpdateEventStatus(ctx context.Context, ex repository.Executor, eventID int, newStatus string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE events SET status = $1 WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, newStatus, eventID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrEventNotFound // 404
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) GetEventByID(ctx context.Context, ex repository.Executor, orgID int, id int) (*model.Event, error) { // select FOR UPDATE
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, cancel_reason, COALESCE(owner_id, 0), org_id, venue_name, venue_address, contact, cover_url, COALESCE(series_id, 0)
 	FROM events 
-	WHERE id = $1 FOR UPDATE`
+	WHERE id = $1 AND org_id = $2 FOR UPDATE`
 
 	var event model.Event
 
-	err := exec.QueryRowContext(ctx, query, id).Scan(&event.ID,
+	err = exec.QueryRowContext(ctx, query, id, orgID).Scan(&event.ID,
 		&event.Title,
 		&event.Descr,
 		&event.Status,
-		&event.EventDate,
+		&event.StartsAt,
+		&event.EndsAt,
+		&event.TimeZone,
 		&event.Created,
 		&event.BookWindow,
 		&event.TotalSeats,
-		&event.AvailSeats)
+		&event.AvailSeats,
+		&event.MaxPerBook,
+		&event.CancelReason,
+		&event.OwnerID,
+		&event.OrgID,
+		&event.Venue,
+		&event.Address,
+		&event.Contact,
+		&event.CoverURL,
+		&event.SeriesID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -131,14 +383,103 @@ func (pr PostgresRepo) GetEventByID(ctx context.Context, exec Executor, id int)
 	return &event, nil
 }
 
-func (pr PostgresRepo) GetEventsList(ctx context.Context, exec Executor, role string) ([]*model.Event, error) {
-	query := `SELECT id, title, description, status, event_date, created_at, bookwindow, total_seats, avail_seats 
-	FROM events`
-	if role != model.RoleAdmin { // пользователю - только актуальные ивенты
-		query += ` WHERE event_date > now() AND status = 'actual'`
+// GetEventByIDNoLock - чтение ивента без блокировки строки, для просмотра информации об ивенте
+func (pr PostgresRepo) GetEventByIDNoLock(ctx context.Context, ex repository.Executor, orgID int, id int) (*model.Event, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
 	}
 
-	rows, err := exec.QueryContext(ctx, query)
+	query := `SELECT id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, cancel_reason, COALESCE(owner_id, 0), org_id, venue_name, venue_address, contact, cover_url, COALESCE(series_id, 0)
+	FROM events 
+	WHERE id = $1 AND org_id = $2`
+
+	var event model.Event
+
+	err = exec.QueryRowContext(ctx, query, id, orgID).Scan(&event.ID,
+		&event.Title,
+		&event.Descr,
+		&event.Status,
+		&event.StartsAt,
+		&event.EndsAt,
+		&event.TimeZone,
+		&event.Created,
+		&event.BookWindow,
+		&event.TotalSeats,
+		&event.AvailSeats,
+		&event.MaxPerBook,
+		&event.CancelReason,
+		&event.OwnerID,
+		&event.OrgID,
+		&event.Venue,
+		&event.Address,
+		&event.Contact,
+		&event.CoverURL,
+		&event.SeriesID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrEventNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return &event, nil
+}
+
+// eventsVisibleCond - видимость ивентов по роли: $2 - все статусы, иначе только актуальные ($3),
+// но ивенты, которыми управляет $4 (владелец или соорганизатор), видны в любом статусе
+const eventsVisibleCond = `($2 OR status = $3 OR ($4 > 0 AND (owner_id = $4
+	OR EXISTS (SELECT 1 FROM event_organizers eo WHERE eo.event_id = events.id AND eo.user_id = $4))))`
+
+// eventsPageOrder - условие keyset-пагинации и порядок для каждой сортировки; $10 - id последнего ивента страницы, $14 - его дата
+var eventsPageOrder = map[string]struct{ after, order string }{
+	model.SortCreated:     {`id > $10`, `id`},
+	model.SortCreatedDesc: {`id < $10`, `id DESC`},
+	model.SortDate:        {`(starts_at, id) > ($14, $10)`, `starts_at, id`},
+	model.SortDateDesc:    {`(starts_at, id) < ($14, $10)`, `starts_at DESC, id DESC`},
+}
+
+// GetEventsList - страница ивентов организации; сортировка должна быть уже проверена сервисом
+func (pr PostgresRepo) GetEventsList(ctx context.Context, ex repository.Executor, filter model.EventFilter) ([]*model.Event, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	page, ok := eventsPageOrder[filter.Sort]
+	if !ok {
+		page = eventsPageOrder[model.SortCreated]
+	}
+
+	query := `SELECT id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, cancel_reason, COALESCE(owner_id, 0), org_id, venue_name, venue_address, contact, cover_url, COALESCE(series_id, 0)
+	FROM events
+	WHERE org_id = $1 AND ` + eventsVisibleCond + `
+	AND ($5 = '' OR status = $5)
+	AND ($6::timestamptz IS NULL OR starts_at >= $6)
+	AND ($7::timestamptz IS NULL OR starts_at < $7)
+	AND ($8 = '' OR position(lower($8) IN lower(title)) > 0)
+	AND (NOT $9 OR avail_seats > 0)
+	AND ($12 = '' OR EXISTS (SELECT 1 FROM event_categories ec JOIN categories c ON c.id = ec.category_id
+		WHERE ec.event_id = events.id AND c.slug = $12))
+	AND ($13 = '' OR EXISTS (SELECT 1 FROM event_tags et WHERE et.event_id = events.id AND et.tag = $13))
+	AND ($10 = 0 OR ` + page.after + `)
+	ORDER BY ` + page.order + `
+	LIMIT $11`
+	args := []any{filter.OrgID, filter.AllStatuses, model.EventStatusActual, filter.ManagedBy,
+		filter.Status, filter.From, filter.To, filter.Query, filter.HasSeats, 0, filter.Limit, filter.Category, filter.Tag}
+	if filter.After != nil {
+		args[9] = filter.After.ID
+	}
+	if filter.Sort == model.SortDate || filter.Sort == model.SortDateDesc {
+		var after *time.Time
+		if filter.After != nil {
+			after = &filter.After.Date
+		}
+		args = append(args, after)
+	}
+
+	rows, err := exec.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -157,11 +498,22 @@ func (pr PostgresRepo) GetEventsList(ctx context.Context, exec Executor, role st
 			&event.Title,
 			&event.Descr,
 			&event.Status,
-			&event.EventDate,
+			&event.StartsAt,
+			&event.EndsAt,
+			&event.TimeZone,
 			&event.Created,
 			&event.BookWindow,
 			&event.TotalSeats,
-			&event.AvailSeats); err != nil {
+			&event.AvailSeats,
+			&event.MaxPerBook,
+			&event.CancelReason,
+			&event.OwnerID,
+			&event.OrgID,
+			&event.Venue,
+			&event.Address,
+			&event.Contact,
+			&event.CoverURL,
+			&event.SeriesID); err != nil {
 			return nil, err
 		}
 		events = append(events, &event)
@@ -174,19 +526,98 @@ func (pr PostgresRepo) GetEventsList(ctx context.Context, exec Executor, role st
 	return events, nil
 }
 
-func (pr PostgresRepo) GetBookByID(ctx context.Context, exec Executor, id int) (*model.Book, error) {
-	query := `SELECT id, event_id, user_id, status, created_at, confirm_deadline 
+// SearchEvents - полнотекстовый поиск по названию и описанию с той же видимостью, что и у GetEventsList;
+// фрагменты с совпадениями строит ts_headline, описание без совпадений фрагмента не дает
+func (pr PostgresRepo) SearchEvents(ctx context.Context, ex repository.Executor, search model.EventSearch) ([]*model.EventSearchResult, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, cancel_reason, COALESCE(owner_id, 0), org_id, venue_name, venue_address, contact, cover_url, COALESCE(series_id, 0),
+		ts_rank_cd(search_vector, q) AS rank,
+		ts_headline('russian', title, q, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+		CASE WHEN to_tsvector('russian', COALESCE(description, '')) @@ q
+			THEN ts_headline('russian', description, q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=" ... "')
+			ELSE '' END
+	FROM events, websearch_to_tsquery('russian', $5) AS q
+	WHERE org_id = $1 AND search_vector @@ q AND ` + eventsVisibleCond + `
+	ORDER BY rank DESC, id
+	LIMIT $6 OFFSET $7`
+
+	rows, err := exec.QueryContext(ctx, query, search.OrgID, search.AllStatuses, model.EventStatusActual, search.ManagedBy,
+		search.Query, search.Limit, search.Offset)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	results := make([]*model.EventSearchResult, 0)
+
+	for rows.Next() {
+		var res model.EventSearchResult
+		if err := rows.Scan(&res.ID,
+			&res.Title,
+			&res.Descr,
+			&res.Status,
+			&res.StartsAt,
+			&res.EndsAt,
+			&res.TimeZone,
+			&res.Created,
+			&res.BookWindow,
+			&res.TotalSeats,
+			&res.AvailSeats,
+			&res.MaxPerBook,
+			&res.CancelReason,
+			&res.OwnerID,
+			&res.OrgID,
+			&res.Venue,
+			&res.Address,
+			&res.Contact,
+			&res.CoverURL,
+			&res.SeriesID,
+			&res.Rank,
+			&res.TitleHighlight,
+			&res.Snippet); err != nil {
+			return nil, err
+		}
+		results = append(results, &res)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return results, nil
+}
+
+func (pr PostgresRepo) GetBookByID(ctx context.Context, ex repository.Executor, orgID int, id int) (*model.Book, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, event_id, user_id, status, quantity, created_at, confirm_deadline, expired_at, cancelled_at, org_id 
 	FROM bookings 
-	WHERE id = $1 FOR UPDATE`
+	WHERE id = $1 AND org_id = $2 FOR UPDATE`
 
 	var book model.Book
 
-	err := exec.QueryRowContext(ctx, query, id).Scan(&book.ID,
+	err = exec.QueryRowContext(ctx, query, id, orgID).Scan(&book.ID,
 		&book.EventID,
 		&book.UserID,
 		&book.Status,
+		&book.Quantity,
 		&book.Created,
-		&book.ConfirmDeadline)
+		&book.ConfirmDeadline,
+		&book.ExpiredAt,
+		&book.CancelledAt,
+		&book.OrgID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -198,18 +629,69 @@ func (pr PostgresRepo) GetBookByID(ctx context.Context, exec Executor, id int) (
 	return &book, nil
 }
 
-func (pr PostgresRepo) GetBooksListByUser(ctx context.Context, exec Executor, id int) ([]*model.Book, error) {
-	query := `SELECT id, event_id, user_id, status, created_at, confirm_deadline FROM bookings 
-	WHERE user_id = $1`
-	rows, err := exec.QueryContext(ctx, query, id)
+// GetBookByIDNoLock - бронь без блокировки строки: узнать её ивент, чтобы заблокировать его раньше брони
+func (pr PostgresRepo) GetBookByIDNoLock(ctx context.Context, ex repository.Executor, orgID int, id int) (*model.Book, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, event_id, user_id, status, quantity, created_at, confirm_deadline, expired_at, cancelled_at, org_id 
+	FROM bookings 
+	WHERE id = $1 AND org_id = $2`
+
+	var book model.Book
+
+	err = exec.QueryRowContext(ctx, query, id, orgID).Scan(&book.ID,
+		&book.EventID,
+		&book.UserID,
+		&book.Status,
+		&book.Quantity,
+		&book.Created,
+		&book.ConfirmDeadline,
+		&book.ExpiredAt,
+		&book.CancelledAt,
+		&book.OrgID)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, model.ErrUserNotFound
+			return nil, model.ErrBookNotFound
 		default:
 			return nil, err // 500
 		}
 	}
+	return &book, nil
+}
+
+// GetBooksListByUser - страница броней пользователя в организации; сортировка только по порядку создания
+func (pr PostgresRepo) GetBooksListByUser(ctx context.Context, ex repository.Executor, filter model.BookFilter) ([]*model.Book, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	after, order := `id > $6`, `id`
+	if filter.Sort == model.SortCreatedDesc {
+		after, order = `id < $6`, `id DESC`
+	}
+	afterID := 0
+	if filter.After != nil {
+		afterID = filter.After.ID
+	}
+
+	// LIMIT NULL - без ограничения
+	query := `SELECT id, event_id, user_id, status, quantity, created_at, confirm_deadline, expired_at, cancelled_at, org_id FROM bookings 
+	WHERE user_id = $1 AND org_id = $2 
+	AND ($3 = '' OR status = $3)
+	AND ($4::timestamptz IS NULL OR created_at >= $4)
+	AND ($5::timestamptz IS NULL OR created_at < $5)
+	AND ($6 = 0 OR ` + after + `)
+	ORDER BY ` + order + `
+	LIMIT NULLIF($7, 0)`
+	rows, err := exec.QueryContext(ctx, query, filter.UserID, filter.OrgID, filter.Status, filter.From, filter.To, afterID, filter.Limit)
+	if err != nil {
+		return nil, err // 500
+	}
 
 	defer func() {
 		if err := rows.Close(); err != nil {
@@ -225,8 +707,12 @@ func (pr PostgresRepo) GetBooksListByUser(ctx context.Context, exec Executor, id
 			&book.EventID,
 			&book.UserID,
 			&book.Status,
+			&book.Quantity,
 			&book.Created,
-			&book.ConfirmDeadline); err != nil {
+			&book.ConfirmDeadline,
+			&book.ExpiredAt,
+			&book.CancelledAt,
+			&book.OrgID); err != nil {
 			return nil, err
 		}
 		books = append(books, &book)
@@ -239,11 +725,99 @@ func (pr PostgresRepo) GetBooksListByUser(ctx context.Context, exec Executor, id
 	return books, nil
 }
 
-// GetExpiredBooksList - эксклюзивно для воркера BookCleaner
-func (pr PostgresRepo) GetExpiredBooksList(ctx context.Context, exec Executor) ([]*model.Book, error) {
-	query := `SELECT id, event_id, user_id, status, created_at FROM bookings 
-	WHERE confirm_deadline < now() AND status != $1 FOR UPDATE`
-	rows, err := exec.QueryContext(ctx, query, model.BookStatusConfirmed)
+// GetBooksListByEvent - все брони ивента вместе с имейлами пользователей, только для тех, кто управляет ивентом
+func (pr PostgresRepo) GetBooksListByEvent(ctx context.Context, ex repository.Executor, eventID int) ([]*model.BookWithUser, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT b.id, b.event_id, b.user_id, b.status, b.quantity, b.created_at, b.confirm_deadline, b.expired_at, b.cancelled_at, u.email 
+	FROM bookings b 
+	JOIN users u ON u.id = b.user_id 
+	WHERE b.event_id = $1 
+	ORDER BY b.id`
+	rows, err := exec.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	books := make([]*model.BookWithUser, 0)
+
+	for rows.Next() {
+		var book model.BookWithUser
+		if err := rows.Scan(&book.ID,
+			&book.EventID,
+			&book.UserID,
+			&book.Status,
+			&book.Quantity,
+			&book.Created,
+			&book.ConfirmDeadline,
+			&book.ExpiredAt,
+			&book.CancelledAt,
+			&book.Email); err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return books, nil
+}
+
+// GetBookStatsByEvent - количество броней ивента по статусам и ближайший дедлайн неподтвержденной брони
+func (pr PostgresRepo) GetBookStatsByEvent(ctx context.Context, ex repository.Executor, eventID int) (*model.BookStats, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT 
+	COUNT(*) FILTER (WHERE status = $2), 
+	COUNT(*) FILTER (WHERE status = $3), 
+	COUNT(*) FILTER (WHERE status = $4), 
+	COUNT(*) FILTER (WHERE status = $5), 
+	MIN(confirm_deadline) FILTER (WHERE status = $2 AND confirm_deadline > now()) 
+	FROM bookings 
+	WHERE event_id = $1`
+
+	var stats model.BookStats
+
+	err = exec.QueryRowContext(ctx, query, eventID,
+		model.BookStatusCreated,
+		model.BookStatusConfirmed,
+		model.BookStatusCancelled,
+		model.BookStatusExpired).Scan(&stats.Created,
+		&stats.Confirmed,
+		&stats.Cancelled,
+		&stats.Expired,
+		&stats.NextExpiry)
+	if err != nil {
+		return nil, err // 500
+	}
+	return &stats, nil
+}
+
+// GetExpiredBooksList - эксклюзивно для воркера BookCleaner: кандидаты без блокировки, воркер блокирует их после их ивентов
+func (pr PostgresRepo) GetExpiredBooksList(ctx context.Context, ex repository.Executor) ([]*model.Book, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, event_id, user_id, status, quantity, created_at, org_id FROM bookings 
+	WHERE confirm_deadline < now() AND status = $1 
+	ORDER BY event_id, id`
+	rows, err := exec.QueryContext(ctx, query, model.BookStatusCreated)
 	if err != nil {
 		return nil, err
 	}
@@ -262,7 +836,9 @@ func (pr PostgresRepo) GetExpiredBooksList(ctx context.Context, exec Executor) (
 			&book.EventID,
 			&book.UserID,
 			&book.Status,
-			&book.Created); err != nil {
+			&book.Quantity,
+			&book.Created,
+			&book.OrgID); err != nil {
 			return nil, err
 		}
 		books = append(books, &book)
@@ -275,21 +851,135 @@ func (pr PostgresRepo) GetExpiredBooksList(ctx context.Context, exec Executor) (
 	return books, nil
 }
 
-func (pr PostgresRepo) GetUserByID(ctx context.Context, exec Executor, id int) (*model.User, error) {
-	query := `SELECT id, created_at, role, name, surname, tel, email, pass_hash 
+// GetPendingBooksList - неподтвержденные брони для восстановления очереди планировщика истечения
+func (pr PostgresRepo) GetPendingBooksList(ctx context.Context, ex repository.Executor) ([]*model.Book, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, event_id, user_id, status, quantity, created_at, confirm_deadline, org_id FROM bookings 
+	WHERE status = $1 
+	ORDER BY confirm_deadline`
+	rows, err := exec.QueryContext(ctx, query, model.BookStatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	books := make([]*model.Book, 0)
+
+	for rows.Next() {
+		var book model.Book
+		if err := rows.Scan(&book.ID,
+			&book.EventID,
+			&book.UserID,
+			&book.Status,
+			&book.Quantity,
+			&book.Created,
+			&book.ConfirmDeadline,
+			&book.OrgID); err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return books, nil
+}
+
+// GetPastEventsList - эксклюзивно для воркера EventSweeper: актуальные ивенты, которые уже начались
+func (pr PostgresRepo) GetPastEventsList(ctx context.Context, ex repository.Executor) ([]*model.Event, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, cancel_reason, COALESCE(owner_id, 0), org_id, venue_name, venue_address, contact, cover_url, COALESCE(series_id, 0)
+	FROM events 
+	WHERE starts_at <= now() AND status = $1 
+	ORDER BY id FOR UPDATE`
+	rows, err := exec.QueryContext(ctx, query, model.EventStatusActual)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	events := make([]*model.Event, 0)
+
+	for rows.Next() {
+		var event model.Event
+		if err := rows.Scan(&event.ID,
+			&event.Title,
+			&event.Descr,
+			&event.Status,
+			&event.StartsAt,
+			&event.EndsAt,
+			&event.TimeZone,
+			&event.Created,
+			&event.BookWindow,
+			&event.TotalSeats,
+			&event.AvailSeats,
+			&event.MaxPerBook,
+			&event.CancelReason,
+			&event.OwnerID,
+			&event.OrgID,
+			&event.Venue,
+			&event.Address,
+			&event.Contact,
+			&event.CoverURL,
+			&event.SeriesID); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return events, nil
+}
+
+func (pr PostgresRepo) GetUserByID(ctx context.Context, ex repository.Executor, id int) (*model.User, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, created_at, name, surname, tel, email, pass_hash, disabled_at, email_verified_at, locked_until,
+	COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step 
 	FROM users 
 	WHERE id = $1`
 
 	var user model.User
 
-	err := exec.QueryRowContext(ctx, query, id).Scan(&user.ID,
+	err = exec.QueryRowContext(ctx, query, id).Scan(&user.ID,
 		&user.Created,
-		&user.Role,
 		&user.Name,
 		&user.Surname,
 		&user.Tel,
 		&user.Email,
-		&user.PassHash)
+		&user.PassHash,
+		&user.DisabledAt,
+		&user.EmailVerifiedAt,
+		&user.LockedUntil,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+		&user.TOTPLastStep)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -301,21 +991,32 @@ func (pr PostgresRepo) GetUserByID(ctx context.Context, exec Executor, id int) (
 	return &user, nil
 }
 
-func (pr PostgresRepo) GetUserByEmail(ctx context.Context, exec Executor, email string) (*model.User, error) {
-	query := `SELECT id, created_at, role, name, surname, tel, email, pass_hash 
+func (pr PostgresRepo) GetUserByEmail(ctx context.Context, ex repository.Executor, email string) (*model.User, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, created_at, name, surname, tel, email, pass_hash, disabled_at, email_verified_at, locked_until,
+	COALESCE(totp_secret, ''), totp_enabled_at, totp_last_step 
 	FROM users 
 	WHERE email = $1`
 
 	var user model.User
 
-	err := exec.QueryRowContext(ctx, query, email).Scan(&user.ID,
+	err = exec.QueryRowContext(ctx, query, email).Scan(&user.ID,
 		&user.Created,
-		&user.Role,
 		&user.Name,
 		&user.Surname,
 		&user.Tel,
 		&user.Email,
-		&user.PassHash)
+		&user.PassHash,
+		&user.DisabledAt,
+		&user.EmailVerifiedAt,
+		&user.LockedUntil,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+		&user.TOTPLastStep)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -327,12 +1028,18 @@ func (pr PostgresRepo) GetUserByEmail(ctx context.Context, exec Executor, email
 	return &user, nil
 }
 
-func (pr PostgresRepo) IncrementAvailSeatsByEventID(ctx context.Context, exec Executor, eventID int) error {
+// IncreaseAvailSeatsByEventID - возврат n мест ивенту при отмене/истечении брони
+func (pr PostgresRepo) IncreaseAvailSeatsByEventID(ctx context.Context, ex repository.Executor, eventID int, n int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE events 
-	SET avail_seats = avail_seats + 1 
+	SET avail_seats = avail_seats + $2 
 	WHERE id = $1`
 
-	res, err := exec.ExecContext(ctx, query, eventID)
+	res, err := exec.ExecContext(ctx, query, eventID, n)
 	if err != nil {
 		return err // 500
 	}
@@ -344,18 +1051,32 @@ func (pr PostgresRepo) IncrementAvailSeatsByEventID(ctx context.Context, exec Ex
 	return nil
 }
 
-func (pr PostgresRepo) DecrementAvailSeatsByEventID(ctx context.Context, exec Executor, eventID int) error {
+// DecreaseAvailSeatsByEventID - атомарное списание n мест: если свободных мест меньше n, ничего не меняется
+func (pr PostgresRepo) DecreaseAvailSeatsByEventID(ctx context.Context, ex repository.Executor, eventID int, n int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE events 
-	SET avail_seats = avail_seats - 1 
-	WHERE id = $1`
+	SET avail_seats = avail_seats - $2 
+	WHERE id = $1 AND avail_seats >= $2`
 
-	res, err := exec.ExecContext(ctx, query, eventID)
+	res, err := exec.ExecContext(ctx, query, eventID, n)
 	if err != nil {
 		return err // 500
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return model.ErrEventNotFound // 404
+		// различаем отсутствие ивента и нехватку мест
+		var exists bool
+		if err := exec.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM events WHERE id = $1)`, eventID).Scan(&exists); err != nil {
+			return err // 500
+		}
+		if !exists {
+			return model.ErrEventNotFound // 404
+		}
+		return model.ErrNoSeatsAvailable // 409
 	}
 
 	return nil
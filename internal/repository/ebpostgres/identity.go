@@ -0,0 +1,49 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (pr PostgresRepo) CreateUserIdentity(ctx context.Context, ex repository.Executor, identity *model.UserIdentity) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_identities (id, user_id, issuer, subject, email, created_at)
+	VALUES (DEFAULT, $1, $2, $3, $4, DEFAULT) RETURNING id, created_at`
+	return exec.QueryRowContext(ctx, query, identity.UserID, identity.Issuer, identity.Subject, identity.Email).Scan(&identity.ID, &identity.Created)
+}
+
+func (pr PostgresRepo) GetUserIdentity(ctx context.Context, ex repository.Executor, issuer string, subject string) (*model.UserIdentity, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, issuer, subject, email, created_at
+	FROM user_identities
+	WHERE issuer = $1 AND subject = $2`
+
+	var identity model.UserIdentity
+	err = exec.QueryRowContext(ctx, query, issuer, subject).Scan(&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.Created)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrIdentityNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return &identity, nil
+}
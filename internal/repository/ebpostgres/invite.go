@@ -0,0 +1,111 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+func (pr PostgresRepo) CreateInvite(ctx context.Context, ex repository.Executor, invite *model.Invite) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO invites (id, token_hash, role, email, created_by, created_at, expires_at, org_id)
+	VALUES (DEFAULT, $1, $2, $3, NULLIF($4, 0), DEFAULT, $5, $6) RETURNING id, created_at`
+	err = exec.QueryRowContext(ctx, query, invite.TokenHash, invite.Role, invite.Email, invite.CreatedBy, invite.ExpiresAt, invite.OrgID).Scan(&invite.ID, &invite.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrInviteExists // 409
+		}
+		return err
+	}
+	return nil
+}
+
+// GetInviteByTokenHash - select FOR UPDATE: одно приглашение нельзя использовать дважды параллельными регистрациями
+func (pr PostgresRepo) GetInviteByTokenHash(ctx context.Context, ex repository.Executor, hash string) (*model.Invite, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, token_hash, role, email, COALESCE(created_by, 0), created_at, expires_at, COALESCE(used_by, 0), used_at, org_id
+	FROM invites
+	WHERE token_hash = $1
+	FOR UPDATE`
+
+	var invite model.Invite
+
+	err = exec.QueryRowContext(ctx, query, hash).Scan(&invite.ID,
+		&invite.TokenHash,
+		&invite.Role,
+		&invite.Email,
+		&invite.CreatedBy,
+		&invite.Created,
+		&invite.ExpiresAt,
+		&invite.UsedBy,
+		&invite.UsedAt,
+		&invite.OrgID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrInviteNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return &invite, nil
+}
+
+// ExtendUnusedInvite продлевает срок неиспользованного приглашения; использованное не трогает - ErrInviteNotFound
+func (pr PostgresRepo) ExtendUnusedInvite(ctx context.Context, ex repository.Executor, hash string, expiresAt time.Time) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE invites
+	SET expires_at = $1
+	WHERE token_hash = $2 AND used_at IS NULL`
+
+	res, err := exec.ExecContext(ctx, query, expiresAt, hash)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrInviteNotFound
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) MarkInviteUsed(ctx context.Context, ex repository.Executor, inviteID int, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE invites
+	SET used_by = $1, used_at = now()
+	WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, userID, inviteID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrInviteNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package ebpostgres
+
+import (
+	"context"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// SetTOTPSecret - новый секрет при настройке 2FA; включается она только после подтверждения кодом
+func (pr PostgresRepo) SetTOTPSecret(ctx context.Context, ex repository.Executor, userID int, secret string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET totp_secret = $1, totp_enabled_at = NULL, totp_last_step = 0
+	WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// EnableTOTP включает 2FA; step - шаг кода, которым она подтверждена
+func (pr PostgresRepo) EnableTOTP(ctx context.Context, ex repository.Executor, userID int, step int64) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET totp_enabled_at = now(), totp_last_step = $1
+	WHERE id = $2 AND totp_secret IS NOT NULL`
+
+	res, err := exec.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrMFANotSetUp
+	}
+
+	return nil
+}
+
+// DisableTOTP выключает 2FA и удаляет коды восстановления
+func (pr PostgresRepo) DisableTOTP(ctx context.Context, ex repository.Executor, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0
+	WHERE id = $1`
+
+	res, err := exec.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserNotFound
+	}
+
+	_, err = exec.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	return err // 500
+}
+
+// UseTOTPStep принимает код с шагом step, только если он новее последнего принятого - один код нельзя использовать дважды
+func (pr PostgresRepo) UseTOTPStep(ctx context.Context, ex repository.Executor, userID int, step int64) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET totp_last_step = $1
+	WHERE id = $2 AND totp_last_step < $1`
+
+	res, err := exec.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми
+func (pr PostgresRepo) ReplaceRecoveryCodes(ctx context.Context, ex repository.Executor, userID int, hashes []string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	if _, err := exec.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err // 500
+	}
+
+	query := `INSERT INTO recovery_codes (id, user_id, code_hash, used_at)
+	VALUES (DEFAULT, $1, $2, NULL)`
+	for _, hash := range hashes {
+		if _, err := exec.ExecContext(ctx, query, userID, hash); err != nil {
+			return err // 500
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode гасит неиспользованный код восстановления пользователя
+func (pr PostgresRepo) UseRecoveryCode(ctx context.Context, ex repository.Executor, userID int, hash string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE recovery_codes
+	SET used_at = now()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	res, err := exec.ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrInvalidMFACode
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+func (pr PostgresRepo) CreateOrganization(ctx context.Context, ex repository.Executor, org *model.Organization) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO organizations (id, name, slug, created_at)
+	VALUES (DEFAULT, $1, $2, DEFAULT) RETURNING id, created_at`
+	err = exec.QueryRowContext(ctx, query, org.Name, org.Slug).Scan(&org.ID, &org.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrOrgSlugTaken // 409
+		}
+		return err
+	}
+	return nil
+}
+
+func (pr PostgresRepo) GetOrganizationBySlug(ctx context.Context, ex repository.Executor, slug string) (*model.Organization, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, name, slug, created_at
+	FROM organizations
+	WHERE slug = $1`
+
+	var org model.Organization
+
+	err = exec.QueryRowContext(ctx, query, slug).Scan(&org.ID, &org.Name, &org.Slug, &org.Created)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrOrgNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return &org, nil
+}
+
+// GetUserOrganizations - организации пользователя с его ролью в каждой, по порядку создания
+func (pr PostgresRepo) GetUserOrganizations(ctx context.Context, ex repository.Executor, userID int) ([]*model.Organization, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT o.id, o.name, o.slug, o.created_at, m.role
+	FROM org_members m
+	JOIN organizations o ON o.id = m.org_id
+	WHERE m.user_id = $1
+	ORDER BY o.id`
+	rows, err := exec.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	orgs := make([]*model.Organization, 0)
+
+	for rows.Next() {
+		var org model.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.Created, &org.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, &org)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return orgs, nil
+}
+
+func (pr PostgresRepo) AddOrgMember(ctx context.Context, ex repository.Executor, orgID int, userID int, role string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO org_members (org_id, user_id, role, created_at)
+	VALUES ($1, $2, $3, DEFAULT)`
+	if _, err := exec.ExecContext(ctx, query, orgID, userID, role); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrAlreadyOrgMember
+		}
+		return err // 500
+	}
+	return nil
+}
+
+func (pr PostgresRepo) GetOrgMemberRole(ctx context.Context, ex repository.Executor, orgID int, userID int) (string, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return "", err
+	}
+
+	query := `SELECT role FROM org_members WHERE org_id = $1 AND user_id = $2`
+
+	var role string
+	if err := exec.QueryRowContext(ctx, query, orgID, userID).Scan(&role); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", model.ErrNotOrgMember
+		default:
+			return "", err // 500
+		}
+	}
+	return role, nil
+}
+
+func (pr PostgresRepo) UpdateOrgMemberRole(ctx context.Context, ex repository.Executor, orgID int, userID int, role string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE org_members
+	SET role = $1
+	WHERE org_id = $2 AND user_id = $3`
+
+	res, err := exec.ExecContext(ctx, query, role, orgID, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotOrgMember
+	}
+
+	return nil
+}
+
+// RemoveOrgMember - исключение из организации; пользователь перестает быть и соорганизатором её ивентов,
+// а его брони остаются - места держатся до отмены или истечения
+func (pr PostgresRepo) RemoveOrgMember(ctx context.Context, ex repository.Executor, orgID int, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM org_members
+	WHERE org_id = $1 AND user_id = $2`
+
+	res, err := exec.ExecContext(ctx, query, orgID, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotOrgMember
+	}
+
+	query = `DELETE FROM event_organizers
+	WHERE user_id = $2 AND event_id IN (SELECT id FROM events WHERE org_id = $1)`
+	if _, err := exec.ExecContext(ctx, query, orgID, userID); err != nil {
+		return err // 500
+	}
+
+	// бывший участник не увидит бронь из очереди, а места ждали бы его весь срок подтверждения
+	query = `DELETE FROM waitlist
+	WHERE user_id = $2 AND event_id IN (SELECT id FROM events WHERE org_id = $1)`
+	if _, err := exec.ExecContext(ctx, query, orgID, userID); err != nil {
+		return err // 500
+	}
+
+	return nil
+}
+
+// HasOrgMemberWithRole - есть ли в организации хотя бы один участник с ролью (для бутстрапа первого админа)
+func (pr PostgresRepo) HasOrgMemberWithRole(ctx context.Context, ex repository.Executor, orgID int, role string) (bool, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return false, err
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM org_members WHERE org_id = $1 AND role = $2)`
+
+	var found bool
+	if err := exec.QueryRowContext(ctx, query, orgID, role).Scan(&found); err != nil {
+		return false, err // 500
+	}
+	return found, nil
+}
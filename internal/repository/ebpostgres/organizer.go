@@ -0,0 +1,108 @@
+package ebpostgres
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+func (pr PostgresRepo) AddEventOrganizer(ctx context.Context, ex repository.Executor, eventID int, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO event_organizers (event_id, user_id, added_at)
+	VALUES ($1, $2, DEFAULT)`
+	if _, err := exec.ExecContext(ctx, query, eventID, userID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrAlreadyOrganizer
+		}
+		return err // 500
+	}
+	return nil
+}
+
+func (pr PostgresRepo) RemoveEventOrganizer(ctx context.Context, ex repository.Executor, eventID int, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM event_organizers
+	WHERE event_id = $1 AND user_id = $2`
+
+	res, err := exec.ExecContext(ctx, query, eventID, userID)
+	if err != nil {
+		return err // 500
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err // 500
+	}
+	if rows == 0 {
+		return model.ErrOrganizerNotFound // 404
+	}
+	return nil
+}
+
+// GetEventOrganizers - соорганизаторы ивента с имейлами в порядке назначения
+func (pr PostgresRepo) GetEventOrganizers(ctx context.Context, ex repository.Executor, eventID int) ([]*model.EventOrganizer, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT eo.event_id, eo.user_id, u.email, eo.added_at
+	FROM event_organizers eo
+	JOIN users u ON u.id = eo.user_id
+	WHERE eo.event_id = $1
+	ORDER BY eo.added_at, eo.user_id`
+	rows, err := exec.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	organizers := make([]*model.EventOrganizer, 0)
+
+	for rows.Next() {
+		var eo model.EventOrganizer
+		if err := rows.Scan(&eo.EventID, &eo.UserID, &eo.Email, &eo.Added); err != nil {
+			return nil, err
+		}
+		organizers = append(organizers, &eo)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return organizers, nil
+}
+
+func (pr PostgresRepo) IsEventOrganizer(ctx context.Context, ex repository.Executor, eventID int, userID int) (bool, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return false, err
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM event_organizers WHERE event_id = $1 AND user_id = $2)`
+
+	var found bool
+	if err := exec.QueryRowContext(ctx, query, eventID, userID).Scan(&found); err != nil {
+		return false, err
+	}
+	return found, nil
+}
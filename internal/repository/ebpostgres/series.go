@@ -0,0 +1,228 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+const seriesColumns = `id, org_id, COALESCE(owner_id, 0), rrule, title, description, starts_at, ends_at, timezone, bookwindow, total_seats, max_per_book,
+	venue_name, venue_address, contact, cover_url, categories, tags, generated_until, finished, created_at`
+
+func (pr PostgresRepo) CreateSeries(ctx context.Context, ex repository.Executor, series *model.EventSeries) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	t := series.Template
+	query := `INSERT INTO event_series (id, org_id, owner_id, rrule, title, description, starts_at, ends_at, timezone, bookwindow, total_seats, max_per_book,
+	venue_name, venue_address, contact, cover_url, categories, tags, created_at)
+	VALUES (DEFAULT, $1, NULLIF($2, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, COALESCE($16::text[], '{}'), COALESCE($17::text[], '{}'), DEFAULT)
+	RETURNING id, created_at`
+	return exec.QueryRowContext(ctx, query, series.OrgID, series.OwnerID, series.RRule, t.Title, t.Descr, t.StartsAt, t.EndsAt, t.TimeZone, t.BookWindow, t.TotalSeats, t.MaxPerBook,
+		t.Venue, t.Address, t.Contact, t.CoverURL, pq.Array(t.Categories), pq.Array(t.Tags)).Scan(&series.ID, &series.Created)
+}
+
+func (pr PostgresRepo) GetSeriesByID(ctx context.Context, ex repository.Executor, orgID int, id int) (*model.EventSeries, error) { // select FOR UPDATE
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + seriesColumns + `
+	FROM event_series
+	WHERE id = $1 AND org_id = $2 FOR UPDATE`
+
+	series, err := scanSeries(exec.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrSeriesNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return series, nil
+}
+
+func (pr PostgresRepo) GetSeriesByIDNoLock(ctx context.Context, ex repository.Executor, orgID int, id int) (*model.EventSeries, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + seriesColumns + `
+	FROM event_series
+	WHERE id = $1 AND org_id = $2`
+
+	series, err := scanSeries(exec.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrSeriesNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return series, nil
+}
+
+// GetPendingSeries - эксклюзивно для планировщика серий: незавершенные серии, вхождения которых созданы не до horizon
+func (pr PostgresRepo) GetPendingSeries(ctx context.Context, ex repository.Executor, horizon time.Time) ([]*model.EventSeries, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + seriesColumns + `
+	FROM event_series
+	WHERE NOT finished AND (generated_until IS NULL OR generated_until < $1)
+	ORDER BY id`
+	rows, err := exec.QueryContext(ctx, query, horizon)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	list := make([]*model.EventSeries, 0)
+
+	for rows.Next() {
+		series, err := scanSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, series)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return list, nil
+}
+
+func (pr PostgresRepo) UpdateSeries(ctx context.Context, ex repository.Executor, series *model.EventSeries) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	t := series.Template
+	query := `UPDATE event_series
+	SET title = $1, description = $2, starts_at = $3, ends_at = $4, bookwindow = $5, total_seats = $6, max_per_book = $7,
+	venue_name = $8, venue_address = $9, contact = $10, cover_url = $11, categories = COALESCE($12::text[], '{}'), tags = COALESCE($13::text[], '{}'),
+	generated_until = $14, finished = $15
+	WHERE id = $16`
+
+	res, err := exec.ExecContext(ctx, query, t.Title, t.Descr, t.StartsAt, t.EndsAt, t.BookWindow, t.TotalSeats, t.MaxPerBook,
+		t.Venue, t.Address, t.Contact, t.CoverURL, pq.Array(t.Categories), pq.Array(t.Tags), series.GeneratedUntil, series.Finished, series.ID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrSeriesNotFound
+	}
+
+	return nil
+}
+
+// GetSeriesEvents - вхождения серии в любом статусе с началом не раньше from
+func (pr PostgresRepo) GetSeriesEvents(ctx context.Context, ex repository.Executor, seriesID int, from time.Time) ([]*model.Event, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, title, description, status, starts_at, ends_at, timezone, created_at, bookwindow, total_seats, avail_seats, max_per_book, cancel_reason, COALESCE(owner_id, 0), org_id, venue_name, venue_address, contact, cover_url, COALESCE(series_id, 0)
+	FROM events
+	WHERE series_id = $1 AND starts_at >= $2
+	ORDER BY starts_at, id`
+	rows, err := exec.QueryContext(ctx, query, seriesID, from)
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	events := make([]*model.Event, 0)
+
+	for rows.Next() {
+		var event model.Event
+		if err := rows.Scan(&event.ID,
+			&event.Title,
+			&event.Descr,
+			&event.Status,
+			&event.StartsAt,
+			&event.EndsAt,
+			&event.TimeZone,
+			&event.Created,
+			&event.BookWindow,
+			&event.TotalSeats,
+			&event.AvailSeats,
+			&event.MaxPerBook,
+			&event.CancelReason,
+			&event.OwnerID,
+			&event.OrgID,
+			&event.Venue,
+			&event.Address,
+			&event.Contact,
+			&event.CoverURL,
+			&event.SeriesID); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return events, nil
+}
+
+func scanSeries(row rowScanner) (*model.EventSeries, error) {
+	var s model.EventSeries
+
+	err := row.Scan(&s.ID,
+		&s.OrgID,
+		&s.OwnerID,
+		&s.RRule,
+		&s.Template.Title,
+		&s.Template.Descr,
+		&s.Template.StartsAt,
+		&s.Template.EndsAt,
+		&s.Template.TimeZone,
+		&s.Template.BookWindow,
+		&s.Template.TotalSeats,
+		&s.Template.MaxPerBook,
+		&s.Template.Venue,
+		&s.Template.Address,
+		&s.Template.Contact,
+		&s.Template.CoverURL,
+		pq.Array(&s.Template.Categories),
+		pq.Array(&s.Template.Tags),
+		&s.GeneratedUntil,
+		&s.Finished,
+		&s.Created)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
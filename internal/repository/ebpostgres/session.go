@@ -0,0 +1,170 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (pr PostgresRepo) CreateSession(ctx context.Context, ex repository.Executor, session *model.Session) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO sessions (id, user_id, token_hash, created_at, expires_at, mfa, org_id)
+	VALUES (DEFAULT, $1, $2, DEFAULT, $3, $4, $5) RETURNING id, created_at`
+	return exec.QueryRowContext(ctx, query, session.UserID, session.TokenHash, session.ExpiresAt, session.MFA, session.OrgID).Scan(&session.ID, &session.Created)
+}
+
+func (pr PostgresRepo) GetSessionByID(ctx context.Context, ex repository.Executor, sessionID int) (*model.Session, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, token_hash, created_at, expires_at, revoked_at, mfa, org_id
+	FROM sessions
+	WHERE id = $1`
+
+	return scanSession(exec.QueryRowContext(ctx, query, sessionID))
+}
+
+// GetSessionByTokenHash - select FOR UPDATE: параллельные refresh одним токеном выполняются по очереди
+func (pr PostgresRepo) GetSessionByTokenHash(ctx context.Context, ex repository.Executor, hash string) (*model.Session, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, token_hash, created_at, expires_at, revoked_at, mfa, org_id
+	FROM sessions
+	WHERE token_hash = $1
+	FOR UPDATE`
+
+	return scanSession(exec.QueryRowContext(ctx, query, hash))
+}
+
+// RotateSessionToken - замена refresh-токена сессии на новый с продлением срока жизни
+func (pr PostgresRepo) RotateSessionToken(ctx context.Context, ex repository.Executor, sessionID int, hash string, expiresAt time.Time) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE sessions
+	SET token_hash = $1, expires_at = $2
+	WHERE id = $3`
+
+	res, err := exec.ExecContext(ctx, query, hash, expiresAt, sessionID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// SetSessionOrg - смена текущей организации сессии
+func (pr PostgresRepo) SetSessionOrg(ctx context.Context, ex repository.Executor, sessionID int, orgID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE sessions
+	SET org_id = $1
+	WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, orgID, sessionID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) RevokeSession(ctx context.Context, ex repository.Executor, sessionID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE sessions
+	SET revoked_at = COALESCE(revoked_at, now())
+	WHERE id = $1`
+
+	res, err := exec.ExecContext(ctx, query, sessionID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeSessionsByUser - "выйти на всех устройствах"
+func (pr PostgresRepo) RevokeSessionsByUser(ctx context.Context, ex repository.Executor, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE sessions
+	SET revoked_at = now()
+	WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err = exec.ExecContext(ctx, query, userID)
+	return err // 500
+}
+
+// RevokeOrgSessions - отзыв сессий пользователя в организации: роль в ней изменилась или участие прекращено
+func (pr PostgresRepo) RevokeOrgSessions(ctx context.Context, ex repository.Executor, orgID int, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE sessions
+	SET revoked_at = now()
+	WHERE org_id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	_, err = exec.ExecContext(ctx, query, orgID, userID)
+	return err // 500
+}
+
+func scanSession(row *sql.Row) (*model.Session, error) {
+	var session model.Session
+
+	err := row.Scan(&session.ID,
+		&session.UserID,
+		&session.TokenHash,
+		&session.Created,
+		&session.ExpiresAt,
+		&session.RevokedAt,
+		&session.MFA,
+		&session.OrgID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrSessionNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return &session, nil
+}
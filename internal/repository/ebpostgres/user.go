@@ -0,0 +1,262 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+// usersSearchCond - подстрока имейла/имени/фамилии без учета регистра; position вместо LIKE,
+// чтобы спецсимволы % и _ в поисковой строке не требовали экранирования
+const usersSearchCond = `($2 = ''
+	OR position(lower($2) IN lower(u.email)) > 0
+	OR position(lower($2) IN lower(COALESCE(u.name, ''))) > 0
+	OR position(lower($2) IN lower(COALESCE(u.surname, ''))) > 0)`
+
+// GetUsersList - постраничный поиск участников организации с их ролью в ней, только для админа
+func (pr PostgresRepo) GetUsersList(ctx context.Context, ex repository.Executor, filter model.UserFilter) ([]*model.User, int, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) 
+	FROM users u 
+	JOIN org_members m ON m.user_id = u.id AND m.org_id = $1 
+	WHERE ` + usersSearchCond
+	if err := exec.QueryRowContext(ctx, countQuery, filter.OrgID, filter.Query).Scan(&total); err != nil {
+		return nil, 0, err // 500
+	}
+
+	query := `SELECT u.id, u.created_at, m.role, u.name, u.surname, u.tel, u.email, u.disabled_at, u.email_verified_at, u.locked_until, u.totp_enabled_at 
+	FROM users u 
+	JOIN org_members m ON m.user_id = u.id AND m.org_id = $1 
+	WHERE ` + usersSearchCond + ` 
+	ORDER BY u.id 
+	LIMIT $3 OFFSET $4`
+	rows, err := exec.QueryContext(ctx, query, filter.OrgID, filter.Query, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	users := make([]*model.User, 0)
+
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID,
+			&user.Created,
+			&user.Role,
+			&user.Name,
+			&user.Surname,
+			&user.Tel,
+			&user.Email,
+			&user.DisabledAt,
+			&user.EmailVerifiedAt,
+			&user.LockedUntil,
+			&user.TOTPEnabledAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+
+	if rows.Err() != nil {
+		return nil, 0, rows.Err()
+	}
+
+	return users, total, nil
+}
+
+// SetUserDisabled - блокировка/разблокировка аккаунта; время первой блокировки сохраняется при повторной
+func (pr PostgresRepo) SetUserDisabled(ctx context.Context, ex repository.Executor, userID int, disabled bool) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET disabled_at = CASE WHEN $1 THEN COALESCE(disabled_at, now()) ELSE NULL END
+	WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, disabled, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// DeleteUser - брони, очередь ожидания, участие в организациях и сессии пользователя удаляются каскадно;
+// места активных броней сервис должен вернуть ивентам до удаления
+func (pr PostgresRepo) DeleteUser(ctx context.Context, ex repository.Executor, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM users
+	WHERE id = $1`
+
+	res, err := exec.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// GetActiveBooksByUser - брони пользователя во всех организациях, держащие места (created/confirmed), select FOR UPDATE
+func (pr PostgresRepo) GetActiveBooksByUser(ctx context.Context, ex repository.Executor, userID int) ([]*model.Book, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, event_id, user_id, status, quantity, created_at, confirm_deadline, expired_at, cancelled_at, org_id FROM bookings 
+	WHERE user_id = $1 AND status = ANY($2) 
+	ORDER BY id 
+	FOR UPDATE`
+	rows, err := exec.QueryContext(ctx, query, userID, pq.Array([]string{model.BookStatusCreated, model.BookStatusConfirmed}))
+	if err != nil {
+		return nil, err // 500
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Printf("Error while closing *sql.Rows after scanning: %v", err)
+		}
+	}()
+
+	books := make([]*model.Book, 0)
+
+	for rows.Next() {
+		var book model.Book
+		if err := rows.Scan(&book.ID,
+			&book.EventID,
+			&book.UserID,
+			&book.Status,
+			&book.Quantity,
+			&book.Created,
+			&book.ConfirmDeadline,
+			&book.ExpiredAt,
+			&book.CancelledAt,
+			&book.OrgID); err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return books, nil
+}
+
+func (pr PostgresRepo) SetEmailVerified(ctx context.Context, ex repository.Executor, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET email_verified_at = COALESCE(email_verified_at, now())
+	WHERE id = $1`
+
+	res, err := exec.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) UpdateUserPassword(ctx context.Context, ex repository.Executor, userID int, passHash string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET pass_hash = $1
+	WHERE id = $2`
+
+	res, err := exec.ExecContext(ctx, query, passHash, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (pr PostgresRepo) RegisterLoginFailure(ctx context.Context, ex repository.Executor, userID int, since time.Time, maxFailures int, lockUntil time.Time) (*time.Time, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	// счетчик и блокировка считаются одним UPDATE по старым значениям строки - параллельные попытки не теряются
+	query := `UPDATE users
+	SET failed_logins = CASE WHEN failed_login_at > $2 THEN failed_logins + 1 ELSE 1 END,
+		failed_login_at = now(),
+		locked_until = CASE WHEN (CASE WHEN failed_login_at > $2 THEN failed_logins + 1 ELSE 1 END) >= $3 THEN $4 ELSE locked_until END
+	WHERE id = $1
+	RETURNING locked_until`
+
+	var lockedUntil *time.Time
+	err = exec.QueryRowContext(ctx, query, userID, since, maxFailures, lockUntil).Scan(&lockedUntil)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrUserNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	if lockedUntil != nil && !lockedUntil.After(time.Now()) {
+		return nil, nil // прошлая блокировка уже истекла
+	}
+	return lockedUntil, nil
+}
+
+func (pr PostgresRepo) ResetLoginFailures(ctx context.Context, ex repository.Executor, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users
+	SET failed_logins = 0, failed_login_at = NULL, locked_until = NULL
+	WHERE id = $1 AND (failed_logins > 0 OR locked_until IS NOT NULL)`
+
+	_, err = exec.ExecContext(ctx, query, userID)
+	return err // 500
+}
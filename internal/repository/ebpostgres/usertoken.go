@@ -0,0 +1,91 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+func (pr PostgresRepo) CreateUserToken(ctx context.Context, ex repository.Executor, token *model.UserToken) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_tokens (id, user_id, purpose, token_hash, created_at, expires_at)
+	VALUES (DEFAULT, $1, $2, $3, DEFAULT, $4) RETURNING id, created_at`
+	return exec.QueryRowContext(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.Created)
+}
+
+// GetUserTokenByHash - select FOR UPDATE: один токен нельзя использовать дважды параллельными запросами
+func (pr PostgresRepo) GetUserTokenByHash(ctx context.Context, ex repository.Executor, hash string, purpose string) (*model.UserToken, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, user_id, purpose, token_hash, created_at, expires_at, used_at
+	FROM user_tokens
+	WHERE token_hash = $1 AND purpose = $2
+	FOR UPDATE`
+
+	var token model.UserToken
+
+	err = exec.QueryRowContext(ctx, query, hash, purpose).Scan(&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.Created,
+		&token.ExpiresAt,
+		&token.UsedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrUserTokenNotFound
+		default:
+			return nil, err // 500
+		}
+	}
+	return &token, nil
+}
+
+func (pr PostgresRepo) MarkUserTokenUsed(ctx context.Context, ex repository.Executor, tokenID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE user_tokens
+	SET used_at = now()
+	WHERE id = $1`
+
+	res, err := exec.ExecContext(ctx, query, tokenID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrUserTokenNotFound
+	}
+
+	return nil
+}
+
+// InvalidateUserTokens - гасит все неиспользованные токены пользователя с указанным назначением,
+// чтобы действовала только последняя отправленная ссылка
+func (pr PostgresRepo) InvalidateUserTokens(ctx context.Context, ex repository.Executor, userID int, purpose string) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE user_tokens
+	SET used_at = now()
+	WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+
+	_, err = exec.ExecContext(ctx, query, userID, purpose)
+	return err // 500
+}
@@ -0,0 +1,144 @@
+package ebpostgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/lib/pq"
+)
+
+func (pr PostgresRepo) CreateWaitlistEntry(ctx context.Context, ex repository.Executor, entry *model.WaitlistEntry) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO waitlist (id, event_id, user_id, quantity, created_at)
+	VALUES (DEFAULT, $1, $2, $3, DEFAULT) RETURNING id, created_at`
+	err = exec.QueryRowContext(ctx, query, entry.EventID, entry.UserID, entry.Quantity).Scan(&entry.ID, &entry.Created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return model.ErrAlreadyWaitlisted // 409
+		}
+		return err
+	}
+	return nil
+}
+
+func (pr PostgresRepo) DeleteWaitlistEntry(ctx context.Context, ex repository.Executor, eventID int, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM waitlist
+	WHERE event_id = $1 AND user_id = $2`
+
+	res, err := exec.ExecContext(ctx, query, eventID, userID)
+	if err != nil {
+		return err // 500
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return model.ErrNotWaitlisted // 404
+	}
+
+	return nil
+}
+
+// DeleteWaitlistByEvent - очистка очереди при отмене/истечении ивента
+func (pr PostgresRepo) DeleteWaitlistByEvent(ctx context.Context, ex repository.Executor, eventID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM waitlist
+	WHERE event_id = $1`
+
+	_, err = exec.ExecContext(ctx, query, eventID)
+	return err // 500
+}
+
+// DeleteWaitlistByUser - заблокированный пользователь не сможет подтвердить бронь из очереди, поэтому уходит из всех очередей
+func (pr PostgresRepo) DeleteWaitlistByUser(ctx context.Context, ex repository.Executor, userID int) error {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM waitlist
+	WHERE user_id = $1`
+
+	_, err = exec.ExecContext(ctx, query, userID)
+	return err // 500
+}
+
+// GetWaitlistHead - первый в очереди ивента вместе с имейлом для уведомления, select FOR UPDATE
+func (pr PostgresRepo) GetWaitlistHead(ctx context.Context, ex repository.Executor, eventID int) (*model.WaitlistEntry, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT w.id, w.event_id, w.user_id, w.quantity, w.created_at, u.email
+	FROM waitlist w
+	JOIN users u ON u.id = w.user_id
+	WHERE w.event_id = $1
+	ORDER BY w.id
+	LIMIT 1
+	FOR UPDATE OF w`
+
+	entry := model.WaitlistEntry{Position: 1}
+
+	err = exec.QueryRowContext(ctx, query, eventID).Scan(&entry.ID,
+		&entry.EventID,
+		&entry.UserID,
+		&entry.Quantity,
+		&entry.Created,
+		&entry.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrNotWaitlisted
+		default:
+			return nil, err // 500
+		}
+	}
+	return &entry, nil
+}
+
+// GetWaitlistEntry - запись пользователя в очереди ивента с его текущей позицией
+func (pr PostgresRepo) GetWaitlistEntry(ctx context.Context, ex repository.Executor, eventID int, userID int) (*model.WaitlistEntry, error) {
+	exec, err := asSQL(ex)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT w.id, w.event_id, w.user_id, w.quantity, w.created_at,
+	(SELECT COUNT(*) FROM waitlist p WHERE p.event_id = w.event_id AND p.id <= w.id)
+	FROM waitlist w
+	WHERE w.event_id = $1 AND w.user_id = $2`
+
+	var entry model.WaitlistEntry
+
+	err = exec.QueryRowContext(ctx, query, eventID, userID).Scan(&entry.ID,
+		&entry.EventID,
+		&entry.UserID,
+		&entry.Quantity,
+		&entry.Created,
+		&entry.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, model.ErrNotWaitlisted
+		default:
+			return nil, err // 500
+		}
+	}
+	return &entry, nil
+}
@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
-	"github.com/UnendingLoop/EventBooker/internal/repository/ebpostgres"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -17,30 +16,139 @@ import (
 	"github.com/wb-go/wbf/dbpg"
 )
 
-type EBRepo interface {
-	CreateEvent(ctx context.Context, exec ebpostgres.Executor, newEvent *model.Event) error // только для админа
-	CreateBook(ctx context.Context, exec ebpostgres.Executor, newBook *model.Book) error
-	CreateUser(ctx context.Context, exec ebpostgres.Executor, newUser *model.User) error
-
-	DeleteEvent(ctx context.Context, exec ebpostgres.Executor, eventID int) error // только для админа
-	DeleteBook(ctx context.Context, exec ebpostgres.Executor, bookID int) error   // эксклюзивно для воркера BookCleaner
-
-	UpdateBookStatus(ctx context.Context, exec ebpostgres.Executor, bookID int, newStatus string) error
+// Executor - исполнитель запросов, который сервис передает в методы EBRepo: подключение к хранилищу либо открытая транзакция.
+// Конкретный тип определяется реализацией EBRepo и выдается её TxManager.
+type Executor any
 
-	GetEventByID(ctx context.Context, exec ebpostgres.Executor, eventID int) (*model.Event, error)
-	GetEventsList(ctx context.Context, exec ebpostgres.Executor, role string) ([]*model.Event, error)
-	GetBookByID(ctx context.Context, exec ebpostgres.Executor, bookID int) (*model.Book, error)
-	GetBooksListByUser(ctx context.Context, exec ebpostgres.Executor, id int) ([]*model.Book, error)
-	GetExpiredBooksList(ctx context.Context, exec ebpostgres.Executor) ([]*model.Book, error)
-	GetUserByID(ctx context.Context, exec ebpostgres.Executor, userID int) (*model.User, error)
-	GetUserByEmail(ctx context.Context, exec ebpostgres.Executor, email string) (*model.User, error)
+// Tx - открытая транзакция хранилища
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
 
-	IncrementAvailSeatsByEventID(ctx context.Context, exec ebpostgres.Executor, eventID int) error
-	DecrementAvailSeatsByEventID(ctx context.Context, exec ebpostgres.Executor, eventID int) error
+// TxManager отвязывает сервисный слой от конкретного хранилища: выдает исполнитель без транзакции и открывает транзакции
+type TxManager interface {
+	Executor() Executor
+	BeginTx(ctx context.Context) (Tx, error)
 }
 
-func NewPostgresImageRepo(dbconn *dbpg.DB) EBRepo {
-	return &ebpostgres.PostgresRepo{}
+// EBRepo - хранилище приложения. Данные организаций изолированы: методы, принимающие orgID, - точки входа по
+// идентификаторам от клиента и не находят чужие строки; остальные методы работают с ивентами и бронями,
+// уже найденными через них в той же транзакции, либо вызываются воркерами для всех организаций сразу.
+type EBRepo interface {
+	CreateEvent(ctx context.Context, exec Executor, newEvent *model.Event) error // владелец - из OwnerID, организация - из OrgID
+	CreateBook(ctx context.Context, exec Executor, newBook *model.Book) error    // организация - из OrgID
+	CreateUser(ctx context.Context, exec Executor, newUser *model.User) error
+
+	DeleteEvent(ctx context.Context, exec Executor, eventID int) error            // брони, очередь и соорганизаторы удаляются каскадно
+	MarkBookExpired(ctx context.Context, exec Executor, bookID int) error         // эксклюзивно для воркеров BookCleaner/BookScheduler
+	MarkBookCancelled(ctx context.Context, exec Executor, bookID int) error       // отмена с отметкой времени - от неё считается срок хранения
+	PurgeBooks(ctx context.Context, exec Executor, before time.Time) (int, error) // эксклюзивно для воркера BookPurger
+
+	UpdateBookStatus(ctx context.Context, exec Executor, bookID int, newStatus string) error
+	UpdateEvent(ctx context.Context, exec Executor, event *model.Event) error
+	CancelEvent(ctx context.Context, exec Executor, eventID int, reason string) error
+	CancelBooksByEvent(ctx context.Context, exec Executor, eventID int, statuses []string) ([]*model.BookWithUser, error)
+	UpdateEventStatus(ctx context.Context, exec Executor, eventID int, newStatus string) error // эксклюзивно для воркера EventSweeper
+
+	GetEventByID(ctx context.Context, exec Executor, orgID int, eventID int) (*model.Event, error)
+	GetEventByIDNoLock(ctx context.Context, exec Executor, orgID int, eventID int) (*model.Event, error)           // только чтение, без блокировки строки
+	GetEventsList(ctx context.Context, exec Executor, filter model.EventFilter) ([]*model.Event, error)            // страница по filter.After и filter.Limit
+	SearchEvents(ctx context.Context, exec Executor, search model.EventSearch) ([]*model.EventSearchResult, error) // по убыванию релевантности
+	LoadEventLabels(ctx context.Context, exec Executor, events []*model.Event) error                               // заполняет категории и теги ивентов
+	SetEventCategories(ctx context.Context, exec Executor, orgID int, eventID int, slugs []string) error           // заменяет набор; ErrCategoryNotFound
+	SetEventTags(ctx context.Context, exec Executor, eventID int, tags []string) error                             // заменяет набор
+	GetBookByID(ctx context.Context, exec Executor, orgID int, bookID int) (*model.Book, error)
+	GetBookByIDNoLock(ctx context.Context, exec Executor, orgID int, bookID int) (*model.Book, error) // без блокировки: ивент брони блокируется раньше неё
+	GetBooksListByUser(ctx context.Context, exec Executor, filter model.BookFilter) ([]*model.Book, error)
+	GetBooksListByEvent(ctx context.Context, exec Executor, eventID int) ([]*model.BookWithUser, error) // только для тех, кто управляет ивентом
+	GetBookStatsByEvent(ctx context.Context, exec Executor, eventID int) (*model.BookStats, error)
+	GetExpiredBooksList(ctx context.Context, exec Executor) ([]*model.Book, error)                        // эксклюзивно для воркера BookCleaner, без блокировки, по ивентам
+	GetPendingBooksList(ctx context.Context, exec Executor) ([]*model.Book, error)                        // неподтвержденные брони для планировщика истечения
+	GetPastEventsList(ctx context.Context, exec Executor) ([]*model.Event, error)                         // эксклюзивно для воркера EventSweeper, FOR UPDATE по порядку id
+	GetUserByID(ctx context.Context, exec Executor, userID int) (*model.User, error)                      // пользователь без роли - роль есть только в организации
+	GetUserByEmail(ctx context.Context, exec Executor, email string) (*model.User, error)                 // пользователь без роли
+	GetUsersList(ctx context.Context, exec Executor, filter model.UserFilter) ([]*model.User, int, error) // только для админа, возвращает и общее число найденных
+	SetUserDisabled(ctx context.Context, exec Executor, userID int, disabled bool) error                  // только для админа
+	DeleteUser(ctx context.Context, exec Executor, userID int) error                                      // только для админа; брони, очередь, участие в организациях и сессии удаляются каскадно
+	GetActiveBooksByUser(ctx context.Context, exec Executor, userID int) ([]*model.Book, error)           // created/confirmed во всех организациях, FOR UPDATE
+	SetEmailVerified(ctx context.Context, exec Executor, userID int) error
+	UpdateUserPassword(ctx context.Context, exec Executor, userID int, passHash string) error
+	// RegisterLoginFailure засчитывает неудачный вход (попытки старше since не учитываются) и при достижении maxFailures
+	// блокирует вход до lockUntil; возвращает время окончания блокировки, если она действует
+	RegisterLoginFailure(ctx context.Context, exec Executor, userID int, since time.Time, maxFailures int, lockUntil time.Time) (*time.Time, error)
+	ResetLoginFailures(ctx context.Context, exec Executor, userID int) error // после успешного входа или сброса пароля
+
+	SetTOTPSecret(ctx context.Context, exec Executor, userID int, secret string) error // новая настройка 2FA, до подтверждения кодом она не действует
+	EnableTOTP(ctx context.Context, exec Executor, userID int, step int64) error       // ErrMFANotSetUp без секрета
+	DisableTOTP(ctx context.Context, exec Executor, userID int) error                  // коды восстановления удаляются
+	UseTOTPStep(ctx context.Context, exec Executor, userID int, step int64) error      // ErrInvalidMFACode, если код этого шага уже принимался
+	ReplaceRecoveryCodes(ctx context.Context, exec Executor, userID int, hashes []string) error
+	UseRecoveryCode(ctx context.Context, exec Executor, userID int, hash string) error // ErrInvalidMFACode, если код не найден или уже использован
+
+	CreateWaitlistEntry(ctx context.Context, exec Executor, entry *model.WaitlistEntry) error
+	DeleteWaitlistEntry(ctx context.Context, exec Executor, eventID int, userID int) error
+	DeleteWaitlistByEvent(ctx context.Context, exec Executor, eventID int) error
+	DeleteWaitlistByUser(ctx context.Context, exec Executor, userID int) error                     // все очереди пользователя - при блокировке
+	GetWaitlistHead(ctx context.Context, exec Executor, eventID int) (*model.WaitlistEntry, error) // первый в очереди, FOR UPDATE
+	GetWaitlistEntry(ctx context.Context, exec Executor, eventID int, userID int) (*model.WaitlistEntry, error)
+
+	CreateSession(ctx context.Context, exec Executor, session *model.Session) error
+	GetSessionByID(ctx context.Context, exec Executor, sessionID int) (*model.Session, error)
+	GetSessionByTokenHash(ctx context.Context, exec Executor, hash string) (*model.Session, error) // FOR UPDATE - для ротации refresh-токена
+	RotateSessionToken(ctx context.Context, exec Executor, sessionID int, hash string, expiresAt time.Time) error
+	SetSessionOrg(ctx context.Context, exec Executor, sessionID int, orgID int) error
+	RevokeSession(ctx context.Context, exec Executor, sessionID int) error
+	RevokeSessionsByUser(ctx context.Context, exec Executor, userID int) error
+	RevokeOrgSessions(ctx context.Context, exec Executor, orgID int, userID int) error // только сессии пользователя в организации
+
+	CreateUserToken(ctx context.Context, exec Executor, token *model.UserToken) error
+	GetUserTokenByHash(ctx context.Context, exec Executor, hash string, purpose string) (*model.UserToken, error) // FOR UPDATE - токен одноразовый
+	MarkUserTokenUsed(ctx context.Context, exec Executor, tokenID int) error
+	InvalidateUserTokens(ctx context.Context, exec Executor, userID int, purpose string) error // гасит все неиспользованные токены пользователя
+
+	CreateAPIKey(ctx context.Context, exec Executor, key *model.APIKey) error
+	GetAPIKeysByUser(ctx context.Context, exec Executor, userID int) ([]*model.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, exec Executor, hash string) (*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, exec Executor, userID int, keyID int) error // ErrAPIKeyNotFound и для чужого ключа
+	TouchAPIKey(ctx context.Context, exec Executor, keyID int) error              // отметка last_used_at, не чаще раза в минуту
+
+	CreateUserIdentity(ctx context.Context, exec Executor, identity *model.UserIdentity) error
+	GetUserIdentity(ctx context.Context, exec Executor, issuer string, subject string) (*model.UserIdentity, error) // ErrIdentityNotFound
+
+	AddEventOrganizer(ctx context.Context, exec Executor, eventID int, userID int) error    // ErrAlreadyOrganizer при повторном назначении
+	RemoveEventOrganizer(ctx context.Context, exec Executor, eventID int, userID int) error // ErrOrganizerNotFound
+	GetEventOrganizers(ctx context.Context, exec Executor, eventID int) ([]*model.EventOrganizer, error)
+	IsEventOrganizer(ctx context.Context, exec Executor, eventID int, userID int) (bool, error)
+
+	CreateOrganization(ctx context.Context, exec Executor, org *model.Organization) error // ErrOrgSlugTaken
+	GetOrganizationBySlug(ctx context.Context, exec Executor, slug string) (*model.Organization, error)
+	GetUserOrganizations(ctx context.Context, exec Executor, userID int) ([]*model.Organization, error) // с ролью пользователя, по порядку создания
+	AddOrgMember(ctx context.Context, exec Executor, orgID int, userID int, role string) error          // ErrAlreadyOrgMember
+	GetOrgMemberRole(ctx context.Context, exec Executor, orgID int, userID int) (string, error)         // ErrNotOrgMember
+	UpdateOrgMemberRole(ctx context.Context, exec Executor, orgID int, userID int, role string) error   // ErrNotOrgMember
+	RemoveOrgMember(ctx context.Context, exec Executor, orgID int, userID int) error                    // ErrNotOrgMember; снимает с соорганизаторов и из очередей ивентов организации
+	HasOrgMemberWithRole(ctx context.Context, exec Executor, orgID int, role string) (bool, error)
+
+	CreateCategory(ctx context.Context, exec Executor, category *model.Category) error // ErrCategoryExists
+	GetCategoriesList(ctx context.Context, exec Executor, orgID int) ([]*model.Category, error)
+	UpdateCategory(ctx context.Context, exec Executor, category *model.Category) error  // ErrCategoryNotFound, ErrCategoryExists; slug меняется и в шаблонах серий
+	DeleteCategory(ctx context.Context, exec Executor, orgID int, categoryID int) error // ErrCategoryNotFound; ивенты остаются без неё
+
+	CreateSeries(ctx context.Context, exec Executor, series *model.EventSeries) error
+	GetSeriesByID(ctx context.Context, exec Executor, orgID int, seriesID int) (*model.EventSeries, error)       // select FOR UPDATE; ErrSeriesNotFound
+	GetSeriesByIDNoLock(ctx context.Context, exec Executor, orgID int, seriesID int) (*model.EventSeries, error) // ErrSeriesNotFound
+	GetPendingSeries(ctx context.Context, exec Executor, horizon time.Time) ([]*model.EventSeries, error)        // незавершенные серии всех организаций без вхождений до horizon - для планировщика серий
+	UpdateSeries(ctx context.Context, exec Executor, series *model.EventSeries) error                            // шаблон, generated_until и finished
+	GetSeriesEvents(ctx context.Context, exec Executor, seriesID int, from time.Time) ([]*model.Event, error)    // вхождения с началом не раньше from, по началу
+
+	CreateInvite(ctx context.Context, exec Executor, invite *model.Invite) error
+	GetInviteByTokenHash(ctx context.Context, exec Executor, hash string) (*model.Invite, error) // FOR UPDATE - приглашение одноразовое
+	MarkInviteUsed(ctx context.Context, exec Executor, inviteID int, userID int) error
+	ExtendUnusedInvite(ctx context.Context, exec Executor, hash string, expiresAt time.Time) error // только неиспользованное приглашение, иначе ErrInviteNotFound
+
+	IncreaseAvailSeatsByEventID(ctx context.Context, exec Executor, eventID int, n int) error
+	DecreaseAvailSeatsByEventID(ctx context.Context, exec Executor, eventID int, n int) error // не дает уйти в минус: ErrNoSeatsAvailable
 }
 
 func ConnectWithRetries(appConfig *config.Config, retryCount int, idleTime time.Duration) *dbpg.DB {
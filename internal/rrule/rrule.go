@@ -0,0 +1,247 @@
+// Package rrule implements the subset of iCalendar recurrence rules (RFC 5545 RRULE) used by event series:
+// WEEKLY with BYDAY, MONTHLY with BYMONTHDAY, INTERVAL and COUNT or UNTIL
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+
+	MaxCount    = 500 // вхождений в серии с COUNT
+	maxInterval = 99
+	maxIdle     = 100 // периодов подряд без вхождений, после которых правило считается исчерпанным (BYMONTHDAY=30 в феврале)
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Rule - разобранное правило повторения. Время вхождений - время начала серии в её часовом поясе
+// на каждую подходящую дату, поэтому переход на летнее время его не сдвигает
+type Rule struct {
+	Freq       string
+	Interval   int            // каждый N-й период
+	ByDay      []time.Weekday // для WEEKLY; пусто - день недели начала серии
+	ByMonthDay []int          // для MONTHLY, отрицательные - с конца месяца; пусто - число начала серии
+	Count      int            // 0 - без ограничения числа вхождений
+	until      string         // UNTIL как задан: дата, местное время серии или UTC
+}
+
+// Parse разбирает правило вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10", префикс "RRULE:" допускается
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return nil, ErrInvalidRule
+	}
+	r := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" || seen[key] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRule, part)
+		}
+		seen[key] = true
+		var err error
+		switch key {
+		case "FREQ":
+			if value != FreqWeekly && value != FreqMonthly {
+				err = errors.New("only WEEKLY and MONTHLY are supported")
+			}
+			r.Freq = value
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && (r.Interval < 1 || r.Interval > maxInterval) {
+				err = fmt.Errorf("must be from 1 to %d", maxInterval)
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					err = fmt.Errorf("unknown day %q", d)
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				md, convErr := strconv.Atoi(d)
+				if convErr != nil || md == 0 || md < -31 || md > 31 {
+					err = fmt.Errorf("day %q must be from 1 to 31 or from -31 to -1", d)
+					break
+				}
+				r.ByMonthDay = append(r.ByMonthDay, md)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && (r.Count < 1 || r.Count > MaxCount) {
+				err = fmt.Errorf("must be from 1 to %d", MaxCount)
+			}
+		case "UNTIL":
+			if _, _, err = parseUntil(value); err == nil {
+				r.until = value
+			}
+		default:
+			err = errors.New("unsupported part")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRule, key, err)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	case r.Count > 0 && r.until != "":
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	case r.Freq == FreqWeekly && len(r.ByMonthDay) > 0, r.Freq == FreqMonthly && len(r.ByDay) > 0:
+		return nil, fmt.Errorf("%w: BYDAY is for WEEKLY, BYMONTHDAY - for MONTHLY", ErrInvalidRule)
+	}
+	return r, nil
+}
+
+// String - правило в каноническом виде, в котором оно хранится
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			days = append(days, strings.ToUpper(wd.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, md := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(md))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.until != "" {
+		parts = append(parts, "UNTIL="+r.until)
+	}
+	return strings.Join(parts, ";")
+}
+
+// Bounded - ограничено ли число вхождений
+func (r *Rule) Bounded() bool {
+	return r.Count > 0 || r.until != ""
+}
+
+// Occurrences - начала вхождений по порядку: первое - первая подходящая под правило дата не раньше start,
+// время суток и часовой пояс берутся из start. COUNT отсчитывается от start, UNTIL включается
+func (r *Rule) Occurrences(start time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		until := r.untilIn(start.Location())
+		emitted, idle := 0, 0
+		for period := 0; idle < maxIdle; period += r.Interval {
+			found := false
+			for _, t := range r.candidates(start, period) {
+				if t.Before(start) {
+					continue
+				}
+				if !until.IsZero() && t.After(until) {
+					return
+				}
+				found = true
+				if !yield(t) {
+					return
+				}
+				emitted++
+				if r.Count > 0 && emitted == r.Count {
+					return
+				}
+			}
+			if found {
+				idle = 0
+			} else {
+				idle++
+			}
+		}
+	}
+}
+
+// candidates - подходящие под правило моменты в периоде номер period от начала серии, по возрастанию
+func (r *Rule) candidates(start time.Time, period int) []time.Time {
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, loc)
+	}
+	res := make([]time.Time, 0)
+
+	switch r.Freq {
+	case FreqWeekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		// неделя начинается с понедельника (WKST=MO)
+		monday := start.Day() - (int(start.Weekday())+6)%7 + 7*period
+		for _, wd := range days {
+			res = append(res, at(start.Year(), start.Month(), monday+(int(wd)+6)%7))
+		}
+	case FreqMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(period), 1, 0, 0, 0, 0, loc)
+		inMonth := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, loc).Day()
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		for _, md := range days {
+			if md < 0 {
+				md = inMonth + md + 1
+			}
+			if md >= 1 && md <= inMonth { // 31-го числа нет в коротких месяцах - они пропускаются
+				res = append(res, at(first.Year(), first.Month(), md))
+			}
+		}
+	}
+
+	slices.SortFunc(res, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(res, time.Time.Equal)
+}
+
+// untilIn - UNTIL как момент времени: дата включается целиком, время без Z - местное время серии
+func (r *Rule) untilIn(loc *time.Location) time.Time {
+	if r.until == "" {
+		return time.Time{}
+	}
+	t, wall, _ := parseUntil(r.until)
+	if !wall {
+		return t
+	}
+	if len(r.until) == len("20060102") {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// parseUntil - форматы UNTIL: 20060102, 20060102T150405 (местное время серии), 20060102T150405Z
+func parseUntil(s string) (t time.Time, wall bool, err error) {
+	if t, err = time.Parse("20060102T150405Z", s); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{"20060102T150405", "20060102"} {
+		if t, err = time.Parse(layout, s); err == nil {
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, errors.New("must be a date or date-time like 20300101T000000Z")
+}
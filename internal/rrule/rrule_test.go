@@ -0,0 +1,216 @@
+package rrule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %q: %v", name, err)
+	}
+	return loc
+}
+
+// take - первые n вхождений в формате "2006-01-02 15:04 -07"
+func take(r *Rule, start time.Time, n int) []string {
+	res := make([]string, 0, n)
+	for t := range r.Occurrences(start) {
+		res = append(res, t.Format("2006-01-02 15:04 -07"))
+		if len(res) == n {
+			break
+		}
+	}
+	return res
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string // канонический вид, пусто - ожидается ошибка
+		bounded bool
+	}{
+		{in: "FREQ=WEEKLY", want: "FREQ=WEEKLY"},
+		{in: "rrule:freq=weekly;byday=mo,we;count=10", want: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10", bounded: true},
+		{in: " FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=1,-1 ", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{in: "FREQ=MONTHLY;INTERVAL=3;UNTIL=20301231", want: "FREQ=MONTHLY;INTERVAL=3;UNTIL=20301231", bounded: true},
+		{in: "FREQ=WEEKLY;UNTIL=20301231T190000", want: "FREQ=WEEKLY;UNTIL=20301231T190000", bounded: true},
+		{in: "FREQ=WEEKLY;UNTIL=20301231T190000Z", want: "FREQ=WEEKLY;UNTIL=20301231T190000Z", bounded: true},
+		{in: ""},
+		{in: "BYDAY=MO"},
+		{in: "FREQ=DAILY"},
+		{in: "FREQ=WEEKLY;FREQ=WEEKLY"},
+		{in: "FREQ=WEEKLY;INTERVAL=0"},
+		{in: "FREQ=WEEKLY;INTERVAL=100"},
+		{in: "FREQ=WEEKLY;BYDAY=XX"},
+		{in: "FREQ=WEEKLY;BYMONTHDAY=1"},
+		{in: "FREQ=MONTHLY;BYDAY=MO"},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=0"},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=-32"},
+		{in: "FREQ=WEEKLY;COUNT=0"},
+		{in: "FREQ=WEEKLY;COUNT=501"},
+		{in: "FREQ=WEEKLY;UNTIL=2030-12-31"},
+		{in: "FREQ=WEEKLY;COUNT=5;UNTIL=20301231"},
+		{in: "FREQ=WEEKLY;WKST=SU"},
+		{in: "FREQ=WEEKLY;COUNT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			r, err := Parse(tt.in)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Fatalf("Parse() error = %v, want ErrInvalidRule", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := r.Bounded(); got != tt.bounded {
+				t.Errorf("Bounded() = %v, want %v", got, tt.bounded)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	utc := time.UTC
+	berlin := mustLoad(t, "Europe/Berlin")
+	ny := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		n     int // сколько вхождений взять, если правило не ограничено
+		want  []string
+	}{
+		{
+			name:  "weekly on start weekday",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: time.Date(2030, 1, 2, 19, 0, 0, 0, utc), // среда
+			want:  []string{"2030-01-02 19:00 +00", "2030-01-09 19:00 +00", "2030-01-16 19:00 +00"},
+		},
+		{
+			name:  "weekly by days skips days before start",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=4",
+			start: time.Date(2030, 1, 2, 19, 0, 0, 0, utc), // среда: понедельник этой недели уже прошел
+			want:  []string{"2030-01-02 19:00 +00", "2030-01-04 19:00 +00", "2030-01-07 19:00 +00", "2030-01-09 19:00 +00"},
+		},
+		{
+			name:  "every second week with sunday at the end of the week",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,MO;COUNT=4",
+			start: time.Date(2030, 1, 7, 10, 0, 0, 0, utc), // понедельник
+			want:  []string{"2030-01-07 10:00 +00", "2030-01-13 10:00 +00", "2030-01-21 10:00 +00", "2030-01-27 10:00 +00"},
+		},
+		{
+			name:  "last day of month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4",
+			start: time.Date(2030, 1, 15, 18, 0, 0, 0, utc),
+			want:  []string{"2030-01-31 18:00 +00", "2030-02-28 18:00 +00", "2030-03-31 18:00 +00", "2030-04-30 18:00 +00"},
+		},
+		{
+			name:  "last day of month in leap year",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=2",
+			start: time.Date(2032, 1, 31, 18, 0, 0, 0, utc),
+			want:  []string{"2032-01-31 18:00 +00", "2032-02-29 18:00 +00"},
+		},
+		{
+			name:  "31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=4",
+			start: time.Date(2030, 1, 31, 12, 0, 0, 0, utc),
+			want:  []string{"2030-01-31 12:00 +00", "2030-03-31 12:00 +00", "2030-05-31 12:00 +00", "2030-07-31 12:00 +00"},
+		},
+		{
+			name:  "first and 15th, every other month",
+			rule:  "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=15,1;COUNT=4",
+			start: time.Date(2030, 1, 1, 9, 30, 0, 0, utc),
+			want:  []string{"2030-01-01 09:30 +00", "2030-01-15 09:30 +00", "2030-03-01 09:30 +00", "2030-03-15 09:30 +00"},
+		},
+		{
+			name:  "until date is inclusive",
+			rule:  "FREQ=WEEKLY;UNTIL=20300116",
+			start: time.Date(2030, 1, 2, 23, 0, 0, 0, berlin),
+			n:     10,
+			want:  []string{"2030-01-02 23:00 +01", "2030-01-09 23:00 +01", "2030-01-16 23:00 +01"},
+		},
+		{
+			name:  "until local time is in series zone",
+			rule:  "FREQ=WEEKLY;UNTIL=20300116T190000",
+			start: time.Date(2030, 1, 2, 19, 0, 0, 0, berlin),
+			n:     10,
+			want:  []string{"2030-01-02 19:00 +01", "2030-01-09 19:00 +01", "2030-01-16 19:00 +01"},
+		},
+		{
+			name:  "until utc excludes later occurrence the same day",
+			rule:  "FREQ=WEEKLY;UNTIL=20300116T170000Z",
+			start: time.Date(2030, 1, 2, 19, 0, 0, 0, berlin), // 18:00 UTC
+			n:     10,
+			want:  []string{"2030-01-02 19:00 +01", "2030-01-09 19:00 +01"},
+		},
+		{
+			name:  "wall clock kept across DST start",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: time.Date(2030, 3, 24, 10, 0, 0, 0, berlin), // переход на летнее время - 31 марта
+			want:  []string{"2030-03-24 10:00 +01", "2030-03-31 10:00 +02", "2030-04-07 10:00 +02"},
+		},
+		{
+			name:  "wall clock kept across DST end",
+			rule:  "FREQ=WEEKLY;COUNT=2",
+			start: time.Date(2030, 10, 28, 19, 0, 0, 0, ny), // переход на зимнее время - 3 ноября
+			want:  []string{"2030-10-28 19:00 -04", "2030-11-04 19:00 -05"},
+		},
+		{
+			name:  "unbounded rule continues",
+			rule:  "FREQ=MONTHLY",
+			start: time.Date(2030, 11, 10, 8, 0, 0, 0, utc),
+			n:     3,
+			want:  []string{"2030-11-10 08:00 +00", "2030-12-10 08:00 +00", "2031-01-10 08:00 +00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			n := tt.n
+			if n == 0 {
+				n = MaxCount + 1 // ограниченное правило должно закончиться само
+			}
+			if got := take(r, tt.start, n); !slices.Equal(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesEndWhenNothingMatches(t *testing.T) {
+	// 31-е число и UNTIL до ближайшего такого месяца: вхождений нет, перебор должен закончиться
+	r, err := Parse("FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20300330")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := take(r, time.Date(2030, 2, 1, 10, 0, 0, 0, time.UTC), MaxCount); len(got) != 0 {
+		t.Errorf("Occurrences() = %v, want none", got)
+	}
+
+	// неограниченное правило, все месяцы которого - февраль без 30-го числа, заканчивается после maxIdle пустых периодов
+	r, err = Parse("FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=30")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := take(r, time.Date(2030, 2, 1, 10, 0, 0, 0, time.UTC), MaxCount); len(got) != 0 {
+		t.Errorf("Occurrences() = %v, want none", got)
+	}
+}
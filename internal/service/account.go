@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+const (
+	verifyEmailTTL   = 24 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+// VerifyEmail подтверждает имейл по одноразовому токену из письма
+func (eb EBService) VerifyEmail(ctx context.Context, token string) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if token == "" {
+		return model.ErrInvalidUserToken
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'VerifyEmail': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'VerifyEmail': %v", rid, err)
+			}
+		}
+	}()
+
+	ut, err := eb.useUserToken(ctx, tx, token, model.TokenPurposeVerifyEmail)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidUserToken):
+			return err
+		default:
+			log.Printf("RID %q Failed to use verification token in 'VerifyEmail': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.SetEmailVerified(ctx, tx, ut.UserID); err != nil {
+		log.Printf("RID %q Failed to mark email as verified in DB in 'VerifyEmail': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'VerifyEmail': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	return nil
+}
+
+// ResendVerification повторно отправляет письмо подтверждения; предыдущие ссылки перестают действовать
+func (eb EBService) ResendVerification(ctx context.Context, uid int) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	user, err := eb.repo.GetUserByID(ctx, eb.txm.Executor(), uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'ResendVerification': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := eb.sendVerificationMail(ctx, user); err != nil {
+		log.Printf("RID %q Failed to send verification mail in 'ResendVerification': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	return nil
+}
+
+// ForgotPassword отправляет ссылку сброса пароля. Ответ не зависит от того, есть ли такой пользователь,
+// чтобы эндпоинт нельзя было использовать для перебора зарегистрированных имейлов.
+func (eb EBService) ForgotPassword(ctx context.Context, email string) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return model.ErrEmptyEmail
+	}
+
+	user, err := eb.repo.GetUserByEmail(ctx, eb.txm.Executor(), email)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'ForgotPassword': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+
+	token, err := eb.issueUserToken(ctx, user.ID, model.TokenPurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		log.Printf("RID %q Failed to issue reset token in 'ForgotPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	eb.sendMail(ctx, &model.Mail{
+		To:      user.Email,
+		Subject: "Password reset",
+		Text: fmt.Sprintf("To set a new password open %s/ui/#reset=%s\nThe link is valid for %s. If you did not request a reset, ignore this mail.",
+			eb.opts.AppURL, token, resetPasswordTTL),
+	})
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма и отзывает все сессии пользователя
+func (eb EBService) ResetPassword(ctx context.Context, token string, password string) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if token == "" {
+		return model.ErrInvalidUserToken
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	passHash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("RID %q Failed to hash password in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'ResetPassword': %v", rid, err)
+			}
+		}
+	}()
+
+	ut, err := eb.useUserToken(ctx, tx, token, model.TokenPurposeResetPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidUserToken):
+			return err
+		default:
+			log.Printf("RID %q Failed to use reset token in 'ResetPassword': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.UpdateUserPassword(ctx, tx, ut.UserID, passHash); err != nil {
+		log.Printf("RID %q Failed to update password in DB in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// старый пароль мог быть скомпрометирован - выходим на всех устройствах
+	if err := eb.repo.RevokeSessionsByUser(ctx, tx, ut.UserID); err != nil {
+		log.Printf("RID %q Failed to revoke user sessions in DB in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// письмо со ссылкой пришло на этот имейл - значит, он принадлежит пользователю
+	if err := eb.repo.SetEmailVerified(ctx, tx, ut.UserID); err != nil {
+		log.Printf("RID %q Failed to mark email as verified in DB in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// владелец сменил пароль - временная блокировка входа больше не нужна
+	if err := eb.repo.ResetLoginFailures(ctx, tx, ut.UserID); err != nil {
+		log.Printf("RID %q Failed to reset login failures in DB in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'ResetPassword': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	return nil
+}
+
+// sendVerificationMail выпускает токен подтверждения и отправляет письмо со ссылкой
+func (eb EBService) sendVerificationMail(ctx context.Context, user *model.User) error {
+	token, err := eb.issueUserToken(ctx, user.ID, model.TokenPurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	eb.sendMail(ctx, &model.Mail{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Text: fmt.Sprintf("To confirm your email open %s/ui/#verify=%s\nThe link is valid for %s.",
+			eb.opts.AppURL, token, verifyEmailTTL),
+	})
+	return nil
+}
+
+// checkEmailVerified - при включенной опции RequireVerifiedEmail бронировать могут только пользователи с подтвержденным имейлом
+func (eb EBService) checkEmailVerified(ctx context.Context, uid int) error {
+	if !eb.opts.RequireVerifiedEmail {
+		return nil
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, eb.txm.Executor(), uid)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		return model.ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+// issueUserToken гасит прежние токены пользователя с тем же назначением и выпускает новый
+func (eb EBService) issueUserToken(ctx context.Context, uid int, purpose string, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		return "", err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'issueUserToken': %v", model.RequestIDFromCtx(ctx), err)
+			}
+		}
+	}()
+
+	if err := eb.repo.InvalidateUserTokens(ctx, tx, uid, purpose); err != nil {
+		return "", err
+	}
+	ut := &model.UserToken{
+		UserID:    uid,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+	if err := eb.repo.CreateUserToken(ctx, tx, ut); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	committed = true
+
+	return token, nil
+}
+
+// useUserToken достает токен с блокировкой, проверяет срок и помечает использованным
+func (eb EBService) useUserToken(ctx context.Context, tx repository.Tx, token string, purpose string) (*model.UserToken, error) {
+	ut, err := eb.repo.GetUserTokenByHash(ctx, tx, hashToken(token), purpose)
+	if err != nil {
+		if errors.Is(err, model.ErrUserTokenNotFound) {
+			return nil, model.ErrInvalidUserToken
+		}
+		return nil, err
+	}
+	if ut.UsedAt != nil || !ut.ExpiresAt.After(time.Now().UTC()) {
+		return nil, model.ErrInvalidUserToken
+	}
+
+	if err := eb.repo.MarkUserTokenUsed(ctx, tx, ut.ID); err != nil {
+		return nil, err
+	}
+
+	return ut, nil
+}
+
+func (eb EBService) sendMail(ctx context.Context, m *model.Mail) {
+	if err := eb.mailer.Send(ctx, m); err != nil {
+		log.Printf("RID %q Failed to send mail to %q: %v", model.RequestIDFromCtx(ctx), m.To, err)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+const (
+	apiKeyPrefix      = "ebk_" // по префиксу RequireAuth отличает API-ключ, а пользователь - ключ от других секретов
+	apiKeyShownPrefix = 12     // столько символов ключа хранится открыто, чтобы ключ можно было узнать в списке
+	defaultAPIKeyTTL  = 90 * 24 * time.Hour
+	maxAPIKeyTTL      = 365 * 24 * time.Hour
+	maxAPIKeyName     = 100
+)
+
+// CreateAPIKey выпускает API-ключ пользователя в организации orgID текущей сессии; сам ключ возвращается в key.Key только здесь.
+// mfa - ключ выпускается из сессии, прошедшей 2FA: такой ключ проходит RequireMFA.
+func (eb EBService) CreateAPIKey(ctx context.Context, uid int, orgID int, mfa bool, key *model.APIKey, ttl time.Duration) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+	if err := validateNormalizeAPIKey(key); err != nil {
+		return err
+	}
+	if ttl == 0 {
+		ttl = defaultAPIKeyTTL
+	}
+	if ttl < 24*time.Hour || ttl > maxAPIKeyTTL {
+		return model.ErrIncorrectAPIKeyTTL
+	}
+
+	token, err := newToken()
+	if err != nil {
+		log.Printf("RID %q Failed to generate API key in 'CreateAPIKey': %v", rid, err)
+		return model.ErrCommon500
+	}
+	key.Key = apiKeyPrefix + token
+	key.Prefix = key.Key[:apiKeyShownPrefix]
+	key.KeyHash = hashToken(key.Key)
+	key.UserID = uid
+	key.OrgID = orgID
+	key.MFA = mfa
+	key.ExpiresAt = time.Now().UTC().Add(ttl)
+
+	if err := eb.repo.CreateAPIKey(ctx, eb.txm.Executor(), key); err != nil {
+		log.Printf("RID %q Failed to create API key in DB in 'CreateAPIKey': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	return nil
+}
+
+// GetAPIKeys - ключи пользователя, включая отозванные и истекшие, без самих ключей
+func (eb EBService) GetAPIKeys(ctx context.Context, uid int) ([]*model.APIKey, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+
+	keys, err := eb.repo.GetAPIKeysByUser(ctx, eb.txm.Executor(), uid)
+	if err != nil {
+		log.Printf("RID %q Failed to get API keys from DB in 'GetAPIKeys': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ пользователя; чужой ключ выглядит как несуществующий
+func (eb EBService) RevokeAPIKey(ctx context.Context, uid int, keyID int) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+	if keyID < 1 {
+		return model.ErrAPIKeyNotFound
+	}
+
+	if err := eb.repo.RevokeAPIKey(ctx, eb.txm.Executor(), uid, keyID); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAPIKeyNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to revoke API key in DB in 'RevokeAPIKey': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey используется в RequireAuth: ключ действует, пока не отозван, не истек и владелец не заблокирован.
+// Роль (в организации ключа) и имейл берутся у владельца на момент запроса; исключенному из организации ключ больше не служит.
+func (eb EBService) AuthenticateAPIKey(ctx context.Context, rawKey string) (*model.APIKey, *model.User, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, nil, model.ErrInvalidAPIKey
+	}
+
+	key, err := eb.repo.GetAPIKeyByHash(ctx, eb.txm.Executor(), hashToken(rawKey))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrAPIKeyNotFound):
+			return nil, nil, model.ErrInvalidAPIKey
+		default:
+			log.Printf("RID %q Failed to get API key from DB in 'AuthenticateAPIKey': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	if key.RevokedAt != nil || !key.ExpiresAt.After(time.Now().UTC()) {
+		return nil, nil, model.ErrInvalidAPIKey
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, eb.txm.Executor(), key.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, nil, model.ErrInvalidAPIKey
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'AuthenticateAPIKey': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, model.ErrInvalidAPIKey
+	}
+	user.Role, err = eb.repo.GetOrgMemberRole(ctx, eb.txm.Executor(), key.OrgID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotOrgMember):
+			return nil, nil, model.ErrInvalidAPIKey
+		default:
+			log.Printf("RID %q Failed to get user role in organization from DB in 'AuthenticateAPIKey': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+
+	// отметка использования не критична для самого запроса
+	if err := eb.repo.TouchAPIKey(ctx, eb.txm.Executor(), key.ID); err != nil {
+		log.Printf("RID %q Failed to update API key last use in DB in 'AuthenticateAPIKey': %v", rid, err)
+	}
+
+	return key, user, nil
+}
+
+func validateNormalizeAPIKey(key *model.APIKey) error {
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" || utf8.RuneCountInString(key.Name) > maxAPIKeyName || len(key.Scopes) == 0 {
+		return model.ErrIncorrectAPIKey
+	}
+
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, s := range key.Scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !slices.Contains(model.APIKeyScopes, s) {
+			return model.ErrIncorrectAPIKey
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	key.Scopes = scopes
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+const maxCategoryName = 100
+
+// GetCategories - категории текущей организации для навигации по ивентам, доступны всем её участникам
+func (eb EBService) GetCategories(ctx context.Context, actor model.Actor) ([]*model.Category, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	categories, err := eb.repo.GetCategoriesList(ctx, eb.txm.Executor(), actor.OrgID)
+	if err != nil {
+		log.Printf("RID %q Failed to get categories from DB in 'GetCategories': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	return categories, nil
+}
+
+// CreateCategory - новая категория в организации админа
+func (eb EBService) CreateCategory(ctx context.Context, category *model.Category, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermCategoriesEdit) {
+		return model.ErrAccessDenied
+	}
+	if err := validateNormalizeCategory(category); err != nil {
+		return err
+	}
+	category.OrgID = actor.OrgID
+
+	if err := eb.repo.CreateCategory(ctx, eb.txm.Executor(), category); err != nil {
+		switch {
+		case errors.Is(err, model.ErrCategoryExists):
+			return err
+		default:
+			log.Printf("RID %q Failed to create category in DB in 'CreateCategory': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	return nil
+}
+
+// UpdateCategory меняет имя и slug категории; ивенты остаются в ней, так как связаны по id, а шаблоны серий получают новый slug
+func (eb EBService) UpdateCategory(ctx context.Context, category *model.Category, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermCategoriesEdit) {
+		return model.ErrAccessDenied
+	}
+	if category.ID < 1 {
+		return model.ErrCategoryNotFound
+	}
+	if err := validateNormalizeCategory(category); err != nil {
+		return err
+	}
+	category.OrgID = actor.OrgID
+
+	if err := eb.repo.UpdateCategory(ctx, eb.txm.Executor(), category); err != nil {
+		switch {
+		case errors.Is(err, model.ErrCategoryNotFound), errors.Is(err, model.ErrCategoryExists):
+			return err
+		default:
+			log.Printf("RID %q Failed to update category in DB in 'UpdateCategory': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	return nil
+}
+
+// DeleteCategory удаляет категорию; её ивенты остаются в остальных своих категориях
+func (eb EBService) DeleteCategory(ctx context.Context, id int, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermCategoriesEdit) {
+		return model.ErrAccessDenied
+	}
+	if id < 1 {
+		return model.ErrCategoryNotFound
+	}
+
+	if err := eb.repo.DeleteCategory(ctx, eb.txm.Executor(), actor.OrgID, id); err != nil {
+		switch {
+		case errors.Is(err, model.ErrCategoryNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to delete category in DB in 'DeleteCategory': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	return nil
+}
+
+func validateNormalizeCategory(c *model.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Slug = strings.ToLower(strings.TrimSpace(c.Slug))
+	if c.Name == "" || utf8.RuneCountInString(c.Name) > maxCategoryName || !slugPattern.MatchString(c.Slug) {
+		return model.ErrIncorrectCategory
+	}
+	return nil
+}
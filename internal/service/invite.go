@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+const (
+	defaultInviteTTL   = 72 * time.Hour
+	maxInviteTTL       = 30 * 24 * time.Hour
+	bootstrapInviteTTL = 7 * 24 * time.Hour
+)
+
+// CreateInvite выпускает одноразовое приглашение с ролью в организацию invite.OrgID; сам токен возвращается в invite.Token только здесь
+func (eb EBService) CreateInvite(ctx context.Context, invite *model.Invite, ttl time.Duration, role string) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !model.RoleHasPermission(role, model.PermInvitesCreate) {
+		return model.ErrAccessDenied
+	}
+	if !model.ValidRole(invite.Role) {
+		return model.ErrIncorrectUserRole
+	}
+	if ttl == 0 {
+		ttl = defaultInviteTTL
+	}
+	if ttl < time.Hour || ttl > maxInviteTTL {
+		return model.ErrIncorrectInviteTTL
+	}
+	invite.Email = strings.ToLower(strings.TrimSpace(invite.Email))
+
+	token, err := newToken()
+	if err != nil {
+		log.Printf("RID %q Failed to generate invite token in 'CreateInvite': %v", rid, err)
+		return model.ErrCommon500
+	}
+	invite.Token = token
+	invite.TokenHash = hashToken(token)
+	invite.ExpiresAt = time.Now().UTC().Add(ttl)
+
+	if err := eb.repo.CreateInvite(ctx, eb.txm.Executor(), invite); err != nil {
+		log.Printf("RID %q Failed to create invite in DB in 'CreateInvite': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	return nil
+}
+
+// BootstrapAdmin превращает одноразовый токен из окружения в приглашение админа организации по умолчанию, пока в ней нет ни одного админа.
+// Вызывается при старте приложения; после регистрации первого админа токен больше не действует.
+func (eb EBService) BootstrapAdmin(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+
+	org, err := eb.repo.GetOrganizationBySlug(ctx, eb.txm.Executor(), model.DefaultOrgSlug)
+	if err != nil {
+		return err
+	}
+
+	hasAdmin, err := eb.repo.HasOrgMemberWithRole(ctx, eb.txm.Executor(), org.ID, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if hasAdmin {
+		log.Println("Admin already exists, bootstrap token is ignored")
+		return nil
+	}
+
+	invite := &model.Invite{
+		OrgID:     org.ID,
+		TokenHash: hashToken(token),
+		Role:      model.RoleAdmin,
+		ExpiresAt: time.Now().UTC().Add(bootstrapInviteTTL),
+	}
+	if err := eb.repo.CreateInvite(ctx, eb.txm.Executor(), invite); err != nil {
+		if !errors.Is(err, model.ErrInviteExists) {
+			return err
+		}
+		// токен уже заведен при прошлом запуске: пока им не воспользовались, срок отсчитывается заново от этого старта
+		err = eb.repo.ExtendUnusedInvite(ctx, eb.txm.Executor(), invite.TokenHash, invite.ExpiresAt)
+		switch {
+		case err == nil:
+			log.Println("Bootstrap admin invite already exists, its expiration is extended")
+		case errors.Is(err, model.ErrInviteNotFound):
+			log.Println("Bootstrap admin token has expired: it is already used, set a new ADMIN_BOOTSTRAP_TOKEN to invite the first admin")
+		default:
+			return err
+		}
+		return nil
+	}
+
+	log.Println("Bootstrap admin invite is created: sign up with it to get the first admin account")
+	return nil
+}
+
+// lockInvite достает приглашение по токену с блокировкой и проверяет, что им можно воспользоваться с этим имейлом
+func (eb EBService) lockInvite(ctx context.Context, tx repository.Tx, token string, email string) (*model.Invite, error) {
+	invite, err := eb.repo.GetInviteByTokenHash(ctx, tx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, model.ErrInviteNotFound) {
+			return nil, model.ErrInvalidInvite
+		}
+		return nil, err
+	}
+	if invite.UsedAt != nil || !invite.ExpiresAt.After(time.Now().UTC()) {
+		return nil, model.ErrInvalidInvite
+	}
+	if invite.Email != "" && invite.Email != email {
+		return nil, model.ErrInvalidInvite
+	}
+
+	return invite, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+func TestBootstrapAdminExistingInvite(t *testing.T) {
+	e := newTestEnv(t)
+
+	// приглашение с прошлого запуска успело истечь
+	stale := &model.Invite{
+		OrgID:     testOrg,
+		TokenHash: hashToken("boot"),
+		Role:      model.RoleAdmin,
+		ExpiresAt: time.Now().UTC().Add(-time.Hour),
+	}
+	if err := e.repo.CreateInvite(e.ctx, e.store, stale); err != nil {
+		t.Fatalf("create invite: %v", err)
+	}
+
+	if err := e.svc.BootstrapAdmin(e.ctx, "boot"); err != nil {
+		t.Fatalf("BootstrapAdmin() error = %v", err)
+	}
+	invite, err := e.repo.GetInviteByTokenHash(e.ctx, e.store, hashToken("boot"))
+	if err != nil {
+		t.Fatalf("get invite: %v", err)
+	}
+	if !invite.ExpiresAt.After(time.Now().UTC().Add(bootstrapInviteTTL - time.Minute)) {
+		t.Fatalf("unused invite expires at %v, want extended by %v", invite.ExpiresAt, bootstrapInviteTTL)
+	}
+
+	// использованное приглашение не оживает, даже если админов в организации не осталось
+	u := e.user("first@test.io", model.RoleUser)
+	if err := e.repo.MarkInviteUsed(e.ctx, e.store, invite.ID, u.UserID); err != nil {
+		t.Fatalf("mark invite used: %v", err)
+	}
+	if err := e.svc.BootstrapAdmin(e.ctx, "boot"); err != nil {
+		t.Fatalf("BootstrapAdmin() with used invite error = %v", err)
+	}
+	used, err := e.repo.GetInviteByTokenHash(e.ctx, e.store, hashToken("boot"))
+	if err != nil {
+		t.Fatalf("get invite: %v", err)
+	}
+	if used.UsedAt == nil || !used.ExpiresAt.Equal(invite.ExpiresAt) {
+		t.Errorf("used invite = %+v, want it unchanged", used)
+	}
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/UnendingLoop/EventBooker/internal/totp"
+)
+
+const (
+	mfaIssuer          = "EventBooker" // имя сервиса в приложении-аутентификаторе
+	recoveryCodesCount = 10
+)
+
+// SetupTOTP выпускает новый секрет 2FA; включится она только после подтверждения кодом в EnableTOTP
+func (eb EBService) SetupTOTP(ctx context.Context, uid int) (*model.TOTPSetup, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, eb.txm.Executor(), uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'SetupTOTP': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, model.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		log.Printf("RID %q Failed to generate TOTP secret in 'SetupTOTP': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	if err := eb.repo.SetTOTPSecret(ctx, eb.txm.Executor(), uid, secret); err != nil {
+		log.Printf("RID %q Failed to save TOTP secret to DB in 'SetupTOTP': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	return &model.TOTPSetup{Secret: secret, URI: totp.URI(mfaIssuer, user.Email, secret)}, nil
+}
+
+// EnableTOTP включает 2FA после проверки первого кода: выдает коды восстановления (показываются один раз),
+// отзывает все сессии пользователя и открывает новую в организации orgID - уже с пройденным вторым фактором
+func (eb EBService) EnableTOTP(ctx context.Context, uid int, orgID int, code string) (*model.AuthTokens, []string, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return nil, nil, model.ErrIncorrectUserID
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'EnableTOTP': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'EnableTOTP': %v", rid, err)
+			}
+		}
+	}()
+
+	user, err := eb.repo.GetUserByID(ctx, tx, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, nil, err
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'EnableTOTP': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	switch {
+	case user.TOTPEnabledAt != nil:
+		return nil, nil, model.ErrMFAAlreadyEnabled
+	case user.TOTPSecret == "":
+		return nil, nil, model.ErrMFANotSetUp
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, nil, model.ErrInvalidMFACode
+	}
+	if err := eb.repo.EnableTOTP(ctx, tx, uid, step); err != nil {
+		log.Printf("RID %q Failed to enable TOTP in DB in 'EnableTOTP': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Printf("RID %q Failed to generate recovery codes in 'EnableTOTP': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	if err := eb.repo.ReplaceRecoveryCodes(ctx, tx, uid, hashes); err != nil {
+		log.Printf("RID %q Failed to save recovery codes to DB in 'EnableTOTP': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+
+	// сессии, открытые только по паролю, могли принадлежать тому, от кого защищает 2FA
+	if err := eb.repo.RevokeSessionsByUser(ctx, tx, uid); err != nil {
+		log.Printf("RID %q Failed to revoke user sessions in DB in 'EnableTOTP': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	tokens, err := eb.startSession(ctx, tx, user, orgID, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotOrgMember): // исключен из организации после входа
+			return nil, nil, err
+		default:
+			log.Printf("RID %q Failed to start session in 'EnableTOTP': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'EnableTOTP': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed = true
+
+	return tokens, codes, nil
+}
+
+// DisableTOTP выключает 2FA по действующему коду или коду восстановления; при RequireAdminMFA админам недоступно
+func (eb EBService) DisableTOTP(ctx context.Context, uid int, code string, recoveryCode string) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'DisableTOTP': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'DisableTOTP': %v", rid, err)
+			}
+		}
+	}()
+
+	user, err := eb.repo.GetUserByID(ctx, tx, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'DisableTOTP': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+	if user.TOTPEnabledAt == nil {
+		return model.ErrMFANotSetUp
+	}
+	if eb.opts.RequireAdminMFA {
+		isAdmin, err := eb.isAdminInAnyOrg(ctx, tx, uid)
+		if err != nil {
+			log.Printf("RID %q Failed to get user organizations from DB in 'DisableTOTP': %v", rid, err)
+			return model.ErrCommon500
+		}
+		if isAdmin {
+			return model.ErrMFARequired
+		}
+	}
+
+	if err := eb.checkSecondFactor(ctx, tx, user, code, recoveryCode); err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidMFACode):
+			return err
+		default:
+			log.Printf("RID %q Failed to check second factor in 'DisableTOTP': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.DisableTOTP(ctx, tx, uid); err != nil {
+		log.Printf("RID %q Failed to disable TOTP in DB in 'DisableTOTP': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'DisableTOTP': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	return nil
+}
+
+// VerifyMFA - второй шаг входа: по промежуточному токену из LoginUser и коду 2FA (или коду восстановления)
+// открывает сессию. Неверные коды засчитываются в те же неудачные входы, что и неверные пароли.
+func (eb EBService) VerifyMFA(ctx context.Context, mfaToken string, code string, recoveryCode string) (*model.AuthTokens, *model.User, error) {
+	uid, err := eb.jwtManager.ParseMFAPending(mfaToken)
+	if err != nil {
+		return nil, nil, model.ErrInvalidMFAToken
+	}
+
+	tokens, user, err := eb.openMFASession(ctx, uid, code, recoveryCode)
+	if errors.Is(err, model.ErrInvalidMFACode) {
+		// неудача пишется уже после отката транзакции проверки
+		eb.registerLoginFailure(ctx, uid)
+	}
+	return tokens, user, err
+}
+
+// openMFASession проверяет второй фактор и открывает сессию в одной транзакции
+func (eb EBService) openMFASession(ctx context.Context, uid int, code string, recoveryCode string) (*model.AuthTokens, *model.User, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'openMFASession': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'openMFASession': %v", rid, err)
+			}
+		}
+	}()
+
+	user, err := eb.repo.GetUserByID(ctx, tx, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, nil, model.ErrInvalidMFAToken
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'openMFASession': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	switch {
+	case user.DisabledAt != nil:
+		return nil, nil, model.ErrUserDisabled
+	case user.TOTPEnabledAt == nil: // 2FA выключили после ввода пароля
+		return nil, nil, model.ErrInvalidMFAToken
+	case user.LockedUntil != nil && user.LockedUntil.After(time.Now()):
+		return nil, nil, model.ErrTooManyAttempts
+	}
+
+	if err := eb.checkSecondFactor(ctx, tx, user, code, recoveryCode); err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidMFACode):
+			return nil, nil, err
+		default:
+			log.Printf("RID %q Failed to check second factor in 'openMFASession': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.ResetLoginFailures(ctx, tx, uid); err != nil {
+		log.Printf("RID %q Failed to reset login failures in DB in 'openMFASession': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	tokens, err := eb.startSession(ctx, tx, user, 0, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoOrgMembership):
+			return nil, nil, err // 403
+		default:
+			log.Printf("RID %q Failed to start session in 'openMFASession': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'openMFASession': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed = true
+
+	return tokens, user, nil
+}
+
+// isAdminInAnyOrg - админу хотя бы одной организации нельзя выключить 2FA при RequireAdminMFA
+func (eb EBService) isAdminInAnyOrg(ctx context.Context, exec repository.Executor, uid int) (bool, error) {
+	orgs, err := eb.repo.GetUserOrganizations(ctx, exec, uid)
+	if err != nil {
+		return false, err
+	}
+	for _, o := range orgs {
+		if o.Role == model.RoleAdmin {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkSecondFactor принимает код из аутентификатора или, если его нет, код восстановления; оба одноразовые
+func (eb EBService) checkSecondFactor(ctx context.Context, exec repository.Executor, user *model.User, code string, recoveryCode string) error {
+	switch {
+	case code != "":
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return model.ErrInvalidMFACode
+		}
+		return eb.repo.UseTOTPStep(ctx, exec, user.ID, step)
+	case recoveryCode != "":
+		return eb.repo.UseRecoveryCode(ctx, exec, user.ID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	default:
+		return model.ErrInvalidMFACode
+	}
+}
+
+// newRecoveryCodes - коды восстановления вида xxxxx-xxxxx и их хэши для БД
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode - код можно ввести с дефисом или без, в любом регистре
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/totp"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// totpCode - код аутентификатора для шага step по RFC 6238
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestCheckSecondFactorRejectsReplay(t *testing.T) {
+	e := newTestEnv(t)
+	actor := e.user("mfa@test.io", model.RoleUser)
+	// у границы шага ждем следующего, чтобы окно проверки не сдвинулось посреди теста
+	if totp.Period-time.Now().Unix()%totp.Period < 3 {
+		time.Sleep(3 * time.Second)
+	}
+	current := totp.Step(time.Now())
+	if err := e.repo.SetTOTPSecret(e.ctx, e.store, actor.UserID, testTOTPSecret); err != nil {
+		t.Fatalf("set secret: %v", err)
+	}
+	// код включения 2FA - на два шага раньше, чтобы окно проверки было свободно
+	if err := e.repo.EnableTOTP(e.ctx, e.store, actor.UserID, current-2); err != nil {
+		t.Fatalf("enable totp: %v", err)
+	}
+
+	steps := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "previous step", code: totpCode(t, testTOTPSecret, current-1)},
+		{name: "same code again", code: totpCode(t, testTOTPSecret, current-1), wantErr: model.ErrInvalidMFACode},
+		{name: "current step", code: totpCode(t, testTOTPSecret, current)},
+		{name: "older step after newer", code: totpCode(t, testTOTPSecret, current-1), wantErr: model.ErrInvalidMFACode},
+		{name: "current step again", code: totpCode(t, testTOTPSecret, current), wantErr: model.ErrInvalidMFACode},
+		{name: "wrong code", code: "000000x", wantErr: model.ErrInvalidMFACode},
+	}
+
+	// шаги зависят друг от друга, поэтому идут по порядку на одном пользователе
+	for _, st := range steps {
+		user, err := e.repo.GetUserByID(e.ctx, e.store, actor.UserID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		if err := e.svc.checkSecondFactor(e.ctx, e.store, user, st.code, ""); !errors.Is(err, st.wantErr) {
+			t.Errorf("%s: checkSecondFactor() error = %v, want %v", st.name, err, st.wantErr)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/oidc"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// OIDCProvider - внешний провайдер входа (SSO) с потоком authorization code + PKCE
+type OIDCProvider interface {
+	AuthURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Identity, error)
+}
+
+// SetOIDCProvider включает вход через OIDC; вызывать до начала обработки запросов
+func (eb *EBService) SetOIDCProvider(p OIDCProvider) {
+	eb.oidc = p
+}
+
+// StartOIDCLogin готовит вход через провайдера: возвращает адрес, на который отправляется браузер,
+// и токен с state, nonce и PKCE verifier - он кладется в cookie и проверяется в CompleteOIDCLogin
+func (eb EBService) StartOIDCLogin(ctx context.Context) (string, string, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if eb.oidc == nil {
+		return "", "", model.ErrOIDCDisabled
+	}
+
+	state, err := newToken()
+	if err != nil {
+		log.Printf("RID %q Failed to generate OIDC state in 'StartOIDCLogin': %v", rid, err)
+		return "", "", model.ErrCommon500
+	}
+	nonce, err := newToken()
+	if err != nil {
+		log.Printf("RID %q Failed to generate OIDC nonce in 'StartOIDCLogin': %v", rid, err)
+		return "", "", model.ErrCommon500
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		log.Printf("RID %q Failed to generate PKCE verifier in 'StartOIDCLogin': %v", rid, err)
+		return "", "", model.ErrCommon500
+	}
+
+	stateToken, err := eb.jwtManager.GenerateOIDCState(state, nonce, verifier)
+	if err != nil {
+		log.Printf("RID %q Failed to generate OIDC state token in 'StartOIDCLogin': %v", rid, err)
+		return "", "", model.ErrCommon500
+	}
+	authURL, err := eb.oidc.AuthURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("RID %q Failed to build OIDC auth URL in 'StartOIDCLogin': %v", rid, err)
+		return "", "", model.ErrOIDCLoginFailed
+	}
+
+	return authURL, stateToken, nil
+}
+
+// CompleteOIDCLogin обрабатывает возврат от провайдера: сверяет state, обменивает код на ID-токен и открывает сессию.
+// Пользователь находится по привязанной учетной записи провайдера, при первом входе - привязывается по подтвержденному
+// провайдером имейлу или создается с ролью user. С включенной 2FA, как и при входе по паролю, выдается только MFAToken.
+func (eb EBService) CompleteOIDCLogin(ctx context.Context, stateToken string, state string, code string) (*model.AuthTokens, *model.User, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if eb.oidc == nil {
+		return nil, nil, model.ErrOIDCDisabled
+	}
+
+	wantState, nonce, verifier, err := eb.jwtManager.ParseOIDCState(stateToken)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(wantState)) != 1 {
+		return nil, nil, model.ErrInvalidOIDCState
+	}
+	if code == "" {
+		return nil, nil, model.ErrOIDCLoginFailed
+	}
+
+	identity, err := eb.oidc.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		log.Printf("RID %q Failed to exchange OIDC code in 'CompleteOIDCLogin': %v", rid, err)
+		return nil, nil, model.ErrOIDCLoginFailed
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'CompleteOIDCLogin': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'CompleteOIDCLogin': %v", rid, err)
+			}
+		}
+	}()
+
+	user, err := eb.oidcUser(ctx, tx, identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrOIDCEmailMissing),
+			errors.Is(err, model.ErrOIDCEmailNotVerified),
+			errors.Is(err, model.ErrIncorrectEmail),
+			errors.Is(err, model.ErrUserAlreadyExists):
+			return nil, nil, err
+		default:
+			log.Printf("RID %q Failed to find or create OIDC user in 'CompleteOIDCLogin': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, model.ErrUserDisabled
+	}
+
+	var tokens *model.AuthTokens
+	if user.TOTPEnabledAt != nil {
+		// провайдер заменяет только пароль: второй фактор приложения проверяется в VerifyMFA
+		mfaToken, err := eb.jwtManager.GenerateMFAPending(user.ID)
+		if err != nil {
+			log.Printf("RID %q Failed to generate MFA token in 'CompleteOIDCLogin': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+		tokens = &model.AuthTokens{MFAToken: mfaToken}
+	} else {
+		tokens, err = eb.startSession(ctx, tx, user, 0, false)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrNoOrgMembership):
+				return nil, nil, err // 403
+			default:
+				log.Printf("RID %q Failed to start session in 'CompleteOIDCLogin': %v", rid, err)
+				return nil, nil, model.ErrCommon500
+			}
+		}
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'CompleteOIDCLogin': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed = true
+
+	return tokens, user, nil
+}
+
+// oidcUser находит, привязывает или создает пользователя для учетной записи провайдера и применяет маппинг роли;
+// провайдер управляет ролью только в организации по умолчанию
+func (eb EBService) oidcUser(ctx context.Context, exec repository.Executor, identity *oidc.Identity) (*model.User, error) {
+	var user *model.User
+
+	link, err := eb.repo.GetUserIdentity(ctx, exec, identity.Issuer, identity.Subject)
+	switch {
+	case err == nil:
+		user, err = eb.repo.GetUserByID(ctx, exec, link.UserID)
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, model.ErrIdentityNotFound):
+		user, err = eb.linkOIDCUser(ctx, exec, identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	// с настроенным утверждением роль пользователя определяет провайдер - при каждом входе
+	if eb.opts.OIDCRoleClaim == "" {
+		return user, nil
+	}
+	role := model.RoleUser
+	for _, v := range identity.ClaimValues(eb.opts.OIDCRoleClaim) {
+		if slices.Contains(eb.opts.OIDCAdminValues, v) {
+			role = model.RoleAdmin
+			break
+		}
+		if slices.Contains(eb.opts.OIDCOrganizerValues, v) {
+			role = model.RoleOrganizer
+		}
+	}
+	orgID, err := eb.defaultOrgID(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+	current, err := eb.repo.GetOrgMemberRole(ctx, exec, orgID, user.ID)
+	switch {
+	case errors.Is(err, model.ErrNotOrgMember):
+		if err := eb.repo.AddOrgMember(ctx, exec, orgID, user.ID, role); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case role != current:
+		if err := eb.repo.UpdateOrgMemberRole(ctx, exec, orgID, user.ID, role); err != nil {
+			return nil, err
+		}
+		// в access-токенах открытых в ней сессий осталась прежняя роль
+		if err := eb.repo.RevokeOrgSessions(ctx, exec, orgID, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// linkOIDCUser - первый вход через провайдера: к существующему аккаунту учетная запись привязывается,
+// только если провайдер подтвердил имейл, иначе её владелец мог бы захватить чужой аккаунт
+func (eb EBService) linkOIDCUser(ctx context.Context, exec repository.Executor, identity *oidc.Identity) (*model.User, error) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" {
+		return nil, model.ErrOIDCEmailMissing
+	}
+
+	user, err := eb.repo.GetUserByEmail(ctx, exec, email)
+	switch {
+	case err == nil:
+		if !identity.EmailVerified {
+			return nil, model.ErrOIDCEmailNotVerified
+		}
+	case errors.Is(err, model.ErrUserNotFound):
+		// пароль случайный и никому не известен: войти по паролю можно будет только после его сброса
+		password, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		user = &model.User{Email: email, Name: identity.GivenName, Surname: identity.FamilyName, PassHash: password, Role: model.RoleUser}
+		if err := validateNormalizeUser(user); err != nil {
+			return nil, err
+		}
+		if err := eb.repo.CreateUser(ctx, exec, user); err != nil {
+			return nil, err
+		}
+		orgID, err := eb.defaultOrgID(ctx, exec)
+		if err != nil {
+			return nil, err
+		}
+		if err := eb.repo.AddOrgMember(ctx, exec, orgID, user.ID, user.Role); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if identity.EmailVerified && user.EmailVerifiedAt == nil {
+		if err := eb.repo.SetEmailVerified(ctx, exec, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	link := &model.UserIdentity{UserID: user.ID, Issuer: identity.Issuer, Subject: identity.Subject, Email: email}
+	if err := eb.repo.CreateUserIdentity(ctx, exec, link); err != nil {
+		return nil, err
+	}
+
+	// в ответе нужны актуальные поля - например, подтверждение имейла
+	return eb.repo.GetUserByID(ctx, exec, user.ID)
+}
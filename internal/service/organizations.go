@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+const maxOrgName = 100
+
+// slugPattern - slug организаций и категорий попадает в адреса и формы, поэтому только строчные латинские буквы, цифры и дефисы
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,48}[a-z0-9]$`)
+
+// GetOrganizations - организации пользователя с его ролью в каждой
+func (eb EBService) GetOrganizations(ctx context.Context, uid int) ([]*model.Organization, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+
+	orgs, err := eb.repo.GetUserOrganizations(ctx, eb.txm.Executor(), uid)
+	if err != nil {
+		log.Printf("RID %q Failed to get user organizations from DB in 'GetOrganizations': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	return orgs, nil
+}
+
+// CreateOrganization создает организацию; создатель становится её админом и может переключиться в неё через SwitchOrganization
+func (eb EBService) CreateOrganization(ctx context.Context, org *model.Organization, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermOrgsCreate) {
+		return model.ErrAccessDenied
+	}
+	if err := validateNormalizeOrg(org); err != nil {
+		return err
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'CreateOrganization': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'CreateOrganization': %v", rid, err)
+			}
+		}
+	}()
+
+	if err := eb.repo.CreateOrganization(ctx, tx, org); err != nil {
+		switch {
+		case errors.Is(err, model.ErrOrgSlugTaken):
+			return err
+		default:
+			log.Printf("RID %q Failed to create organization in DB in 'CreateOrganization': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.AddOrgMember(ctx, tx, org.ID, actor.UserID, model.RoleAdmin); err != nil {
+		log.Printf("RID %q Failed to add organization creator in DB in 'CreateOrganization': %v", rid, err)
+		return model.ErrCommon500
+	}
+	org.Role = model.RoleAdmin
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'CreateOrganization': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	return nil
+}
+
+// InviteOrgMember выпускает приглашение в организацию админа, привязанное к имейлу. Зарегистрированный пользователь
+// принимает его сам через JoinOrganization, новый - регистрируется с ним. Ответ не зависит от того,
+// есть ли аккаунт с таким имейлом, - по нему нельзя проверить регистрацию в других организациях
+func (eb EBService) InviteOrgMember(ctx context.Context, email string, role string, actor model.Actor) (*model.Invite, error) {
+	if !actor.Can(model.PermUsersManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if role == "" {
+		role = model.RoleUser
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, model.ErrEmptyEmail
+	}
+
+	invite := &model.Invite{OrgID: actor.OrgID, Role: role, Email: email, CreatedBy: actor.UserID}
+	if err := eb.CreateInvite(ctx, invite, 0, actor.Role); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// JoinOrganization - пользователь сам принимает приглашение в другую организацию и становится её участником с ролью приглашения;
+// переключиться в неё можно через SwitchOrganization
+func (eb EBService) JoinOrganization(ctx context.Context, inviteToken string, actor model.Actor) (*model.Organization, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if inviteToken == "" {
+		return nil, model.ErrInvalidInvite
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'JoinOrganization': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'JoinOrganization': %v", rid, err)
+			}
+		}
+	}()
+
+	user, err := eb.repo.GetUserByID(ctx, tx, actor.UserID)
+	if err != nil {
+		log.Printf("RID %q Failed to get user from DB in 'JoinOrganization': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// приглашение блокируется до коммита - его нельзя использовать дважды
+	invite, err := eb.lockInvite(ctx, tx, inviteToken, user.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidInvite):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get invite from DB in 'JoinOrganization': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.AddOrgMember(ctx, tx, invite.OrgID, user.ID, invite.Role); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyOrgMember):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to add organization member in DB in 'JoinOrganization': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.MarkInviteUsed(ctx, tx, invite.ID, user.ID); err != nil {
+		log.Printf("RID %q Failed to mark invite as used in DB in 'JoinOrganization': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	orgs, err := eb.repo.GetUserOrganizations(ctx, tx, user.ID)
+	if err != nil {
+		log.Printf("RID %q Failed to get user organizations from DB in 'JoinOrganization': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'JoinOrganization': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	for _, o := range orgs {
+		if o.ID == invite.OrgID {
+			return o, nil
+		}
+	}
+	return nil, model.ErrOrgNotFound
+}
+
+// RemoveOrgMember исключает пользователя из организации админа; его сессии в ней отзываются, а из очередей ожидания
+// её ивентов он уходит. Аккаунт и участие в других организациях остаются
+func (eb EBService) RemoveOrgMember(ctx context.Context, uid int, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermUsersManage) {
+		return model.ErrAccessDenied
+	}
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+	if uid == actor.UserID { // в организации останется хотя бы один админ
+		return model.ErrSelfModification
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'RemoveOrgMember': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'RemoveOrgMember': %v", rid, err)
+			}
+		}
+	}()
+
+	if err := eb.repo.RemoveOrgMember(ctx, tx, actor.OrgID, uid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotOrgMember):
+			return err
+		default:
+			log.Printf("RID %q Failed to remove organization member in DB in 'RemoveOrgMember': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.RevokeOrgSessions(ctx, tx, actor.OrgID, uid); err != nil {
+		log.Printf("RID %q Failed to revoke user sessions in DB in 'RemoveOrgMember': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'RemoveOrgMember': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	return nil
+}
+
+// defaultOrgID - организация по умолчанию: в неё попадают пользователи без приглашения и указанной организации
+func (eb EBService) defaultOrgID(ctx context.Context, exec repository.Executor) (int, error) {
+	org, err := eb.repo.GetOrganizationBySlug(ctx, exec, model.DefaultOrgSlug)
+	if err != nil {
+		return 0, err
+	}
+	return org.ID, nil
+}
+
+func validateNormalizeOrg(org *model.Organization) error {
+	org.Name = strings.TrimSpace(org.Name)
+	org.Slug = strings.ToLower(strings.TrimSpace(org.Slug))
+	if org.Name == "" || utf8.RuneCountInString(org.Name) > maxOrgName || !slugPattern.MatchString(org.Slug) {
+		return model.ErrIncorrectOrg
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+func TestInviteAndJoinOrganization(t *testing.T) {
+	e := newTestEnv(t)
+	member := e.user("member@test.io", model.RoleUser)
+	stranger := e.user("stranger@test.io", model.RoleUser)
+
+	org := &model.Organization{Name: "Other Hall", Slug: "other-hall"}
+	if err := e.repo.CreateOrganization(e.ctx, e.store, org); err != nil {
+		t.Fatalf("create organization: %v", err)
+	}
+	otherAdmin := e.user("admin@other.io", model.RoleUser)
+	if err := e.repo.AddOrgMember(e.ctx, e.store, org.ID, otherAdmin.UserID, model.RoleAdmin); err != nil {
+		t.Fatalf("add org member: %v", err)
+	}
+	otherAdmin.OrgID, otherAdmin.Role = org.ID, model.RoleAdmin
+
+	// приглашение не раскрывает, зарегистрирован ли имейл, и никого не добавляет само
+	if _, err := e.svc.InviteOrgMember(e.ctx, "nobody@test.io", "", otherAdmin); err != nil {
+		t.Fatalf("InviteOrgMember(unknown email) error = %v", err)
+	}
+	invite, err := e.svc.InviteOrgMember(e.ctx, " Member@Test.IO ", model.RoleOrganizer, otherAdmin)
+	if err != nil {
+		t.Fatalf("InviteOrgMember() error = %v", err)
+	}
+	if invite.Token == "" || invite.OrgID != org.ID || invite.Email != "member@test.io" {
+		t.Fatalf("InviteOrgMember() = %+v", invite)
+	}
+	if orgs, _ := e.svc.GetOrganizations(e.ctx, member.UserID); len(orgs) != 1 {
+		t.Fatalf("member is in %d organizations before accepting, want 1", len(orgs))
+	}
+	if _, err := e.svc.InviteOrgMember(e.ctx, "member@test.io", "", member); !errors.Is(err, model.ErrAccessDenied) {
+		t.Errorf("InviteOrgMember() by user error = %v, want ErrAccessDenied", err)
+	}
+
+	// приглашение для чужого имейла не принимается
+	if _, err := e.svc.JoinOrganization(e.ctx, invite.Token, stranger); !errors.Is(err, model.ErrInvalidInvite) {
+		t.Errorf("JoinOrganization() by stranger error = %v, want ErrInvalidInvite", err)
+	}
+
+	joined, err := e.svc.JoinOrganization(e.ctx, invite.Token, member)
+	if err != nil {
+		t.Fatalf("JoinOrganization() error = %v", err)
+	}
+	if joined.ID != org.ID || joined.Role != model.RoleOrganizer {
+		t.Errorf("JoinOrganization() = %+v, want organizer of %d", joined, org.ID)
+	}
+	if orgs, _ := e.svc.GetOrganizations(e.ctx, member.UserID); len(orgs) != 2 {
+		t.Errorf("member is in %d organizations after accepting, want 2", len(orgs))
+	}
+
+	if _, err := e.svc.JoinOrganization(e.ctx, invite.Token, member); !errors.Is(err, model.ErrInvalidInvite) {
+		t.Errorf("JoinOrganization() with used invite error = %v, want ErrInvalidInvite", err)
+	}
+}
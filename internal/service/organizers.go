@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// checkEventAccess - может ли actor управлять ивентом: админ - любым, организатор - своим или тем, где он соорганизатор
+func (eb EBService) checkEventAccess(ctx context.Context, exec repository.Executor, actor model.Actor, event *model.Event) error {
+	if actor.Can(model.PermEventsManageAny) {
+		return nil
+	}
+	if !actor.Can(model.PermEventsManage) {
+		return model.ErrAccessDenied
+	}
+	if event.OwnerID == actor.UserID {
+		return nil
+	}
+	isOrganizer, err := eb.repo.IsEventOrganizer(ctx, exec, event.ID, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if !isOrganizer {
+		return model.ErrAccessDenied
+	}
+	return nil
+}
+
+// checkEventOwner - удалять ивент и назначать соорганизаторов может только владелец или админ
+func checkEventOwner(actor model.Actor, event *model.Event) error {
+	if actor.Can(model.PermEventsManageAny) {
+		return nil
+	}
+	if actor.Can(model.PermEventsManage) && event.OwnerID == actor.UserID {
+		return nil
+	}
+	return model.ErrAccessDenied
+}
+
+// AddEventOrganizer назначает соорганизатором ивента пользователя с ролью организатора; возвращает обновленный список
+func (eb EBService) AddEventOrganizer(ctx context.Context, eid int, uid int, actor model.Actor) ([]*model.EventOrganizer, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'AddEventOrganizer': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'AddEventOrganizer': %v", rid, err)
+			}
+		}
+	}()
+
+	event, err := eb.repo.GetEventByID(ctx, tx, actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'AddEventOrganizer': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if err := checkEventOwner(actor, event); err != nil {
+		return nil, err
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, tx, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'AddEventOrganizer': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	// соорганизатором может быть только организатор той же организации
+	user.Role, err = eb.repo.GetOrgMemberRole(ctx, tx, event.OrgID, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotOrgMember):
+			return nil, model.ErrUserNotFound
+		default:
+			log.Printf("RID %q Failed to get user role in organization from DB in 'AddEventOrganizer': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if user.Role != model.RoleOrganizer {
+		return nil, model.ErrNotOrganizerRole
+	}
+	if user.ID == event.OwnerID {
+		return nil, model.ErrAlreadyOrganizer
+	}
+
+	if err := eb.repo.AddEventOrganizer(ctx, tx, eid, uid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyOrganizer):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to add event organizer in DB in 'AddEventOrganizer': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	organizers, err := eb.repo.GetEventOrganizers(ctx, tx, eid)
+	if err != nil {
+		log.Printf("RID %q Failed to get event organizers from DB in 'AddEventOrganizer': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'AddEventOrganizer': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	return organizers, nil
+}
+
+// RemoveEventOrganizer снимает соорганизатора с ивента; соорганизатор может сняться и сам
+func (eb EBService) RemoveEventOrganizer(ctx context.Context, eid int, uid int, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsManage) {
+		return model.ErrAccessDenied
+	}
+	if eid < 1 {
+		return model.ErrIncorrectEventID
+	}
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'RemoveEventOrganizer': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'RemoveEventOrganizer': %v", rid, err)
+			}
+		}
+	}()
+
+	event, err := eb.repo.GetEventByID(ctx, tx, actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'RemoveEventOrganizer': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+	if uid != actor.UserID {
+		if err := checkEventOwner(actor, event); err != nil {
+			return err
+		}
+	}
+
+	if err := eb.repo.RemoveEventOrganizer(ctx, tx, eid, uid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrOrganizerNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to remove event organizer in DB in 'RemoveEventOrganizer': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'RemoveEventOrganizer': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	return nil
+}
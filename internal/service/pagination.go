@@ -0,0 +1,69 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	maxSearchQuery   = 200 // длина поискового запроса в символах
+)
+
+// pageCursor - содержимое курсора: сортировка, для которой он выдан, и ключ последнего элемента страницы
+type pageCursor struct {
+	Sort string    `json:"s"`
+	ID   int       `json:"id"`
+	Date time.Time `json:"d,omitzero"`
+}
+
+// encodeCursor - курсор непрозрачен для клиента: он только передает его обратно за следующей страницей
+func encodeCursor(sort string, key model.PageKey) string {
+	raw, _ := json.Marshal(pageCursor{Sort: sort, ID: key.ID, Date: key.Date})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor - пустой курсор означает первую страницу; курсор другой сортировки отклоняется,
+// иначе страницы перемешаются
+func decodeCursor(raw string, sort string) (*model.PageKey, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, model.ErrIncorrectCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort || c.ID < 1 {
+		return nil, model.ErrIncorrectCursor
+	}
+	return &model.PageKey{ID: c.ID, Date: c.Date}, nil
+}
+
+// normalizePageLimit - лимит по умолчанию и проверка границ
+func normalizePageLimit(limit *int) error {
+	if *limit == 0 {
+		*limit = defaultPageLimit
+	}
+	if *limit < 1 || *limit > maxPageLimit {
+		return model.ErrIncorrectPagination
+	}
+	return nil
+}
+
+// dayRangeEnd - даты фильтра приходят началом дня и To включается в диапазон,
+// поэтому в запрос To уходит началом следующего дня
+func dayRangeEnd(from, to *time.Time) (*time.Time, error) {
+	if to == nil {
+		return nil, nil
+	}
+	if from != nil && to.Before(*from) {
+		return nil, model.ErrIncorrectListFilter
+	}
+	end := to.AddDate(0, 0, 1)
+	return &end, nil
+}
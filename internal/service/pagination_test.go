@@ -0,0 +1,70 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	date := time.Date(2030, 3, 1, 19, 30, 0, 0, time.FixedZone("MSK", 3*3600))
+	tests := []struct {
+		name string
+		sort string
+		key  model.PageKey
+	}{
+		{name: "by id", sort: model.SortCreatedDesc, key: model.PageKey{ID: 42}},
+		{name: "by date", sort: model.SortDate, key: model.PageKey{ID: 7, Date: date}},
+		{name: "by date desc", sort: model.SortDateDesc, key: model.PageKey{ID: 1, Date: date}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(encodeCursor(tt.sort, tt.key), tt.sort)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if got.ID != tt.key.ID || !got.Date.Equal(tt.key.Date) {
+				t.Errorf("decodeCursor() = %+v, want %+v", *got, tt.key)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	valid := encodeCursor(model.SortDate, model.PageKey{ID: 5, Date: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)})
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	tests := []struct {
+		name    string
+		raw     string
+		sort    string
+		wantNil bool
+		wantErr error
+	}{
+		{name: "empty cursor is the first page", raw: "", sort: model.SortDate, wantNil: true},
+		{name: "valid", raw: valid, sort: model.SortDate},
+		{name: "cursor of another sort", raw: valid, sort: model.SortDateDesc, wantErr: model.ErrIncorrectCursor},
+		{name: "not base64", raw: "!!!", sort: model.SortDate, wantErr: model.ErrIncorrectCursor},
+		{name: "padded base64", raw: valid + "==", sort: model.SortDate, wantErr: model.ErrIncorrectCursor},
+		{name: "not json", raw: b64([]byte("cursor")), sort: model.SortDate, wantErr: model.ErrIncorrectCursor},
+		{name: "zero id", raw: b64([]byte(`{"s":"date","id":0}`)), sort: model.SortDate, wantErr: model.ErrIncorrectCursor},
+		{name: "negative id", raw: b64([]byte(`{"s":"date","id":-3}`)), sort: model.SortDate, wantErr: model.ErrIncorrectCursor},
+		{name: "broken date", raw: b64([]byte(`{"s":"date","id":3,"d":"yesterday"}`)), sort: model.SortDate, wantErr: model.ErrIncorrectCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.raw, tt.sort)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decodeCursor() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got == nil) != tt.wantNil {
+				t.Errorf("decodeCursor() = %v, want nil: %v", got, tt.wantNil)
+			}
+		})
+	}
+}
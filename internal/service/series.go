@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/UnendingLoop/EventBooker/internal/rrule"
+)
+
+// CreateSeries создает серию по шаблону ивента и сразу - её вхождения на горизонт планирования
+func (eb EBService) CreateSeries(ctx context.Context, series *model.EventSeries, actor model.Actor) (*model.SeriesInfo, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsCreate) {
+		return nil, model.ErrAccessDenied
+	}
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return nil, model.ErrIncorrectRRule
+	}
+	if err := validateNormalizeEvent(&series.Template); err != nil {
+		return nil, err // 400
+	}
+	series.RRule = rule.String()
+	series.OwnerID = actor.UserID
+	series.OrgID = actor.OrgID
+	series.GeneratedUntil, series.Finished = nil, false
+
+	// бегин транзакции - серия создается вместе с первыми вхождениями
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'CreateSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'CreateSeries': %v", rid, err)
+			}
+		}
+	}()
+
+	// вхождения молча пропускают удаленные позже категории, поэтому при создании серии они проверяются явно
+	if _, err := eb.existingCategories(ctx, tx, series.OrgID, series.Template.Categories, true); err != nil {
+		switch {
+		case errors.Is(err, model.ErrCategoryNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get categories from DB in 'CreateSeries': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if err := eb.repo.CreateSeries(ctx, tx, series); err != nil {
+		log.Printf("RID %q Failed to create event series in DB in 'CreateSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	occurrences, err := eb.materializeSeries(ctx, tx, series, rule)
+	if err != nil {
+		log.Printf("RID %q Failed to create event series occurrences in DB in 'CreateSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'CreateSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	eb.presentEvents(append(occurrences, &series.Template)...)
+	series.Template.BookingCloses = nil
+	return &model.SeriesInfo{EventSeries: *series, Occurrences: occurrences}, nil
+}
+
+// GetSeriesInfo - серия с вхождениями; тем, кто управляет ивентами, - вхождения в любом статусе, остальным - только актуальные
+func (eb EBService) GetSeriesInfo(ctx context.Context, id int, actor model.Actor) (*model.SeriesInfo, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if id < 1 {
+		return nil, model.ErrSeriesNotFound
+	}
+
+	series, err := eb.repo.GetSeriesByIDNoLock(ctx, eb.txm.Executor(), actor.OrgID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrSeriesNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event series from DB in 'GetSeriesInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	events, err := eb.repo.GetSeriesEvents(ctx, eb.txm.Executor(), series.ID, time.Time{})
+	if err != nil {
+		log.Printf("RID %q Failed to get event series occurrences from DB in 'GetSeriesInfo': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	if !actor.Can(model.PermEventsManage) {
+		events = slices.DeleteFunc(events, func(e *model.Event) bool { return e.Status != model.EventStatusActual })
+	}
+	if err := eb.repo.LoadEventLabels(ctx, eb.txm.Executor(), events); err != nil {
+		log.Printf("RID %q Failed to get events categories and tags from DB in 'GetSeriesInfo': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	eb.presentEvents(append(events, &series.Template)...)
+	series.Template.BookingCloses = nil
+	return &model.SeriesInfo{EventSeries: *series, Occurrences: events}, nil
+}
+
+// MaterializeSeries - эксклюзивно для планировщика серий: создает вхождения серий на горизонт планирования.
+// Каждая серия - в своей транзакции, чтобы ошибка в одной не останавливала остальные
+func (eb EBService) MaterializeSeries(ctx context.Context) error {
+	pending, err := eb.repo.GetPendingSeries(ctx, eb.txm.Executor(), time.Now().Add(eb.opts.SeriesHorizon))
+	if err != nil {
+		log.Println("Failed to fetch pending event series in 'MaterializeSeries':", err)
+		return model.ErrCommon500
+	}
+
+	var failed error
+	created := 0
+	for _, s := range pending {
+		n, err := eb.materializeSeriesByID(ctx, s.OrgID, s.ID)
+		if err != nil {
+			log.Printf("Failed to create occurrences of event series %d in 'MaterializeSeries': %v", s.ID, err)
+			failed = model.ErrCommon500
+			continue
+		}
+		created += n
+	}
+
+	if created > 0 {
+		log.Printf("Created %d occurrences of event series\n", created)
+	}
+	return failed
+}
+
+func (eb EBService) materializeSeriesByID(ctx context.Context, orgID int, id int) (int, error) {
+	// транзакция - бегин
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("Failed to rollback transaction in 'MaterializeSeries': %v", err)
+			}
+		}
+	}()
+
+	// блокировка серии - параллельное изменение шаблона или второй экземпляр приложения подождут
+	series, err := eb.repo.GetSeriesByID(ctx, tx, orgID, id)
+	if err != nil {
+		return 0, err
+	}
+	if series.Finished {
+		return 0, nil
+	}
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		return 0, err
+	}
+	occurrences, err := eb.materializeSeries(ctx, tx, series, rule)
+	if err != nil {
+		return 0, err
+	}
+
+	// закоммитить транзакцию
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	committed = true
+	return len(occurrences), nil
+}
+
+// materializeSeries создает вхождения заблокированной серии после generated_until до горизонта планирования
+// и сохраняет новые generated_until и finished. Вхождения, начало которых уже прошло, пропускаются
+func (eb EBService) materializeSeries(ctx context.Context, tx repository.Tx, series *model.EventSeries, rule *rrule.Rule) ([]*model.Event, error) {
+	tmpl := series.Template
+	categories, err := eb.existingCategories(ctx, tx, series.OrgID, tmpl.Categories, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	horizon := now.Add(eb.opts.SeriesHorizon)
+	created := make([]*model.Event, 0)
+	finished := true
+	for start := range rule.Occurrences(tmpl.StartsAt.In(eventLocation(tmpl.TimeZone))) {
+		if start.After(horizon) {
+			finished = false
+			break
+		}
+		if series.GeneratedUntil != nil && !start.After(*series.GeneratedUntil) {
+			continue
+		}
+		series.GeneratedUntil = &start
+		if start.Before(now) {
+			continue
+		}
+
+		event := &model.Event{
+			Title:      tmpl.Title,
+			Descr:      tmpl.Descr,
+			Status:     model.EventStatusActual,
+			StartsAt:   model.CustomTime{Time: start},
+			TimeZone:   tmpl.TimeZone,
+			TotalSeats: tmpl.TotalSeats,
+			AvailSeats: tmpl.TotalSeats,
+			BookWindow: tmpl.BookWindow,
+			MaxPerBook: tmpl.MaxPerBook,
+			OwnerID:    series.OwnerID,
+			OrgID:      series.OrgID,
+			Venue:      tmpl.Venue,
+			Address:    tmpl.Address,
+			Contact:    tmpl.Contact,
+			CoverURL:   tmpl.CoverURL,
+			Categories: slices.Clone(categories),
+			Tags:       slices.Clone(tmpl.Tags),
+			SeriesID:   series.ID,
+		}
+		if tmpl.EndsAt != nil {
+			event.EndsAt = &model.CustomTime{Time: start.Add(tmpl.EndsAt.Sub(tmpl.StartsAt.Time))}
+		}
+		if err := eb.repo.CreateEvent(ctx, tx, event); err != nil {
+			return nil, err
+		}
+		if err := eb.saveEventLabels(ctx, tx, event, true, true); err != nil {
+			return nil, err
+		}
+		created = append(created, event)
+	}
+	series.Finished = finished
+
+	if err := eb.repo.UpdateSeries(ctx, tx, series); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// existingCategories - slug из списка, которые есть в организации; strict - отсутствующий slug дает ErrCategoryNotFound
+func (eb EBService) existingCategories(ctx context.Context, exec repository.Executor, orgID int, slugs []string, strict bool) ([]string, error) {
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+	all, err := eb.repo.GetCategoriesList(ctx, exec, orgID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(slugs))
+	for _, slug := range slugs {
+		if slices.ContainsFunc(all, func(c *model.Category) bool { return c.Slug == slug }) {
+			res = append(res, slug)
+		} else if strict {
+			return nil, model.ErrCategoryNotFound
+		}
+	}
+	return res, nil
+}
+
+// UpdateEventSeries применяет изменение к вхождению eid, ко всем следующим актуальным вхождениям его серии
+// и к шаблону, по которому создаются будущие вхождения. Даты вхождений не меняются: у нового начала берется
+// только время суток, окончание сдвигается вместе с началом. Менять серию может только её владелец или админ
+func (eb EBService) UpdateEventSeries(ctx context.Context, eid int, upd *model.EventUpdate, actor model.Actor) (*model.Event, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+	if upd.TimeZone != nil || upd.EventDate != nil {
+		return nil, model.ErrSeriesTimeUpdate
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'UpdateEventSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'UpdateEventSeries': %v", rid, err)
+			}
+		}
+	}()
+
+	event, err := eb.repo.GetEventByIDNoLock(ctx, tx, actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'UpdateEventSeries': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if event.SeriesID == 0 {
+		return nil, model.ErrNotInSeries
+	}
+	// блокировка серии - планировщик не создаст вхождения по старому шаблону, пока идет изменение
+	series, err := eb.repo.GetSeriesByID(ctx, tx, actor.OrgID, event.SeriesID)
+	if err != nil {
+		log.Printf("RID %q Failed to get event series from DB in 'UpdateEventSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	if !actor.Can(model.PermEventsManageAny) && series.OwnerID != actor.UserID {
+		return nil, model.ErrAccessDenied
+	}
+	if event.Status != model.EventStatusActual {
+		return nil, model.ErrEventNotEditable
+	}
+
+	loc := eventLocation(event.TimeZone)
+	shift, err := seriesTimeShift(event, upd, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences, err := eb.repo.GetSeriesEvents(ctx, tx, series.ID, event.StartsAt.Time)
+	if err != nil {
+		log.Printf("RID %q Failed to get event series occurrences from DB in 'UpdateEventSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	var updated *model.Event
+	promoted := make([]*model.BookWithUser, 0)
+	for _, o := range occurrences {
+		if o.Status != model.EventStatusActual {
+			continue
+		}
+		// вхождение с блокировкой, как в UpdateEvent
+		o, err = eb.repo.GetEventByID(ctx, tx, actor.OrgID, o.ID)
+		if err != nil {
+			log.Printf("RID %q Failed to get event from DB in 'UpdateEventSeries': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+		oupd := *upd
+		if shift != nil {
+			oupd.StartsAt, oupd.EndsAt = shift(o)
+		}
+		if err := applyEventUpdate(o, &oupd); err != nil {
+			return nil, err // 400/409
+		}
+		if err := eb.repo.UpdateEvent(ctx, tx, o); err != nil {
+			log.Printf("RID %q Failed to update event in DB in 'UpdateEventSeries': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+		if err := eb.saveEventLabels(ctx, tx, o, upd.Categories != nil, upd.Tags != nil); err != nil {
+			switch {
+			case errors.Is(err, model.ErrCategoryNotFound):
+				return nil, err
+			default:
+				log.Printf("RID %q Failed to save event categories and tags in DB in 'UpdateEventSeries': %v", rid, err)
+				return nil, model.ErrCommon500
+			}
+		}
+		// увеличение вместимости могло освободить места для очереди ожидания
+		p, err := eb.promoteWaitlist(ctx, tx, o.OrgID, o.ID)
+		if err != nil {
+			log.Printf("RID %q Failed to promote waitlist in 'UpdateEventSeries': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+		for _, b := range p {
+			o.AvailSeats -= b.Quantity
+		}
+		promoted = append(promoted, p...)
+		if o.ID == eid {
+			updated = o
+		}
+	}
+
+	applySeriesUpdate(series, updated, upd, loc)
+	if err := eb.repo.UpdateSeries(ctx, tx, series); err != nil {
+		log.Printf("RID %q Failed to update event series in DB in 'UpdateEventSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	if err := eb.repo.LoadEventLabels(ctx, tx, []*model.Event{updated}); err != nil {
+		log.Printf("RID %q Failed to get event categories and tags from DB in 'UpdateEventSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'UpdateEventSeries': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	eb.announcePromotions(ctx, promoted)
+
+	eb.presentEvents(updated)
+	return updated, nil
+}
+
+// seriesTimeShift - по изменению времени вхождения event строит новое начало и окончание для любого вхождения серии:
+// та же дата, новое время суток, прежняя или новая длительность. nil - время не меняется
+func seriesTimeShift(event *model.Event, upd *model.EventUpdate, loc *time.Location) (func(*model.Event) (*model.CustomTime, *model.CustomTime), error) {
+	if upd.StartsAt == nil && upd.EndsAt == nil {
+		return nil, nil
+	}
+
+	clock := event.StartsAt.In(loc)
+	if upd.StartsAt != nil {
+		start := *upd.StartsAt
+		start.InZone(loc)
+		if start.IsZero() {
+			return nil, model.ErrIncorrectEventTime
+		}
+		if start.Format(time.DateOnly) != clock.Format(time.DateOnly) {
+			return nil, model.ErrSeriesTimeUpdate
+		}
+		clock = start.Time
+	}
+	// длительность: -1 - у каждого вхождения своя прежняя, 0 - без окончания
+	duration := time.Duration(-1)
+	if upd.EndsAt != nil {
+		end := *upd.EndsAt
+		end.InZone(loc)
+		duration = 0
+		if !end.IsZero() {
+			if duration = end.Sub(clock); duration <= 0 {
+				return nil, model.ErrIncorrectEventTime
+			}
+		}
+	}
+
+	return func(o *model.Event) (*model.CustomTime, *model.CustomTime) {
+		start := atClock(o.StartsAt.In(loc), clock)
+		ends := &model.CustomTime{} // пустое окончание в EventUpdate убирает его
+		switch {
+		case duration > 0:
+			ends.Time = start.Add(duration)
+		case duration < 0 && o.EndsAt != nil:
+			ends.Time = start.Add(o.EndsAt.Sub(o.StartsAt.Time))
+		}
+		return &model.CustomTime{Time: start}, ends
+	}, nil
+}
+
+// applySeriesUpdate переносит в шаблон серии поля, переданные в upd, уже нормализованными значениями вхождения updated
+func applySeriesUpdate(series *model.EventSeries, updated *model.Event, upd *model.EventUpdate, loc *time.Location) {
+	tmpl := &series.Template
+	if upd.Title != nil {
+		tmpl.Title = updated.Title
+	}
+	if upd.Descr != nil {
+		tmpl.Descr = updated.Descr
+	}
+	if upd.TotalSeats != nil {
+		tmpl.TotalSeats = updated.TotalSeats
+	}
+	if upd.BookWindow != nil {
+		tmpl.BookWindow = updated.BookWindow
+	}
+	if upd.MaxPerBook != nil {
+		tmpl.MaxPerBook = updated.MaxPerBook
+	}
+	if upd.Venue != nil {
+		tmpl.Venue = updated.Venue
+	}
+	if upd.Address != nil {
+		tmpl.Address = updated.Address
+	}
+	if upd.Contact != nil {
+		tmpl.Contact = updated.Contact
+	}
+	if upd.CoverURL != nil {
+		tmpl.CoverURL = updated.CoverURL
+	}
+	if upd.Categories != nil {
+		tmpl.Categories = slices.Clone(updated.Categories)
+	}
+	if upd.Tags != nil {
+		tmpl.Tags = slices.Clone(updated.Tags)
+	}
+	if upd.StartsAt != nil || upd.EndsAt != nil {
+		// время суток шаблона и последнего созданного вхождения меняются вместе, иначе вхождение
+		// в тот же день, но позже, планировщик счел бы ещё не созданным
+		clock := updated.StartsAt.In(loc)
+		tmpl.StartsAt.Time = atClock(tmpl.StartsAt.In(loc), clock)
+		tmpl.EndsAt = nil
+		if updated.EndsAt != nil {
+			tmpl.EndsAt = &model.CustomTime{Time: tmpl.StartsAt.Add(updated.EndsAt.Sub(updated.StartsAt.Time))}
+		}
+		if series.GeneratedUntil != nil {
+			until := atClock(series.GeneratedUntil.In(loc), clock)
+			series.GeneratedUntil = &until
+		}
+	}
+}
+
+// atClock - дата day со временем суток clock в часовом поясе day
+func atClock(day, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location())
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+func TestSeriesKeepsRenamedCategory(t *testing.T) {
+	e := newTestEnv(t)
+	admin := e.user("admin@test.io", model.RoleAdmin)
+	e.svc.opts.SeriesHorizon = 7 * 24 * time.Hour
+
+	category := &model.Category{Name: "Jazz", Slug: "jazz"}
+	if err := e.svc.CreateCategory(e.ctx, category, admin); err != nil {
+		t.Fatalf("CreateCategory() error = %v", err)
+	}
+	info, err := e.svc.CreateSeries(e.ctx, &model.EventSeries{
+		RRule: "FREQ=WEEKLY",
+		Template: model.Event{
+			Title:      "Jam session",
+			StartsAt:   model.CustomTime{Time: time.Now().Add(24 * time.Hour)},
+			TimeZone:   "UTC",
+			TotalSeats: 10,
+			BookWindow: 600,
+			MaxPerBook: 2,
+			Categories: []string{"jazz"},
+		},
+	}, admin)
+	if err != nil {
+		t.Fatalf("CreateSeries() error = %v", err)
+	}
+	created := len(info.Occurrences)
+
+	category.Slug = "live-jazz"
+	if err := e.svc.UpdateCategory(e.ctx, category, admin); err != nil {
+		t.Fatalf("UpdateCategory() error = %v", err)
+	}
+
+	// вхождения, созданные после переименования, остаются в категории
+	e.svc.opts.SeriesHorizon = 21 * 24 * time.Hour
+	if err := e.svc.MaterializeSeries(e.ctx); err != nil {
+		t.Fatalf("MaterializeSeries() error = %v", err)
+	}
+	info, err = e.svc.GetSeriesInfo(e.ctx, info.ID, admin)
+	if err != nil {
+		t.Fatalf("GetSeriesInfo() error = %v", err)
+	}
+	if !slices.Equal(info.Template.Categories, []string{"live-jazz"}) {
+		t.Errorf("template categories = %v, want [live-jazz]", info.Template.Categories)
+	}
+	if len(info.Occurrences) <= created {
+		t.Fatalf("occurrences = %d, want more than %d after materialization", len(info.Occurrences), created)
+	}
+	for _, ev := range info.Occurrences {
+		if !slices.Equal(ev.Categories, []string{"live-jazz"}) {
+			t.Errorf("occurrence %s categories = %v, want [live-jazz]", ev.StartsAt.Format(time.DateOnly), ev.Categories)
+		}
+	}
+}
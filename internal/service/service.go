@@ -4,105 +4,400 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
 	"github.com/UnendingLoop/EventBooker/internal/mwauthlog"
 	"github.com/UnendingLoop/EventBooker/internal/repository"
-	"github.com/wb-go/wbf/dbpg"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type EBService struct {
 	repo       repository.EBRepo
-	db         *dbpg.DB
+	txm        repository.TxManager
 	jwtManager *mwauthlog.JWTManager
+	notifier   Notifier
+	mailer     Mailer
+	scheduler  BookScheduler
+	oidc       OIDCProvider
+	opts       Options
 }
 
-func NewEBService(ebrepo repository.EBRepo, ebdb *dbpg.DB, jwt *mwauthlog.JWTManager) *EBService {
-	return &EBService{repo: ebrepo, db: ebdb, jwtManager: jwt}
+// Options - настройки сервиса из конфига приложения
+type Options struct {
+	SessionTTL           time.Duration // срок жизни refresh-токена
+	AppURL               string        // базовый адрес приложения для ссылок в письмах
+	RequireVerifiedEmail bool          // запрет бронирования до подтверждения имейла
+	RequireAdminMFA      bool          // админ не может выключить 2FA (доступ к админским маршрутам без неё закрывает RequireMFA)
+	MaxLoginFailures     int           // число неудачных входов подряд до временной блокировки
+	LoginLockout         time.Duration // длительность блокировки и окно, в котором считаются неудачные входы
+	OIDCRoleClaim        string        // утверждение ID-токена с ролями/группами; пусто - роль провайдером не управляется
+	OIDCAdminValues      []string      // значения OIDCRoleClaim, дающие роль admin
+	OIDCOrganizerValues  []string      // значения OIDCRoleClaim, дающие роль organizer; без admin- и organizer-значений выставляется user
+	BookingCloseBefore   time.Duration // за сколько до начала ивента закрывается бронирование и очередь ожидания
+	SeriesHorizon        time.Duration // на сколько вперед создаются вхождения серий ивентов
 }
 
-func (eb EBService) CreateUser(ctx context.Context, user *model.User) (string, error) {
+type Notifier interface {
+	Notify(ctx context.Context, n *model.Notification) error
+}
+
+// Mailer отправляет письма со ссылками подтверждения имейла и сброса пароля
+type Mailer interface {
+	Send(ctx context.Context, m *model.Mail) error
+}
+
+// BookScheduler получает дедлайны новых броней, чтобы истечь их ровно в срок
+type BookScheduler interface {
+	Schedule(orgID int, bid int, deadline time.Time)
+}
+
+func NewEBService(ebrepo repository.EBRepo, txm repository.TxManager, jwt *mwauthlog.JWTManager, ntf Notifier, mlr Mailer, opts Options) *EBService {
+	if opts.SessionTTL <= 0 {
+		log.Println("Invalid session TTL provided for EBService. Using default value: 30 days")
+		opts.SessionTTL = 30 * 24 * time.Hour
+	}
+	if opts.AppURL == "" {
+		log.Println("App URL is not provided for EBService. Using default value: http://localhost:8080")
+		opts.AppURL = "http://localhost:8080"
+	}
+	opts.AppURL = strings.TrimRight(opts.AppURL, "/")
+	if opts.MaxLoginFailures <= 0 {
+		log.Println("Invalid max login failures provided for EBService. Using default value: 5")
+		opts.MaxLoginFailures = 5
+	}
+	if opts.LoginLockout <= 0 {
+		log.Println("Invalid login lockout duration provided for EBService. Using default value: 15 minutes")
+		opts.LoginLockout = 15 * time.Minute
+	}
+	if opts.BookingCloseBefore < 0 {
+		log.Println("Invalid booking close offset provided for EBService. Booking will close at event start")
+		opts.BookingCloseBefore = 0
+	}
+	if opts.SeriesHorizon <= 0 {
+		log.Println("Invalid event series horizon provided for EBService. Using default value: 60 days")
+		opts.SeriesHorizon = 60 * 24 * time.Hour
+	}
+	return &EBService{repo: ebrepo, txm: txm, jwtManager: jwt, notifier: ntf, mailer: mlr, opts: opts}
+}
+
+// SetBookScheduler подключает планировщик истечения броней; вызывать до начала обработки запросов.
+// Без планировщика брони истекают только при периодическом запуске CleanExpiredBooks.
+func (eb *EBService) SetBookScheduler(bs BookScheduler) {
+	eb.scheduler = bs
+}
+
+// CreateUser регистрирует пользователя с ролью user в организации orgSlug (пусто - организация по умолчанию);
+// по приглашению inviteToken - в организацию приглашения с его ролью, только так можно получить повышенную роль
+func (eb EBService) CreateUser(ctx context.Context, user *model.User, inviteToken string, orgSlug string) (*model.AuthTokens, error) {
 	rid := model.RequestIDFromCtx(ctx)
 
+	user.Role = model.RoleUser
 	if err := validateNormalizeUser(user); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err := eb.repo.CreateUser(ctx, eb.db, user)
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'CreateUser': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'CreateUser': %v", rid, err)
+			}
+		}
+	}()
+
+	// приглашение блокируется до коммита - его нельзя использовать дважды
+	var invite *model.Invite
+	if inviteToken != "" {
+		invite, err = eb.lockInvite(ctx, tx, inviteToken, user.Email)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrInvalidInvite):
+				return nil, err
+			default:
+				log.Printf("RID %q Failed to get invite from DB in 'CreateUser': %v", rid, err)
+				return nil, model.ErrCommon500
+			}
+		}
+		user.Role = invite.Role
+	}
+
+	// организация приглашения важнее указанной при регистрации
+	var orgID int
+	if invite != nil {
+		orgID = invite.OrgID
+	} else {
+		if orgSlug == "" {
+			orgSlug = model.DefaultOrgSlug
+		}
+		org, err := eb.repo.GetOrganizationBySlug(ctx, tx, strings.ToLower(strings.TrimSpace(orgSlug)))
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrOrgNotFound):
+				return nil, err
+			default:
+				log.Printf("RID %q Failed to get organization from DB in 'CreateUser': %v", rid, err)
+				return nil, model.ErrCommon500
+			}
+		}
+		orgID = org.ID
+	}
+
+	err = eb.repo.CreateUser(ctx, tx, user)
 	if err != nil {
 		switch {
-		case strings.Contains(err.Error(), "unique_violation"):
-			return "", model.ErrUserAlreadyExists
+		case errors.Is(err, model.ErrUserAlreadyExists):
+			return nil, err
 		default:
 			log.Printf("RID %q Failed to put new user to DB in 'CreateUser': %q", rid, err)
-			return "", model.ErrCommon500
+			return nil, model.ErrCommon500
 		}
 	}
 
-	token, err := eb.jwtManager.Generate(user.ID, user.Email, user.Role)
+	if err := eb.repo.AddOrgMember(ctx, tx, orgID, user.ID, user.Role); err != nil {
+		log.Printf("RID %q Failed to add user to organization in DB in 'CreateUser': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	if invite != nil {
+		if err := eb.repo.MarkInviteUsed(ctx, tx, invite.ID, user.ID); err != nil {
+			log.Printf("RID %q Failed to mark invite as used in DB in 'CreateUser': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	tokens, err := eb.startSession(ctx, tx, user, orgID, false)
 	if err != nil {
-		return "", model.ErrCommon500
+		log.Printf("RID %q Failed to start session in 'CreateUser': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'CreateUser': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	// письмо не критично для регистрации - его можно запросить повторно
+	if err := eb.sendVerificationMail(ctx, user); err != nil {
+		log.Printf("RID %q Failed to send verification mail in 'CreateUser': %v", rid, err)
 	}
 
-	return token, nil
+	return tokens, nil
 }
 
-func (eb EBService) LoginUser(ctx context.Context, email string, password string) (string, *model.User, error) {
+// LoginUser не раскрывает, существует ли имейл: для неизвестного адреса ответ и время ответа те же, что и для неверного пароля.
+// После MaxLoginFailures неудачных попыток в пределах LoginLockout вход блокируется на LoginLockout.
+func (eb EBService) LoginUser(ctx context.Context, email string, password string) (*model.AuthTokens, *model.User, error) {
 	rid := model.RequestIDFromCtx(ctx)
 
-	user, err := eb.repo.GetUserByEmail(ctx, eb.db, email)
+	email = strings.ToLower(strings.TrimSpace(email))
+	user, err := eb.repo.GetUserByEmail(ctx, eb.txm.Executor(), email)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrUserNotFound):
-			return "", nil, err
+			// сравнение с фиктивным хэшем выравнивает время ответа
+			_ = bcrypt.CompareHashAndPassword(dummyPassHash, []byte(password))
+			return nil, nil, model.ErrInvalidCredentials
 		default:
 			log.Printf("RID %q Failed to get user from DB in 'LoginUser': %q", rid, err)
-			return "", nil, model.ErrCommon500
+			return nil, nil, model.ErrCommon500
 		}
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PassHash), []byte(password)); err != nil {
-		return "", nil, model.ErrInvalidCredentials
+	// пароль сверяется и при действующей блокировке - чтобы она не отличалась по времени ответа
+	passErr := bcrypt.CompareHashAndPassword([]byte(user.PassHash), []byte(password))
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return nil, nil, model.ErrTooManyAttempts
+	}
+	if passErr != nil {
+		eb.registerLoginFailure(ctx, user.ID)
+		return nil, nil, model.ErrInvalidCredentials
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, model.ErrUserDisabled
 	}
 
-	token, err := eb.jwtManager.Generate(user.ID, user.Email, user.Role)
+	// с включенной 2FA пароль - только первый шаг: сессия откроется в VerifyMFA, там же сбросится счетчик неудач
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := eb.jwtManager.GenerateMFAPending(user.ID)
+		if err != nil {
+			log.Printf("RID %q Failed to generate MFA token in 'LoginUser': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
+		return &model.AuthTokens{MFAToken: mfaToken}, user, nil
+	}
+
+	if err := eb.repo.ResetLoginFailures(ctx, eb.txm.Executor(), user.ID); err != nil {
+		log.Printf("RID %q Failed to reset login failures in DB in 'LoginUser': %v", rid, err)
+		return nil, nil, model.ErrCommon500
+	}
+
+	tokens, err := eb.startSession(ctx, eb.txm.Executor(), user, 0, false)
 	if err != nil {
-		return "", nil, model.ErrCommon500
+		switch {
+		case errors.Is(err, model.ErrNoOrgMembership):
+			return nil, nil, err // 403
+		default:
+			log.Printf("RID %q Failed to start session in 'LoginUser': %v", rid, err)
+			return nil, nil, model.ErrCommon500
+		}
 	}
 
-	return token, user, nil
+	return tokens, user, nil
 }
 
-func (eb EBService) CreateEvent(ctx context.Context, event *model.Event) error {
+// registerLoginFailure засчитывает неверный пароль или код 2FA; неудачный вход остается неудачным, даже если попытку не удалось записать
+func (eb EBService) registerLoginFailure(ctx context.Context, uid int) {
 	rid := model.RequestIDFromCtx(ctx)
 
+	now := time.Now().UTC()
+	lockedUntil, err := eb.repo.RegisterLoginFailure(ctx, eb.txm.Executor(), uid,
+		now.Add(-eb.opts.LoginLockout), eb.opts.MaxLoginFailures, now.Add(eb.opts.LoginLockout))
+	if err != nil {
+		log.Printf("RID %q Failed to register login failure in DB: %v", rid, err)
+		return
+	}
+	if lockedUntil != nil {
+		log.Printf("RID %q User %d is locked out until %s after %d failed logins", rid, uid, lockedUntil.Format(time.RFC3339), eb.opts.MaxLoginFailures)
+	}
+}
+
+// CreateEvent создает ивент; создатель становится его владельцем
+func (eb EBService) CreateEvent(ctx context.Context, event *model.Event, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsCreate) {
+		return model.ErrAccessDenied
+	}
 	if err := validateNormalizeEvent(event); err != nil {
 		return err // 400
 	}
+	event.OwnerID = actor.UserID
+	event.OrgID = actor.OrgID
 
-	if err := eb.repo.CreateEvent(ctx, eb.db, event); err != nil {
+	// бегин транзакции - ивент создается вместе с категориями и тегами
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'CreateEvent': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'CreateEvent': %v", rid, err)
+			}
+		}
+	}()
+
+	if err := eb.repo.CreateEvent(ctx, tx, event); err != nil {
 		log.Printf("RID %q Failed to create new event in DB in 'CreateEvent': %v", rid, err)
 		return model.ErrCommon500
 	}
+	if err := eb.saveEventLabels(ctx, tx, event, true, true); err != nil {
+		switch {
+		case errors.Is(err, model.ErrCategoryNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to save event categories and tags in DB in 'CreateEvent': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'CreateEvent': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
 
+	eb.presentEvents(event)
 	return nil
 }
 
+// saveEventLabels заменяет категории и (или) теги ивента значениями из event
+func (eb EBService) saveEventLabels(ctx context.Context, exec repository.Executor, event *model.Event, categories, tags bool) error {
+	if categories {
+		if err := eb.repo.SetEventCategories(ctx, exec, event.OrgID, event.ID, event.Categories); err != nil {
+			return err
+		}
+	}
+	if tags {
+		if err := eb.repo.SetEventTags(ctx, exec, event.ID, event.Tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookingClosesAt - момент, после которого ивент нельзя забронировать и встать в его очередь ожидания
+func (eb EBService) bookingClosesAt(event *model.Event) time.Time {
+	return event.StartsAt.Add(-eb.opts.BookingCloseBefore)
+}
+
+// presentEvents готовит ивенты к ответу: время - в часовом поясе ивента, дата для старых клиентов и закрытие бронирования
+func (eb EBService) presentEvents(events ...*model.Event) {
+	for _, e := range events {
+		loc := eventLocation(e.TimeZone)
+		e.StartsAt.InZone(loc)
+		if e.EndsAt != nil {
+			e.EndsAt.InZone(loc)
+		}
+		e.EventDate = e.StartsAt.Format(time.DateOnly)
+		closes := eb.bookingClosesAt(e)
+		e.BookingCloses = &closes
+	}
+}
+
+// eventLocation - часовой пояс ивента; пояс проверяется при записи, UTC подставляется только для удаленного из базы tzdata
+func eventLocation(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// BookEvent бронирует места на ивент организации book.OrgID
 func (eb EBService) BookEvent(ctx context.Context, book *model.Book) error {
 	rid := model.RequestIDFromCtx(ctx)
 
-	if book.EventID <= 0 || book.UserID <= 0 {
+	if book.EventID <= 0 || book.UserID <= 0 || book.OrgID <= 0 {
 		return model.ErrEmptyBookInfo // 400
 	}
+	if book.Quantity == 0 { // по умолчанию - одно место
+		book.Quantity = 1
+	}
+	if book.Quantity < 0 {
+		return model.ErrIncorrectQuantity // 400
+	}
+
+	if err := eb.checkEmailVerified(ctx, book.UserID); err != nil {
+		switch {
+		case errors.Is(err, model.ErrEmailNotVerified):
+			return err // 403
+		default:
+			log.Printf("RID %q Failed to check email verification in 'BookEvent': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
 
 	book.Status = model.BookStatusCreated
 
 	// транзакция - бегин
-	tx, err := eb.db.BeginTx(ctx, nil)
+	tx, err := eb.txm.BeginTx(ctx)
 	if err != nil {
 		log.Printf("RID %q Failed to begin transaction in 'BookEvent': %v", rid, err)
 		return model.ErrCommon500 // 500
@@ -116,7 +411,7 @@ func (eb EBService) BookEvent(ctx context.Context, book *model.Book) error {
 		}
 	}()
 	// получаем ивент и проверяем доступность мест
-	event, err := eb.repo.GetEventByID(ctx, tx, book.EventID)
+	event, err := eb.repo.GetEventByID(ctx, tx, book.OrgID, book.EventID)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrEventNotFound):
@@ -130,10 +425,13 @@ func (eb EBService) BookEvent(ctx context.Context, book *model.Book) error {
 	if event.Status != model.EventStatusActual {
 		return model.ErrExpiredEvent // 409
 	}
-	if event.EventDate.Before(time.Now().UTC()) {
-		return model.ErrExpiredEvent // 409
+	if !time.Now().Before(eb.bookingClosesAt(event)) { // закрыто за BookingCloseBefore до начала, либо EventSweeper еще не успел перевести ивент в expired
+		return model.ErrBookingClosed // 409
 	}
-	if event.AvailSeats == 0 {
+	if book.Quantity > event.MaxPerBook {
+		return model.ErrTooManySeatsPerBook // 400
+	}
+	if event.AvailSeats < book.Quantity {
 		return model.ErrNoSeatsAvailable // 409
 	}
 
@@ -146,10 +444,15 @@ func (eb EBService) BookEvent(ctx context.Context, book *model.Book) error {
 		return model.ErrCommon500 // 500
 	}
 
-	// декремент event.availSeats
-	if err := eb.repo.DecrementAvailSeatsByEventID(ctx, tx, book.EventID); err != nil {
-		log.Printf("RID %q Failed to decrement event avail.seats in 'BookEvent': %v", rid, err)
-		return model.ErrCommon500
+	// списание мест у event.availSeats
+	if err := eb.repo.DecreaseAvailSeatsByEventID(ctx, tx, book.EventID, book.Quantity); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNoSeatsAvailable):
+			return err
+		default:
+			log.Printf("RID %q Failed to decrease event avail.seats in 'BookEvent': %v", rid, err)
+			return model.ErrCommon500
+		}
 	}
 	// коммит транзакции
 	if err := tx.Commit(); err != nil {
@@ -159,21 +462,25 @@ func (eb EBService) BookEvent(ctx context.Context, book *model.Book) error {
 
 	committed = true
 
+	if eb.scheduler != nil {
+		eb.scheduler.Schedule(book.OrgID, book.ID, deadline)
+	}
+
 	return nil
 }
 
-func (eb EBService) ConfirmBook(ctx context.Context, bid int, uid int) error {
+func (eb EBService) ConfirmBook(ctx context.Context, bid int, actor model.Actor) error {
 	rid := model.RequestIDFromCtx(ctx)
 
 	if bid < 1 {
 		return model.ErrIncorrectBookID
 	}
-	if uid < 1 {
+	if actor.UserID < 1 {
 		return model.ErrIncorrectUserID
 	}
 
 	// бегин транзакции
-	tx, err := eb.db.BeginTx(ctx, nil)
+	tx, err := eb.txm.BeginTx(ctx)
 	if err != nil {
 		log.Printf("RID %q Failed to begin transaction in 'ConfirmBook': %v", rid, err)
 		return model.ErrCommon500
@@ -188,7 +495,7 @@ func (eb EBService) ConfirmBook(ctx context.Context, bid int, uid int) error {
 	}()
 
 	// проверяем бронь
-	book, err := eb.repo.GetBookByID(ctx, tx, bid)
+	book, err := eb.repo.GetBookByID(ctx, tx, actor.OrgID, bid)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrBookNotFound):
@@ -199,7 +506,7 @@ func (eb EBService) ConfirmBook(ctx context.Context, bid int, uid int) error {
 		}
 	}
 
-	if book.UserID != uid {
+	if book.UserID != actor.UserID {
 		return model.ErrAccessDenied
 	}
 	if book.Status == model.BookStatusCancelled {
@@ -208,6 +515,9 @@ func (eb EBService) ConfirmBook(ctx context.Context, bid int, uid int) error {
 	if book.Status == model.BookStatusConfirmed {
 		return model.ErrBookIsConfirmed
 	}
+	if book.Status == model.BookStatusExpired {
+		return model.ErrExpiredBook
+	}
 	if book.ConfirmDeadline.Before(time.Now().UTC()) {
 		return model.ErrExpiredBook
 	}
@@ -227,18 +537,18 @@ func (eb EBService) ConfirmBook(ctx context.Context, bid int, uid int) error {
 	return nil
 }
 
-func (eb EBService) CancelBook(ctx context.Context, bid int, uid int) error { // не удаляет бронь, а помечает как cancelled и инкрементит availseats
+func (eb EBService) CancelBook(ctx context.Context, bid int, actor model.Actor) error { // не удаляет бронь, а помечает как cancelled и инкрементит availseats
 	rid := model.RequestIDFromCtx(ctx)
 
 	if bid < 1 {
 		return model.ErrIncorrectBookID
 	}
-	if uid < 1 {
+	if actor.UserID < 1 {
 		return model.ErrIncorrectUserID
 	}
 
 	// бегин транзакции
-	tx, err := eb.db.BeginTx(ctx, nil)
+	tx, err := eb.txm.BeginTx(ctx)
 	if err != nil {
 		log.Printf("RID %q Failed to begin transaction in 'CancelBook': %v", rid, err)
 		return model.ErrCommon500
@@ -252,42 +562,52 @@ func (eb EBService) CancelBook(ctx context.Context, bid int, uid int) error { //
 		}
 	}()
 
-	// проверяем бронь
-	book, err := eb.repo.GetBookByID(ctx, tx, bid)
+	// проверяем бронь, залочив сначала её ивент
+	book, event, err := eb.lockBook(ctx, tx, actor.OrgID, bid)
 	if err != nil {
 		switch {
-		case errors.Is(err, model.ErrBookNotFound):
+		case errors.Is(err, model.ErrBookNotFound), errors.Is(err, model.ErrEventNotFound):
 			return err
 		default:
-			log.Printf("RID %q Failed to get book from DB in 'CancelBook': %q", rid, err)
+			log.Printf("RID %q Failed to get book and its event from DB in 'CancelBook': %q", rid, err)
 			return model.ErrCommon500
 		}
 	}
+	// чужую бронь отменяет только тот, кто управляет ивентом
+	if book.UserID != actor.UserID {
+		if err := eb.checkEventAccess(ctx, tx, actor, event); err != nil {
+			switch {
+			case errors.Is(err, model.ErrAccessDenied):
+				return err
+			default:
+				log.Printf("RID %q Failed to check event access in 'CancelBook': %v", rid, err)
+				return model.ErrCommon500
+			}
+		}
+	}
 	if book.Status == model.BookStatusCancelled {
 		return model.ErrBookIsCancelled
 	}
-
-	// получаем ивент чтобы залочить для транзакции
-	_, err = eb.repo.GetEventByID(ctx, tx, book.EventID)
-	if err != nil {
-		switch {
-		case errors.Is(err, model.ErrEventNotFound):
-			return err
-		default:
-			log.Printf("RID %q Failed to get event from DB in 'CancelBook': %q", rid, err)
-			return model.ErrCommon500
-		}
+	if book.Status == model.BookStatusExpired { // место уже возвращено при истечении
+		return model.ErrExpiredBook
 	}
 
 	// отменяем бронь
-	if err := eb.repo.UpdateBookStatus(ctx, tx, bid, model.BookStatusCancelled); err != nil {
+	if err := eb.repo.MarkBookCancelled(ctx, tx, bid); err != nil {
 		log.Printf("RID %q Failed to update book status in DB in 'CancelBook': %v", rid, err)
 		return model.ErrCommon500
 	}
 
-	// инкрементим event.availSeats
-	if err := eb.repo.IncrementAvailSeatsByEventID(ctx, tx, book.EventID); err != nil {
-		log.Printf("RID %q Failed to increment event avail.seats in 'CancelBook': %v", rid, err)
+	// возвращаем места в event.availSeats
+	if err := eb.repo.IncreaseAvailSeatsByEventID(ctx, tx, book.EventID, book.Quantity); err != nil {
+		log.Printf("RID %q Failed to increase event avail.seats in 'CancelBook': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	// отдаем освободившиеся места очереди ожидания
+	promoted, err := eb.promoteWaitlist(ctx, tx, book.OrgID, book.EventID)
+	if err != nil {
+		log.Printf("RID %q Failed to promote waitlist in 'CancelBook': %v", rid, err)
 		return model.ErrCommon500
 	}
 
@@ -297,13 +617,218 @@ func (eb EBService) CancelBook(ctx context.Context, bid int, uid int) error { //
 		return model.ErrCommon500
 	}
 	committed = true
+
+	eb.announcePromotions(ctx, promoted)
 	return nil
 }
 
-func (eb EBService) DeleteEvent(ctx context.Context, eid int, role string) error { // добавить проверку роли пользователя
+// UpdateEvent меняет поля ивента под той же блокировкой строки ивента, что и BookEvent, поэтому пересчет мест не гонится с бронированием
+func (eb EBService) UpdateEvent(ctx context.Context, eid int, upd *model.EventUpdate, actor model.Actor) (*model.Event, error) {
 	rid := model.RequestIDFromCtx(ctx)
 
-	if role != model.RoleAdmin {
+	if !actor.Can(model.PermEventsManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'UpdateEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'UpdateEvent': %v", rid, err)
+			}
+		}
+	}()
+
+	// получаем ивент с блокировкой
+	event, err := eb.repo.GetEventByID(ctx, tx, actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'UpdateEvent': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if err := eb.checkEventAccess(ctx, tx, actor, event); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAccessDenied):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to check event organizers in DB in 'UpdateEvent': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if event.Status != model.EventStatusActual {
+		return nil, model.ErrEventNotEditable
+	}
+
+	if err := applyEventUpdate(event, upd); err != nil {
+		return nil, err // 400/409
+	}
+
+	// апдейтим ивент
+	if err := eb.repo.UpdateEvent(ctx, tx, event); err != nil {
+		log.Printf("RID %q Failed to update event in DB in 'UpdateEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	if err := eb.saveEventLabels(ctx, tx, event, upd.Categories != nil, upd.Tags != nil); err != nil {
+		switch {
+		case errors.Is(err, model.ErrCategoryNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to save event categories and tags in DB in 'UpdateEvent': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if err := eb.repo.LoadEventLabels(ctx, tx, []*model.Event{event}); err != nil {
+		log.Printf("RID %q Failed to get event categories and tags from DB in 'UpdateEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// увеличение вместимости могло освободить места для очереди ожидания
+	promoted, err := eb.promoteWaitlist(ctx, tx, event.OrgID, eid)
+	if err != nil {
+		log.Printf("RID %q Failed to promote waitlist in 'UpdateEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	for _, b := range promoted {
+		event.AvailSeats -= b.Quantity
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'UpdateEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	eb.announcePromotions(ctx, promoted)
+
+	eb.presentEvents(event)
+	return event, nil
+}
+
+// CancelEvent отменяет ивент вместе со всеми его активными бронями в одной транзакции и уведомляет затронутых пользователей
+func (eb EBService) CancelEvent(ctx context.Context, eid int, reason string, actor model.Actor) (*model.Event, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, model.ErrEmptyCancelReason
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'CancelEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'CancelEvent': %v", rid, err)
+			}
+		}
+	}()
+
+	// получаем ивент с блокировкой - новые брони на него будут ждать окончания транзакции
+	event, err := eb.repo.GetEventByID(ctx, tx, actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'CancelEvent': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if err := eb.checkEventAccess(ctx, tx, actor, event); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAccessDenied):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to check event organizers in DB in 'CancelEvent': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	switch event.Status {
+	case model.EventStatusCancelled:
+		return nil, model.ErrEventIsCancelled
+	case model.EventStatusExpired:
+		return nil, model.ErrEventNotEditable
+	}
+
+	// отменяем брони
+	books, err := eb.repo.CancelBooksByEvent(ctx, tx, eid, []string{model.BookStatusCreated, model.BookStatusConfirmed})
+	if err != nil {
+		log.Printf("RID %q Failed to cancel event bookings in DB in 'CancelEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// очередь ожидания больше не нужна
+	if err := eb.repo.DeleteWaitlistByEvent(ctx, tx, eid); err != nil {
+		log.Printf("RID %q Failed to clear event waitlist in DB in 'CancelEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// отменяем ивент
+	if err := eb.repo.CancelEvent(ctx, tx, eid, reason); err != nil {
+		log.Printf("RID %q Failed to cancel event in DB in 'CancelEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'CancelEvent': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	event.Status = model.EventStatusCancelled
+	event.CancelReason = reason
+	event.AvailSeats = event.TotalSeats
+	eb.presentEvents(event)
+
+	// уведомления - только после коммита, по одному на пользователя
+	notified := make(map[int]bool, len(books))
+	for _, b := range books {
+		if notified[b.UserID] {
+			continue
+		}
+		notified[b.UserID] = true
+		eb.notify(ctx, &model.Notification{
+			UserID:  b.UserID,
+			Email:   b.Email,
+			Subject: "Event cancelled",
+			Text:    fmt.Sprintf("Event %q has been cancelled: %s. Your bookings for it are cancelled.", event.Title, reason),
+		})
+	}
+
+	return event, nil
+}
+
+// DeleteEvent удаляет ивент без броней; соорганизатору удаление недоступно - только владельцу или админу
+func (eb EBService) DeleteEvent(ctx context.Context, eid int, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermEventsManage) {
 		return model.ErrAccessDenied
 	}
 	if eid < 1 {
@@ -311,7 +836,7 @@ func (eb EBService) DeleteEvent(ctx context.Context, eid int, role string) error
 	}
 
 	// бегин транзакции
-	tx, err := eb.db.BeginTx(ctx, nil)
+	tx, err := eb.txm.BeginTx(ctx)
 	if err != nil {
 		log.Printf("RID %q Failed to begin transaction in 'DeleteEvent': %v", rid, err)
 		return model.ErrCommon500
@@ -326,7 +851,7 @@ func (eb EBService) DeleteEvent(ctx context.Context, eid int, role string) error
 	}()
 
 	// проверяем данные ивента
-	event, err := eb.repo.GetEventByID(ctx, tx, eid)
+	event, err := eb.repo.GetEventByID(ctx, tx, actor.OrgID, eid)
 	if err != nil {
 		switch {
 		case errors.Is(err, model.ErrEventNotFound):
@@ -336,6 +861,9 @@ func (eb EBService) DeleteEvent(ctx context.Context, eid int, role string) error
 			return model.ErrCommon500
 		}
 	}
+	if err := checkEventOwner(actor, event); err != nil {
+		return err
+	}
 	if event.TotalSeats != event.AvailSeats {
 		return model.ErrEventBusy
 	}
@@ -359,7 +887,7 @@ func (eb EBService) DeleteEvent(ctx context.Context, eid int, role string) error
 
 func (eb EBService) CleanExpiredBooks(ctx context.Context) error {
 	// транзакция - бегин
-	tx, err := eb.db.BeginTx(ctx, nil)
+	tx, err := eb.txm.BeginTx(ctx)
 	if err != nil {
 		log.Println("Failed to begin transaction:", err)
 		return model.ErrCommon500
@@ -373,30 +901,38 @@ func (eb EBService) CleanExpiredBooks(ctx context.Context) error {
 		}
 	}()
 
-	// запрос всех подходящих броней
-	books, err := eb.repo.GetExpiredBooksList(ctx, tx)
+	// запрос всех подходящих броней - без блокировки, по порядку ивентов
+	candidates, err := eb.repo.GetExpiredBooksList(ctx, tx)
 	if err != nil {
 		log.Println("Failed to fetch expired books in 'CleanExpiredBooks':", err)
 		return model.ErrCommon500
 	}
-	if len(books) == 0 {
+	if len(candidates) == 0 {
 		return nil
 	}
 
-	// в цикле проделать декремент ивентов и удаление броней
-	for _, b := range books {
-		// если статус брони cancelled - availSeats уже инкрементирован
-		if b.Status != model.BookStatusCancelled {
-			if err := eb.repo.IncrementAvailSeatsByEventID(ctx, tx, b.EventID); err != nil {
-				log.Println("Failed to decrement event avail.seats in 'CleanExpiredBooks':", err)
-				return model.ErrCommon500
+	// в цикле залочить ивент и бронь, проделать инкремент мест ивента и пометку брони как expired
+	promoted := make([]*model.BookWithUser, 0)
+	expired := 0
+	for _, c := range candidates {
+		b, _, err := eb.lockBook(ctx, tx, c.OrgID, c.ID)
+		if err != nil {
+			if errors.Is(err, model.ErrBookNotFound) { // удалена вместе с пользователем или ивентом
+				continue
 			}
+			log.Println("Failed to lock book in 'CleanExpiredBooks':", err)
+			return model.ErrCommon500
 		}
-
-		if err = eb.repo.DeleteBook(ctx, tx, b.ID); err != nil {
-			log.Println("Failed to delete expired book in 'CleanExpiredBooks':", err)
+		if b.Status != model.BookStatusCreated || b.ConfirmDeadline.After(time.Now().UTC()) { // подтверждена или отменена до блокировки
+			continue
+		}
+		p, err := eb.expireBook(ctx, tx, b)
+		if err != nil {
+			log.Println("Failed to expire book in 'CleanExpiredBooks':", err)
 			return model.ErrCommon500
 		}
+		promoted = append(promoted, p...)
+		expired++
 	}
 
 	// закоммитить транзакцию
@@ -406,35 +942,402 @@ func (eb EBService) CleanExpiredBooks(ctx context.Context) error {
 	}
 
 	committed = true
-	log.Printf("Cleaned %d expired bookings\n", len(books))
+	log.Printf("Expired %d bookings\n", expired)
+
+	eb.announcePromotions(ctx, promoted)
 	return nil
 }
 
-func (eb EBService) GetBooksListByUserID(ctx context.Context, uid int) ([]*model.Book, error) {
-	rid := model.RequestIDFromCtx(ctx)
+// ExpireBook истекает одну бронь организации orgID по сигналу планировщика, если её дедлайн действительно прошел и она не подтверждена
+func (eb EBService) ExpireBook(ctx context.Context, orgID int, bid int) error {
+	// транзакция - бегин
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Println("Failed to begin transaction:", err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("Failed to rollback transaction in 'ExpireBook': %v", err)
+			}
+		}
+	}()
 
-	res, err := eb.repo.GetBooksListByUser(ctx, eb.db, uid)
+	book, _, err := eb.lockBook(ctx, tx, orgID, bid)
 	if err != nil {
 		switch {
-		case errors.Is(err, model.ErrUserNotFound):
-			return nil, err
+		case errors.Is(err, model.ErrBookNotFound): // уже обработана периодической очисткой
+			return nil
 		default:
-			log.Printf("RID %q Failed to get book from DB in 'GetBooksListByUserID': %q", rid, err)
-			return nil, model.ErrCommon500
+			log.Println("Failed to get book in 'ExpireBook':", err)
+			return model.ErrCommon500
 		}
 	}
+	if book.Status != model.BookStatusCreated || book.ConfirmDeadline.After(time.Now().UTC()) {
+		return nil
+	}
 
-	return res, nil
+	promoted, err := eb.expireBook(ctx, tx, book)
+	if err != nil {
+		log.Println("Failed to expire book in 'ExpireBook':", err)
+		return model.ErrCommon500
+	}
+
+	// закоммитить транзакцию
+	if err := tx.Commit(); err != nil {
+		log.Println("Failed to commit transaction in 'ExpireBook':", err)
+		return model.ErrCommon500
+	}
+
+	committed = true
+
+	eb.announcePromotions(ctx, promoted)
+	return nil
+}
+
+// GetPendingBooks - неподтвержденные брони с дедлайнами для восстановления очереди планировщика при старте
+func (eb EBService) GetPendingBooks(ctx context.Context) ([]*model.Book, error) {
+	books, err := eb.repo.GetPendingBooksList(ctx, eb.txm.Executor())
+	if err != nil {
+		log.Println("Failed to fetch pending books in 'GetPendingBooks':", err)
+		return nil, model.ErrCommon500
+	}
+	return books, nil
+}
+
+// lockBook блокирует ивент брони, а затем саму бронь. Ивент всегда блокируется раньше своих броней - так же,
+// как в EventSweeper и при отмене ивента; встречный порядок приводил бы к дедлоку транзакций
+func (eb EBService) lockBook(ctx context.Context, tx repository.Tx, orgID int, bid int) (*model.Book, *model.Event, error) {
+	book, err := eb.repo.GetBookByIDNoLock(ctx, tx, orgID, bid)
+	if err != nil {
+		return nil, nil, err
+	}
+	event, err := eb.repo.GetEventByID(ctx, tx, book.OrgID, book.EventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// до блокировки ивента бронь могли изменить - перечитываем её уже под блокировкой
+	book, err = eb.repo.GetBookByID(ctx, tx, orgID, bid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return book, event, nil
 }
 
-func (eb EBService) GetEventsList(ctx context.Context, role string) ([]*model.Event, error) {
+// expireBook освобождает места истекшей брони, помечает её как expired и отдает места очереди ожидания;
+// бронь и её ивент должны быть заблокированы в tx (lockBook)
+func (eb EBService) expireBook(ctx context.Context, tx repository.Tx, b *model.Book) ([]*model.BookWithUser, error) {
+	if err := eb.repo.IncreaseAvailSeatsByEventID(ctx, tx, b.EventID, b.Quantity); err != nil {
+		return nil, err
+	}
+
+	if err := eb.repo.MarkBookExpired(ctx, tx, b.ID); err != nil {
+		return nil, err
+	}
+
+	return eb.promoteWaitlist(ctx, tx, b.OrgID, b.EventID)
+}
+
+// PurgeOldBooks окончательно удаляет истекшие и отмененные брони старше retention
+func (eb EBService) PurgeOldBooks(ctx context.Context, retention time.Duration) error {
+	n, err := eb.repo.PurgeBooks(ctx, eb.txm.Executor(), time.Now().UTC().Add(-retention))
+	if err != nil {
+		log.Println("Failed to purge old books in 'PurgeOldBooks':", err)
+		return model.ErrCommon500
+	}
+	if n > 0 {
+		log.Printf("Purged %d old bookings\n", n)
+	}
+	return nil
+}
+
+// ExpirePastEvents переводит наступившие ивенты в статус expired и отменяет их неподтвержденные брони с возвратом мест
+func (eb EBService) ExpirePastEvents(ctx context.Context) error {
+	// транзакция - бегин
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Println("Failed to begin transaction:", err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("Failed to rollback transaction in 'ExpirePastEvents': %v", err)
+			}
+		}
+	}()
+
+	// запрос всех наступивших ивентов
+	events, err := eb.repo.GetPastEventsList(ctx, tx)
+	if err != nil {
+		log.Println("Failed to fetch past events in 'ExpirePastEvents':", err)
+		return model.ErrCommon500
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	for _, e := range events {
+		books, err := eb.repo.CancelBooksByEvent(ctx, tx, e.ID, []string{model.BookStatusCreated})
+		if err != nil {
+			log.Println("Failed to cancel unconfirmed books in 'ExpirePastEvents':", err)
+			return model.ErrCommon500
+		}
+		freed := 0
+		for _, b := range books {
+			freed += b.Quantity
+		}
+		if freed > 0 {
+			if err := eb.repo.IncreaseAvailSeatsByEventID(ctx, tx, e.ID, freed); err != nil {
+				log.Println("Failed to increase event avail.seats in 'ExpirePastEvents':", err)
+				return model.ErrCommon500
+			}
+		}
+
+		if err := eb.repo.DeleteWaitlistByEvent(ctx, tx, e.ID); err != nil {
+			log.Println("Failed to clear event waitlist in 'ExpirePastEvents':", err)
+			return model.ErrCommon500
+		}
+
+		if err := eb.repo.UpdateEventStatus(ctx, tx, e.ID, model.EventStatusExpired); err != nil {
+			log.Println("Failed to expire event in 'ExpirePastEvents':", err)
+			return model.ErrCommon500
+		}
+	}
+
+	// закоммитить транзакцию
+	if err := tx.Commit(); err != nil {
+		log.Println("Failed to commit transaction in 'ExpirePastEvents':", err)
+		return model.ErrCommon500
+	}
+
+	committed = true
+	log.Printf("Expired %d past events\n", len(events))
+	return nil
+}
+
+// GetBooksListByUserID - страница броней пользователя в текущей организации и курсор следующей страницы
+// (пустой - страница последняя); filter нормализуется на месте
+func (eb EBService) GetBooksListByUserID(ctx context.Context, filter *model.BookFilter, actor model.Actor) ([]*model.Book, string, error) {
 	rid := model.RequestIDFromCtx(ctx)
 
-	res, err := eb.repo.GetEventsList(ctx, eb.db, role)
+	if err := normalizePageLimit(&filter.Limit); err != nil {
+		return nil, "", err
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = model.SortCreated
+	case model.SortCreated, model.SortCreatedDesc:
+	default:
+		return nil, "", model.ErrIncorrectListFilter
+	}
+	switch filter.Status {
+	case "", model.BookStatusCreated, model.BookStatusConfirmed, model.BookStatusCancelled, model.BookStatusExpired:
+	default:
+		return nil, "", model.ErrIncorrectListFilter
+	}
+	after, err := decodeCursor(filter.Cursor, filter.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+	to, err := dayRangeEnd(filter.From, filter.To)
+	if err != nil {
+		return nil, "", err
+	}
+	filter.OrgID, filter.UserID = actor.OrgID, actor.UserID
+
+	// на одну запись больше страницы - так видно, есть ли следующая
+	query := *filter
+	query.After, query.To, query.Limit = after, to, filter.Limit+1
+
+	res, err := eb.repo.GetBooksListByUser(ctx, eb.txm.Executor(), query)
+	if err != nil {
+		log.Printf("RID %q Failed to get book from DB in 'GetBooksListByUserID': %q", rid, err)
+		return nil, "", model.ErrCommon500
+	}
+
+	next := ""
+	if len(res) > filter.Limit {
+		res = res[:filter.Limit]
+		next = encodeCursor(filter.Sort, model.PageKey{ID: res[len(res)-1].ID})
+	}
+
+	return res, next, nil
+}
+
+// GetEventsList - страница актуальных ивентов и курсор следующей страницы (пустой - страница последняя);
+// админу видны все ивенты, организатору - ещё и свои в любом статусе; filter нормализуется на месте
+func (eb EBService) GetEventsList(ctx context.Context, filter *model.EventFilter, actor model.Actor) ([]*model.Event, string, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if err := normalizePageLimit(&filter.Limit); err != nil {
+		return nil, "", err
+	}
+	switch filter.Sort {
+	case "":
+		filter.Sort = model.SortCreated
+	case model.SortCreated, model.SortCreatedDesc, model.SortDate, model.SortDateDesc:
+	default:
+		return nil, "", model.ErrIncorrectListFilter
+	}
+	switch filter.Status {
+	case "", model.EventStatusActual, model.EventStatusExpired, model.EventStatusCancelled:
+	default:
+		return nil, "", model.ErrIncorrectListFilter
+	}
+	after, err := decodeCursor(filter.Cursor, filter.Sort)
+	if err != nil {
+		return nil, "", err
+	}
+	to, err := dayRangeEnd(filter.From, filter.To)
+	if err != nil {
+		return nil, "", err
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Category = strings.ToLower(strings.TrimSpace(filter.Category))
+	filter.Tag = strings.ToLower(strings.TrimSpace(filter.Tag))
+	filter.OrgID = actor.OrgID
+	filter.AllStatuses = actor.Can(model.PermEventsManageAny)
+	filter.ManagedBy = 0
+	if actor.Can(model.PermEventsManage) {
+		filter.ManagedBy = actor.UserID
+	}
+
+	// на одну запись больше страницы - так видно, есть ли следующая
+	query := *filter
+	query.After, query.To, query.Limit = after, to, filter.Limit+1
+
+	res, err := eb.repo.GetEventsList(ctx, eb.txm.Executor(), query)
 	if err != nil {
 		log.Printf("RID %q Failed to get all events from DB in 'GetEventsList': %v", rid, err)
+		return nil, "", model.ErrCommon500
+	}
+	if err := eb.repo.LoadEventLabels(ctx, eb.txm.Executor(), res); err != nil {
+		log.Printf("RID %q Failed to get events categories and tags from DB in 'GetEventsList': %v", rid, err)
+		return nil, "", model.ErrCommon500
+	}
+
+	next := ""
+	if len(res) > filter.Limit {
+		res = res[:filter.Limit]
+		key := model.PageKey{ID: res[len(res)-1].ID}
+		if filter.Sort == model.SortDate || filter.Sort == model.SortDateDesc {
+			key.Date = res[len(res)-1].StartsAt.Time
+		}
+		next = encodeCursor(filter.Sort, key)
+	}
+
+	eb.presentEvents(res...)
+	return res, next, nil
+}
+
+// SearchEvents - полнотекстовый поиск по названию и описанию, по убыванию релевантности; видимость та же, что у GetEventsList.
+// Страница задается limit/offset: порядок по релевантности не дает устойчивого ключа для курсора
+func (eb EBService) SearchEvents(ctx context.Context, search *model.EventSearch, actor model.Actor) ([]*model.EventSearchResult, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Query == "" || utf8.RuneCountInString(search.Query) > maxSearchQuery {
+		return nil, model.ErrIncorrectSearch
+	}
+	if err := normalizePageLimit(&search.Limit); err != nil {
+		return nil, err
+	}
+	if search.Offset < 0 {
+		return nil, model.ErrIncorrectPagination
+	}
+	search.OrgID = actor.OrgID
+	search.AllStatuses = actor.Can(model.PermEventsManageAny)
+	search.ManagedBy = 0
+	if actor.Can(model.PermEventsManage) {
+		search.ManagedBy = actor.UserID
+	}
+
+	res, err := eb.repo.SearchEvents(ctx, eb.txm.Executor(), *search)
+	if err != nil {
+		log.Printf("RID %q Failed to search events in DB in 'SearchEvents': %v", rid, err)
 		return nil, model.ErrCommon500
 	}
+	events := make([]*model.Event, 0, len(res))
+	for _, r := range res {
+		events = append(events, &r.Event)
+	}
+	if err := eb.repo.LoadEventLabels(ctx, eb.txm.Executor(), events); err != nil {
+		log.Printf("RID %q Failed to get events categories and tags from DB in 'SearchEvents': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	eb.presentEvents(events...)
 
 	return res, nil
 }
+
+// GetEventInfo - ивент со статистикой броней; тем, кто управляет ивентом, ещё и списки броней и соорганизаторов
+func (eb EBService) GetEventInfo(ctx context.Context, eid int, actor model.Actor) (*model.EventInfo, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+
+	event, err := eb.repo.GetEventByIDNoLock(ctx, eb.txm.Executor(), actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'GetEventInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	manager := true
+	if err := eb.checkEventAccess(ctx, eb.txm.Executor(), actor, event); err != nil {
+		if !errors.Is(err, model.ErrAccessDenied) {
+			log.Printf("RID %q Failed to check event organizers in DB in 'GetEventInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+		manager = false
+	}
+	// остальным - только актуальные ивенты, как и в общем списке
+	if !manager && event.Status != model.EventStatusActual {
+		return nil, model.ErrEventNotFound
+	}
+
+	if err := eb.repo.LoadEventLabels(ctx, eb.txm.Executor(), []*model.Event{event}); err != nil {
+		log.Printf("RID %q Failed to get event categories and tags from DB in 'GetEventInfo': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	eb.presentEvents(event)
+
+	stats, err := eb.repo.GetBookStatsByEvent(ctx, eb.txm.Executor(), eid)
+	if err != nil {
+		log.Printf("RID %q Failed to get event bookings stats from DB in 'GetEventInfo': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	info := &model.EventInfo{Event: *event, Stats: *stats}
+
+	if manager {
+		info.Books, err = eb.repo.GetBooksListByEvent(ctx, eb.txm.Executor(), eid)
+		if err != nil {
+			log.Printf("RID %q Failed to get event bookings from DB in 'GetEventInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+		info.Organizers, err = eb.repo.GetEventOrganizers(ctx, eb.txm.Executor(), eid)
+		if err != nil {
+			log.Printf("RID %q Failed to get event organizers from DB in 'GetEventInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	return info, nil
+}
+
+// notify отправляет уведомление; ошибка доставки только логируется и не откатывает уже выполненную операцию
+func (eb EBService) notify(ctx context.Context, n *model.Notification) {
+	if err := eb.notifier.Notify(ctx, n); err != nil {
+		log.Printf("RID %q Failed to notify userID=%d: %v", model.RequestIDFromCtx(ctx), n.UserID, err)
+	}
+}
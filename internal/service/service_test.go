@@ -0,0 +1,332 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+	"github.com/UnendingLoop/EventBooker/internal/repository/ebmemory"
+)
+
+const testOrg = 1 // организация по умолчанию из ebmemory.NewStore
+
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(context.Context, *model.Notification) error { return nil }
+
+// testEnv - сервис поверх in-memory хранилища и фикстуры для него
+type testEnv struct {
+	t     *testing.T
+	ctx   context.Context
+	svc   *EBService
+	repo  repository.EBRepo
+	store *ebmemory.Store
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	repo := ebmemory.NewMemoryRepo()
+	store := ebmemory.NewStore()
+	return &testEnv{
+		t:     t,
+		ctx:   context.Background(),
+		svc:   NewEBService(repo, store, nil, nopNotifier{}, nil, Options{}),
+		repo:  repo,
+		store: store,
+	}
+}
+
+// user - участник организации с ролью role
+func (e *testEnv) user(email string, role string) model.Actor {
+	e.t.Helper()
+	u := &model.User{Email: email, Name: "Test", Surname: "User"}
+	if err := e.repo.CreateUser(e.ctx, e.store, u); err != nil {
+		e.t.Fatalf("create user: %v", err)
+	}
+	if err := e.repo.AddOrgMember(e.ctx, e.store, testOrg, u.ID, role); err != nil {
+		e.t.Fatalf("add org member: %v", err)
+	}
+	return model.Actor{UserID: u.ID, OrgID: testOrg, Role: role}
+}
+
+// event - актуальный ивент, начинающийся через сутки
+func (e *testEnv) event(seats int, maxPerBook int, owner int) *model.Event {
+	e.t.Helper()
+	ev := &model.Event{
+		Title:      "Concert",
+		Status:     model.EventStatusActual,
+		StartsAt:   model.CustomTime{Time: time.Now().Add(24 * time.Hour)},
+		TimeZone:   "UTC",
+		TotalSeats: seats,
+		AvailSeats: seats,
+		BookWindow: 600,
+		MaxPerBook: maxPerBook,
+		OwnerID:    owner,
+		OrgID:      testOrg,
+	}
+	if err := e.repo.CreateEvent(e.ctx, e.store, ev); err != nil {
+		e.t.Fatalf("create event: %v", err)
+	}
+	return ev
+}
+
+// book - бронь в обход BookEvent, чтобы задать любой статус и дедлайн; места списываются как в BookEvent
+func (e *testEnv) book(eventID int, userID int, quantity int, status string, deadline time.Time) *model.Book {
+	e.t.Helper()
+	b := &model.Book{EventID: eventID, UserID: userID, OrgID: testOrg, Quantity: quantity, Status: status, ConfirmDeadline: &deadline}
+	if err := e.repo.CreateBook(e.ctx, e.store, b); err != nil {
+		e.t.Fatalf("create book: %v", err)
+	}
+	if status == model.BookStatusCreated || status == model.BookStatusConfirmed {
+		if err := e.repo.DecreaseAvailSeatsByEventID(e.ctx, e.store, eventID, quantity); err != nil {
+			e.t.Fatalf("decrease seats: %v", err)
+		}
+	}
+	return b
+}
+
+func (e *testEnv) availSeats(eventID int) int {
+	e.t.Helper()
+	ev, err := e.repo.GetEventByIDNoLock(e.ctx, e.store, testOrg, eventID)
+	if err != nil {
+		e.t.Fatalf("get event: %v", err)
+	}
+	return ev.AvailSeats
+}
+
+func (e *testEnv) bookStatus(bookID int) string {
+	e.t.Helper()
+	b, err := e.repo.GetBookByID(e.ctx, e.store, testOrg, bookID)
+	if err != nil {
+		e.t.Fatalf("get book: %v", err)
+	}
+	return b.Status
+}
+
+func TestBookEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		seats     int
+		booked    int // мест уже занято другими бронями
+		quantity  int
+		prepare   func(e *testEnv, ev *model.Event)
+		wantErr   error
+		wantAvail int
+	}{
+		{name: "books free seats", seats: 5, quantity: 2, wantAvail: 3},
+		{name: "one seat by default", seats: 5, quantity: 0, wantAvail: 4},
+		{name: "last seats", seats: 3, booked: 1, quantity: 2, wantAvail: 0},
+		{name: "overbooking", seats: 3, booked: 2, quantity: 2, wantErr: model.ErrNoSeatsAvailable, wantAvail: 1},
+		{name: "sold out", seats: 2, booked: 2, quantity: 1, wantErr: model.ErrNoSeatsAvailable, wantAvail: 0},
+		{name: "more than max per book", seats: 10, quantity: 4, wantErr: model.ErrTooManySeatsPerBook, wantAvail: 10},
+		{name: "negative quantity", seats: 5, quantity: -1, wantErr: model.ErrIncorrectQuantity, wantAvail: 5},
+		{
+			name: "event is not actual", seats: 5, quantity: 1, wantErr: model.ErrExpiredEvent, wantAvail: 5,
+			prepare: func(e *testEnv, ev *model.Event) {
+				if err := e.repo.UpdateEventStatus(e.ctx, e.store, ev.ID, model.EventStatusCancelled); err != nil {
+					e.t.Fatalf("update event status: %v", err)
+				}
+			},
+		},
+		{
+			name: "event already started", seats: 5, quantity: 1, wantErr: model.ErrBookingClosed, wantAvail: 5,
+			prepare: func(e *testEnv, ev *model.Event) {
+				ev.StartsAt = model.CustomTime{Time: time.Now().Add(-time.Minute)}
+				if err := e.repo.UpdateEvent(e.ctx, e.store, ev); err != nil {
+					e.t.Fatalf("update event: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			owner := e.user("owner@test.io", model.RoleOrganizer)
+			guest := e.user("guest@test.io", model.RoleUser)
+			ev := e.event(tt.seats, 3, owner.UserID)
+			if tt.booked > 0 {
+				other := e.user("other@test.io", model.RoleUser)
+				e.book(ev.ID, other.UserID, tt.booked, model.BookStatusConfirmed, time.Now().Add(time.Hour))
+			}
+			if tt.prepare != nil {
+				tt.prepare(e, ev)
+			}
+
+			book := &model.Book{EventID: ev.ID, UserID: guest.UserID, OrgID: testOrg, Quantity: tt.quantity}
+			err := e.svc.BookEvent(e.ctx, book)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BookEvent() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := e.availSeats(ev.ID); got != tt.wantAvail {
+				t.Errorf("avail seats = %d, want %d", got, tt.wantAvail)
+			}
+			if tt.wantErr == nil && (book.ID == 0 || book.Status != model.BookStatusCreated || book.ConfirmDeadline == nil) {
+				t.Errorf("book is not created properly: %+v", book)
+			}
+		})
+	}
+}
+
+func TestConfirmBook(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		deadline   time.Duration // относительно текущего момента
+		byOther    bool
+		wantErr    error
+		wantStatus string
+	}{
+		{name: "confirms before deadline", status: model.BookStatusCreated, deadline: time.Hour, wantStatus: model.BookStatusConfirmed},
+		{name: "after deadline", status: model.BookStatusCreated, deadline: -time.Second, wantErr: model.ErrExpiredBook, wantStatus: model.BookStatusCreated},
+		{name: "already expired", status: model.BookStatusExpired, deadline: -time.Hour, wantErr: model.ErrExpiredBook, wantStatus: model.BookStatusExpired},
+		{name: "already confirmed", status: model.BookStatusConfirmed, deadline: time.Hour, wantErr: model.ErrBookIsConfirmed, wantStatus: model.BookStatusConfirmed},
+		{name: "cancelled", status: model.BookStatusCancelled, deadline: time.Hour, wantErr: model.ErrBookIsCancelled, wantStatus: model.BookStatusCancelled},
+		{name: "someone else's book", status: model.BookStatusCreated, deadline: time.Hour, byOther: true, wantErr: model.ErrAccessDenied, wantStatus: model.BookStatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			owner := e.user("owner@test.io", model.RoleOrganizer)
+			guest := e.user("guest@test.io", model.RoleUser)
+			ev := e.event(5, 3, owner.UserID)
+			b := e.book(ev.ID, guest.UserID, 1, tt.status, time.Now().Add(tt.deadline))
+
+			actor := guest
+			if tt.byOther {
+				actor = e.user("other@test.io", model.RoleUser)
+			}
+			err := e.svc.ConfirmBook(e.ctx, b.ID, actor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmBook() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := e.bookStatus(b.ID); got != tt.wantStatus {
+				t.Errorf("book status = %q, want %q", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCancelBook(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		actor     string // guest - владелец брони, other - другой участник, owner - владелец ивента, admin
+		twice     bool
+		wantErr   error
+		wantAvail int
+	}{
+		{name: "own created book", status: model.BookStatusCreated, actor: "guest", wantAvail: 5},
+		{name: "own confirmed book", status: model.BookStatusConfirmed, actor: "guest", wantAvail: 5},
+		{name: "double cancel returns seats once", status: model.BookStatusCreated, actor: "guest", twice: true, wantErr: model.ErrBookIsCancelled, wantAvail: 5},
+		{name: "expired book", status: model.BookStatusExpired, actor: "guest", wantErr: model.ErrExpiredBook, wantAvail: 5},
+		{name: "someone else's book", status: model.BookStatusCreated, actor: "other", wantErr: model.ErrAccessDenied, wantAvail: 3},
+		{name: "by event owner", status: model.BookStatusCreated, actor: "owner", wantAvail: 5},
+		{name: "by admin", status: model.BookStatusConfirmed, actor: "admin", wantAvail: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			actors := map[string]model.Actor{
+				"owner": e.user("owner@test.io", model.RoleOrganizer),
+				"guest": e.user("guest@test.io", model.RoleUser),
+				"other": e.user("other@test.io", model.RoleUser),
+				"admin": e.user("admin@test.io", model.RoleAdmin),
+			}
+			ev := e.event(5, 3, actors["owner"].UserID)
+			b := e.book(ev.ID, actors["guest"].UserID, 2, tt.status, time.Now().Add(time.Hour))
+
+			err := e.svc.CancelBook(e.ctx, b.ID, actors[tt.actor])
+			if tt.twice {
+				if err != nil {
+					t.Fatalf("first CancelBook() error = %v", err)
+				}
+				err = e.svc.CancelBook(e.ctx, b.ID, actors[tt.actor])
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CancelBook() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := e.availSeats(ev.ID); got != tt.wantAvail {
+				t.Errorf("avail seats = %d, want %d", got, tt.wantAvail)
+			}
+		})
+	}
+}
+
+func TestCleanExpiredBooks(t *testing.T) {
+	e := newTestEnv(t)
+	owner := e.user("owner@test.io", model.RoleOrganizer)
+	guest := e.user("guest@test.io", model.RoleUser)
+	ev := e.event(10, 3, owner.UserID)
+
+	expired := e.book(ev.ID, guest.UserID, 3, model.BookStatusCreated, time.Now().Add(-time.Minute))
+	pending := e.book(ev.ID, guest.UserID, 2, model.BookStatusCreated, time.Now().Add(time.Hour))
+	confirmed := e.book(ev.ID, guest.UserID, 1, model.BookStatusConfirmed, time.Now().Add(-time.Minute))
+
+	if err := e.svc.CleanExpiredBooks(e.ctx); err != nil {
+		t.Fatalf("CleanExpiredBooks() error = %v", err)
+	}
+	// места возвращаются только за истекшую бронь
+	if got := e.availSeats(ev.ID); got != 7 {
+		t.Errorf("avail seats = %d, want 7", got)
+	}
+	for _, tt := range []struct {
+		book *model.Book
+		want string
+	}{
+		{expired, model.BookStatusExpired},
+		{pending, model.BookStatusCreated},
+		{confirmed, model.BookStatusConfirmed},
+	} {
+		if got := e.bookStatus(tt.book.ID); got != tt.want {
+			t.Errorf("book %d status = %q, want %q", tt.book.ID, got, tt.want)
+		}
+	}
+
+	// повторный проход ничего не находит и не возвращает места второй раз
+	if err := e.svc.CleanExpiredBooks(e.ctx); err != nil {
+		t.Fatalf("second CleanExpiredBooks() error = %v", err)
+	}
+	if got := e.availSeats(ev.ID); got != 7 {
+		t.Errorf("avail seats after second run = %d, want 7", got)
+	}
+}
+
+func TestPurgeOldBooks(t *testing.T) {
+	const retention = 50 * time.Millisecond
+	e := newTestEnv(t)
+	owner := e.user("owner@test.io", model.RoleOrganizer)
+	guest := e.user("guest@test.io", model.RoleUser)
+	ev := e.event(10, 3, owner.UserID)
+
+	old := e.book(ev.ID, guest.UserID, 1, model.BookStatusCreated, time.Now().Add(time.Hour))
+	active := e.book(ev.ID, guest.UserID, 1, model.BookStatusConfirmed, time.Now().Add(time.Hour))
+	time.Sleep(2 * retention)
+
+	// бронь старше срока хранения, но отмененная только что, остается
+	if err := e.svc.CancelBook(e.ctx, old.ID, guest); err != nil {
+		t.Fatalf("CancelBook() error = %v", err)
+	}
+	if err := e.svc.PurgeOldBooks(e.ctx, retention); err != nil {
+		t.Fatalf("PurgeOldBooks() error = %v", err)
+	}
+	if got := e.bookStatus(old.ID); got != model.BookStatusCancelled {
+		t.Fatalf("just cancelled book status = %q, want it kept as cancelled", got)
+	}
+
+	time.Sleep(2 * retention)
+	if err := e.svc.PurgeOldBooks(e.ctx, retention); err != nil {
+		t.Fatalf("second PurgeOldBooks() error = %v", err)
+	}
+	if _, err := e.repo.GetBookByID(e.ctx, e.store, testOrg, old.ID); !errors.Is(err, model.ErrBookNotFound) {
+		t.Errorf("book cancelled before retention: get error = %v, want ErrBookNotFound", err)
+	}
+	if got := e.bookStatus(active.ID); got != model.BookStatusConfirmed {
+		t.Errorf("active book status = %q, want it kept", got)
+	}
+}
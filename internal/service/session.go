@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// RefreshSession обменивает refresh-токен на новую пару токенов. Refresh-токен одноразовый:
+// при каждом обмене он ротируется, старый перестает действовать.
+func (eb EBService) RefreshSession(ctx context.Context, refresh string) (*model.AuthTokens, *model.User, error) {
+	return eb.rotateSession(ctx, refresh, 0, "RefreshSession")
+}
+
+// SwitchOrganization переводит сессию refresh-токена в другую организацию пользователя и выдает новую пару токенов
+// с ролью в ней; прочие сессии пользователя остаются в своих организациях
+func (eb EBService) SwitchOrganization(ctx context.Context, refresh string, orgID int) (*model.AuthTokens, *model.User, error) {
+	if orgID < 1 {
+		return nil, nil, model.ErrOrgNotFound
+	}
+	return eb.rotateSession(ctx, refresh, orgID, "SwitchOrganization")
+}
+
+// rotateSession - общая часть обмена и переключения: ротация refresh-токена и выпуск access-токена
+// для организации orgID (0 - текущая организация сессии)
+func (eb EBService) rotateSession(ctx context.Context, refresh string, orgID int, op string) (*model.AuthTokens, *model.User, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if refresh == "" {
+		return nil, nil, model.ErrInvalidRefreshToken
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in '%s': %v", rid, op, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in '%s': %v", rid, op, err)
+			}
+		}
+	}()
+
+	// получаем сессию с блокировкой - один refresh-токен нельзя обменять дважды
+	session, err := eb.repo.GetSessionByTokenHash(ctx, tx, hashToken(refresh))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrSessionNotFound):
+			return nil, nil, model.ErrInvalidRefreshToken
+		default:
+			log.Printf("RID %q Failed to get session from DB in '%s': %v", rid, op, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	if !sessionActive(session) {
+		return nil, nil, model.ErrInvalidRefreshToken
+	}
+
+	// роль и имейл берем из БД - они могли измениться с момента входа
+	user, err := eb.repo.GetUserByID(ctx, tx, session.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, nil, model.ErrInvalidRefreshToken
+		default:
+			log.Printf("RID %q Failed to get user from DB in '%s': %v", rid, op, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, model.ErrUserDisabled
+	}
+
+	switching := orgID != 0 && orgID != session.OrgID
+	if orgID == 0 {
+		orgID = session.OrgID
+	}
+	user.Role, err = eb.repo.GetOrgMemberRole(ctx, tx, orgID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotOrgMember) && switching:
+			return nil, nil, err // 404
+		case errors.Is(err, model.ErrNotOrgMember): // исключен из организации сессии
+			return nil, nil, model.ErrInvalidRefreshToken
+		default:
+			log.Printf("RID %q Failed to get user role in organization from DB in '%s': %v", rid, op, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+	if switching {
+		if err := eb.repo.SetSessionOrg(ctx, tx, session.ID, orgID); err != nil {
+			log.Printf("RID %q Failed to switch session organization in DB in '%s': %v", rid, op, err)
+			return nil, nil, model.ErrCommon500
+		}
+	}
+
+	refresh, err = newToken()
+	if err != nil {
+		log.Printf("RID %q Failed to generate refresh token in '%s': %v", rid, op, err)
+		return nil, nil, model.ErrCommon500
+	}
+	expires := time.Now().UTC().Add(eb.opts.SessionTTL)
+	if err := eb.repo.RotateSessionToken(ctx, tx, session.ID, hashToken(refresh), expires); err != nil {
+		log.Printf("RID %q Failed to rotate session token in DB in '%s': %v", rid, op, err)
+		return nil, nil, model.ErrCommon500
+	}
+
+	access, err := eb.jwtManager.Generate(user.ID, session.ID, orgID, user.Email, user.Role, session.MFA)
+	if err != nil {
+		return nil, nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in '%s': %v", rid, op, err)
+		return nil, nil, model.ErrCommon500
+	}
+	committed = true
+
+	return &model.AuthTokens{Access: access, Refresh: refresh, RefreshExpires: expires}, user, nil
+}
+
+// Logout отзывает сессию refresh-токена вместе со всеми выданными по ней access-токенами.
+// Повторный выход или выход с уже недействительным токеном не считается ошибкой.
+func (eb EBService) Logout(ctx context.Context, refresh string) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if refresh == "" {
+		return nil
+	}
+
+	session, err := eb.repo.GetSessionByTokenHash(ctx, eb.txm.Executor(), hashToken(refresh))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrSessionNotFound):
+			return nil
+		default:
+			log.Printf("RID %q Failed to get session from DB in 'Logout': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.RevokeSession(ctx, eb.txm.Executor(), session.ID); err != nil {
+		log.Printf("RID %q Failed to revoke session in DB in 'Logout': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	return nil
+}
+
+// LogoutAll отзывает все сессии пользователя - "выйти на всех устройствах"
+func (eb EBService) LogoutAll(ctx context.Context, uid int) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+
+	if err := eb.repo.RevokeSessionsByUser(ctx, eb.txm.Executor(), uid); err != nil {
+		log.Printf("RID %q Failed to revoke user sessions in DB in 'LogoutAll': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	return nil
+}
+
+// CheckSession используется в RequireAuth: access-токен действует, только пока жива его сессия
+func (eb EBService) CheckSession(ctx context.Context, sid int) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if sid < 1 { // токен выпущен до появления сессий
+		return model.ErrSessionRevoked
+	}
+
+	session, err := eb.repo.GetSessionByID(ctx, eb.txm.Executor(), sid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrSessionNotFound):
+			return model.ErrSessionRevoked
+		default:
+			log.Printf("RID %q Failed to get session from DB in 'CheckSession': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+	if !sessionActive(session) {
+		return model.ErrSessionRevoked
+	}
+
+	return nil
+}
+
+// startSession открывает новую сессию пользователя в организации orgID и выпускает для неё пару токенов;
+// orgID 0 - первая организация пользователя, user.Role заполняется ролью в ней; mfa - пройдена проверка второго фактора
+func (eb EBService) startSession(ctx context.Context, exec repository.Executor, user *model.User, orgID int, mfa bool) (*model.AuthTokens, error) {
+	if orgID == 0 {
+		orgs, err := eb.repo.GetUserOrganizations(ctx, exec, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(orgs) == 0 {
+			return nil, model.ErrNoOrgMembership
+		}
+		orgID, user.Role = orgs[0].ID, orgs[0].Role
+	} else {
+		role, err := eb.repo.GetOrgMemberRole(ctx, exec, orgID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		user.Role = role
+	}
+
+	refresh, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &model.Session{
+		UserID:    user.ID,
+		OrgID:     orgID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: time.Now().UTC().Add(eb.opts.SessionTTL),
+		MFA:       mfa,
+	}
+	if err := eb.repo.CreateSession(ctx, exec, session); err != nil {
+		return nil, err
+	}
+
+	access, err := eb.jwtManager.Generate(user.ID, session.ID, orgID, user.Email, user.Role, mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuthTokens{Access: access, Refresh: refresh, RefreshExpires: session.ExpiresAt}, nil
+}
+
+func sessionActive(s *model.Session) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now().UTC())
+}
+
+// newToken - криптостойкий случайный токен (refresh-токены, приглашения)
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken - в БД хранится только хэш refresh-токена, утечка таблицы не дает доступа к сессиям
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,17 +1,31 @@
 package service
 
 import (
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	maxEventText       = 200 // площадка, адрес и контакт
+	maxCoverURL        = 2048
+	maxEventCategories = 10
+	maxEventTags       = 20
+	maxTag             = 50
+)
+
+// dummyPassHash - хэш для сравнения при входе с неизвестным имейлом, чтобы время ответа не выдавало существование аккаунта
+var dummyPassHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password for timing"), bcrypt.DefaultCost)
+
 func validateNormalizeUser(u *model.User) error {
-	// Проверка роли
-	if u.Role != model.RoleAdmin && u.Role != model.RoleUser {
+	// Проверка роли - её выставляет сервис, а не клиент
+	if !model.ValidRole(u.Role) {
 		return model.ErrIncorrectUserRole
 	}
 	// Проверка имейл
@@ -36,12 +50,37 @@ func validateNormalizeUser(u *model.User) error {
 	}
 
 	// Генерация хэша из пароля
-	passHash, _ := bcrypt.GenerateFromPassword([]byte(u.PassHash), bcrypt.DefaultCost)
-	u.PassHash = string(passHash)
+	if err := validatePassword(u.PassHash); err != nil {
+		return err
+	}
+	passHash, err := hashPassword(u.PassHash)
+	if err != nil {
+		return err
+	}
+	u.PassHash = passHash
 
 	return nil
 }
 
+// validatePassword - bcrypt не принимает пароли длиннее 72 байт
+func validatePassword(password string) error {
+	if password == "" {
+		return model.ErrEmptyPassword
+	}
+	if len(password) > 72 {
+		return model.ErrPasswordTooLong
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(passHash), nil
+}
+
 func normalizePhone(s string) string {
 	s = strings.TrimSpace(s)
 	s = strings.ReplaceAll(s, " ", "")
@@ -55,16 +94,200 @@ func normalizePhone(s string) string {
 }
 
 func validateNormalizeEvent(event *model.Event) error {
-	if event.Title == "" || event.TotalSeats <= 0 || event.BookWindow <= 0 {
+	if event.Title == "" || event.TotalSeats <= 0 || event.BookWindow <= 0 || event.MaxPerBook < 0 {
 		return model.ErrEmptyEventInfo
 	}
-	if event.EventDate.UTC().Before(time.Now().UTC()) {
-		return model.ErrIncorrectEventTime
+	if event.MaxPerBook == 0 { // по умолчанию - одно место на бронь
+		event.MaxPerBook = 1
+	}
+	if err := normalizeEventTime(event, event.EventDate); err != nil {
+		return err
 	}
 	now := time.Now().UTC()
 	event.Created = &now
 	event.AvailSeats = event.TotalSeats
 	event.Status = model.EventStatusActual
 
+	return validateNormalizeEventMeta(event)
+}
+
+// normalizeEventTime - часовой пояс ивента (по умолчанию UTC) и привязка к нему времени без смещения;
+// legacyDate - дата из устаревшего поля eventdate (конец этого дня), она используется, только если не задан starts_at.
+// Начало должно быть в будущем, окончание - позже начала
+func normalizeEventTime(event *model.Event, legacyDate string) error {
+	event.TimeZone = strings.TrimSpace(event.TimeZone)
+	if event.TimeZone == "" {
+		event.TimeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(event.TimeZone)
+	if err != nil || event.TimeZone == "Local" { // Local - пояс сервера, а не ивента
+		return model.ErrIncorrectTimeZone
+	}
+
+	if event.StartsAt.IsZero() && legacyDate != "" {
+		date, err := time.ParseInLocation(time.DateOnly, legacyDate, loc)
+		if err != nil {
+			return model.ErrIncorrectEventTime
+		}
+		// как до появления starts_at: ивент по дате длится до конца дня, поэтому сегодняшняя дата допустима
+		event.StartsAt = model.CustomTime{Time: date.AddDate(0, 0, 1).Add(-time.Second)}
+	}
+	if event.StartsAt.IsZero() {
+		return model.ErrIncorrectEventTime
+	}
+	// время без смещения сравнивается с текущим только после привязки к поясу ивента
+	event.StartsAt.InZone(loc)
+	if event.StartsAt.Before(time.Now()) {
+		return model.ErrIncorrectEventTime
+	}
+	if event.EndsAt != nil && event.EndsAt.IsZero() {
+		event.EndsAt = nil
+	}
+	if event.EndsAt != nil {
+		event.EndsAt.InZone(loc)
+		if !event.EndsAt.After(event.StartsAt.Time) {
+			return model.ErrIncorrectEventTime
+		}
+	}
+	event.EventDate = ""
 	return nil
 }
+
+// validateNormalizeEventMeta - метаданные ивента для посетителей; категории и теги приводятся
+// к нижнему регистру и очищаются от повторов, поэтому фильтр по ним не зависит от написания
+func validateNormalizeEventMeta(event *model.Event) error {
+	event.Venue = strings.TrimSpace(event.Venue)
+	event.Address = strings.TrimSpace(event.Address)
+	event.Contact = strings.TrimSpace(event.Contact)
+	event.CoverURL = strings.TrimSpace(event.CoverURL)
+	for _, text := range []string{event.Venue, event.Address, event.Contact} {
+		if utf8.RuneCountInString(text) > maxEventText {
+			return model.ErrIncorrectEventMeta
+		}
+	}
+	if event.CoverURL != "" {
+		u, err := url.Parse(event.CoverURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(event.CoverURL) > maxCoverURL {
+			return model.ErrIncorrectEventMeta
+		}
+	}
+
+	event.Categories = normalizeLabels(event.Categories)
+	if len(event.Categories) > maxEventCategories {
+		return model.ErrIncorrectEventMeta
+	}
+	for _, slug := range event.Categories {
+		if !slugPattern.MatchString(slug) {
+			return model.ErrCategoryNotFound
+		}
+	}
+	event.Tags = normalizeLabels(event.Tags)
+	if len(event.Tags) > maxEventTags {
+		return model.ErrIncorrectEventMeta
+	}
+	for _, tag := range event.Tags {
+		if utf8.RuneCountInString(tag) > maxTag {
+			return model.ErrIncorrectEventMeta
+		}
+	}
+
+	return nil
+}
+
+// normalizeLabels - нижний регистр, без пробелов по краям, пустых значений и повторов, по алфавиту
+func normalizeLabels(labels []string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	res := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			res = append(res, l)
+		}
+	}
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// applyEventUpdate валидирует и применяет частичное обновление к заблокированному ивенту.
+// Места, занятые активными бронями (total - avail), сохраняются при изменении вместимости.
+func applyEventUpdate(event *model.Event, upd *model.EventUpdate) error {
+	timeChanged := upd.StartsAt != nil || upd.EndsAt != nil || upd.TimeZone != nil || upd.EventDate != nil
+	if upd.Title == nil && upd.Descr == nil && !timeChanged && upd.TotalSeats == nil && upd.BookWindow == nil && upd.MaxPerBook == nil &&
+		upd.Venue == nil && upd.Address == nil && upd.Contact == nil && upd.CoverURL == nil && upd.Categories == nil && upd.Tags == nil {
+		return model.ErrEmptyEventUpdate
+	}
+
+	if upd.Title != nil {
+		if strings.TrimSpace(*upd.Title) == "" {
+			return model.ErrEmptyEventInfo
+		}
+		event.Title = *upd.Title
+	}
+	if upd.Descr != nil {
+		event.Descr = *upd.Descr
+	}
+	if timeChanged {
+		// смена только пояса не сдвигает моменты начала и окончания - пояс влияет на показ и на новое время без смещения
+		legacyDate := ""
+		if upd.TimeZone != nil {
+			event.TimeZone = *upd.TimeZone
+		}
+		switch {
+		case upd.StartsAt != nil:
+			event.StartsAt = *upd.StartsAt
+		case upd.EventDate != nil:
+			event.StartsAt, legacyDate = model.CustomTime{}, *upd.EventDate
+		}
+		if upd.EndsAt != nil {
+			ends := *upd.EndsAt
+			event.EndsAt = &ends
+		}
+		if err := normalizeEventTime(event, legacyDate); err != nil {
+			return err
+		}
+	}
+	if upd.BookWindow != nil {
+		if *upd.BookWindow <= 0 {
+			return model.ErrEmptyEventInfo
+		}
+		event.BookWindow = *upd.BookWindow
+	}
+	if upd.MaxPerBook != nil {
+		if *upd.MaxPerBook <= 0 {
+			return model.ErrEmptyEventInfo
+		}
+		event.MaxPerBook = *upd.MaxPerBook
+	}
+	if upd.TotalSeats != nil {
+		if *upd.TotalSeats <= 0 {
+			return model.ErrEmptyEventInfo
+		}
+		booked := event.TotalSeats - event.AvailSeats
+		if *upd.TotalSeats < booked {
+			return model.ErrSeatsBelowBooked
+		}
+		event.TotalSeats = *upd.TotalSeats
+		event.AvailSeats = event.TotalSeats - booked
+	}
+	if upd.Venue != nil {
+		event.Venue = *upd.Venue
+	}
+	if upd.Address != nil {
+		event.Address = *upd.Address
+	}
+	if upd.Contact != nil {
+		event.Contact = *upd.Contact
+	}
+	if upd.CoverURL != nil {
+		event.CoverURL = *upd.CoverURL
+	}
+	if upd.Categories != nil {
+		event.Categories = *upd.Categories
+	}
+	if upd.Tags != nil {
+		event.Tags = *upd.Tags
+	}
+
+	return validateNormalizeEventMeta(event)
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// GetUsersList - постраничный поиск участников организации админа; filter нормализуется на месте (лимит по умолчанию)
+func (eb EBService) GetUsersList(ctx context.Context, filter *model.UserFilter, actor model.Actor) ([]*model.User, int, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermUsersManage) {
+		return nil, 0, model.ErrAccessDenied
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultPageLimit
+	}
+	if filter.Limit < 1 || filter.Limit > maxPageLimit || filter.Offset < 0 {
+		return nil, 0, model.ErrIncorrectPagination
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.OrgID = actor.OrgID
+
+	users, total, err := eb.repo.GetUsersList(ctx, eb.txm.Executor(), *filter)
+	if err != nil {
+		log.Printf("RID %q Failed to get users list from DB in 'GetUsersList': %v", rid, err)
+		return nil, 0, model.ErrCommon500
+	}
+
+	return users, total, nil
+}
+
+// GetUserInfo - участник организации с историей броней в ней для админа
+func (eb EBService) GetUserInfo(ctx context.Context, uid int, actor model.Actor) (*model.UserInfo, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermUsersManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, eb.txm.Executor(), uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get user from DB in 'GetUserInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	user.Role, err = eb.orgMemberRole(ctx, eb.txm.Executor(), actor.OrgID, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get user role in organization from DB in 'GetUserInfo': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	books, err := eb.repo.GetBooksListByUser(ctx, eb.txm.Executor(), model.BookFilter{OrgID: actor.OrgID, UserID: uid})
+	if err != nil {
+		log.Printf("RID %q Failed to get user bookings from DB in 'GetUserInfo': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	return &model.UserInfo{User: user, Books: books}, nil
+}
+
+// ChangeUserRole меняет роль участника в организации админа; роль зашита в выданные access-токены,
+// поэтому его сессии в этой организации отзываются
+func (eb EBService) ChangeUserRole(ctx context.Context, uid int, newRole string, actor model.Actor) (*model.User, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermUsersManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+	if uid == actor.UserID { // заодно гарантирует, что в системе останется хотя бы один админ
+		return nil, model.ErrSelfModification
+	}
+	if !model.ValidRole(newRole) {
+		return nil, model.ErrIncorrectUserRole
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'ChangeUserRole': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'ChangeUserRole': %v", rid, err)
+			}
+		}
+	}()
+
+	if err := eb.repo.UpdateOrgMemberRole(ctx, tx, actor.OrgID, uid, newRole); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotOrgMember): // участники других организаций админу не видны
+			return nil, model.ErrUserNotFound
+		default:
+			log.Printf("RID %q Failed to update user role in DB in 'ChangeUserRole': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.RevokeOrgSessions(ctx, tx, actor.OrgID, uid); err != nil {
+		log.Printf("RID %q Failed to revoke user sessions in DB in 'ChangeUserRole': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, tx, uid)
+	if err != nil {
+		log.Printf("RID %q Failed to get user from DB in 'ChangeUserRole': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	user.Role = newRole
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'ChangeUserRole': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	return user, nil
+}
+
+// SetUserDisabled блокирует или разблокирует аккаунт. При блокировке все сессии пользователя отзываются
+// в той же транзакции - RequireAuth перестает пускать его сразу, а вход и refresh отклоняются; из очередей ожидания он уходит.
+// Аккаунт общий для всех организаций, поэтому управлять им может только админ единственной организации пользователя.
+func (eb EBService) SetUserDisabled(ctx context.Context, uid int, disabled bool, actor model.Actor) (*model.User, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermUsersManage) {
+		return nil, model.ErrAccessDenied
+	}
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+	if uid == actor.UserID {
+		return nil, model.ErrSelfModification
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'SetUserDisabled': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'SetUserDisabled': %v", rid, err)
+			}
+		}
+	}()
+
+	role, err := eb.checkSoleOrg(ctx, tx, actor.OrgID, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound), errors.Is(err, model.ErrUserInOtherOrgs):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get user organizations from DB in 'SetUserDisabled': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.SetUserDisabled(ctx, tx, uid, disabled); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to update user in DB in 'SetUserDisabled': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	if disabled {
+		if err := eb.repo.RevokeSessionsByUser(ctx, tx, uid); err != nil {
+			log.Printf("RID %q Failed to revoke user sessions in DB in 'SetUserDisabled': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+		// места из очереди достались бы аккаунту, который не может их подтвердить
+		if err := eb.repo.DeleteWaitlistByUser(ctx, tx, uid); err != nil {
+			log.Printf("RID %q Failed to delete user waitlist entries in DB in 'SetUserDisabled': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	user, err := eb.repo.GetUserByID(ctx, tx, uid)
+	if err != nil {
+		log.Printf("RID %q Failed to get user from DB in 'SetUserDisabled': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	user.Role = role
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'SetUserDisabled': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	return user, nil
+}
+
+// DeleteUser удаляет пользователя. Места его активных броней возвращаются ивентам и отдаются очереди ожидания
+// до удаления - каскад ON DELETE в БД удалил бы брони молча, не восстановив места.
+// Как и блокировка, доступно только админу единственной организации пользователя.
+func (eb EBService) DeleteUser(ctx context.Context, uid int, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if !actor.Can(model.PermUsersManage) {
+		return model.ErrAccessDenied
+	}
+	if uid < 1 {
+		return model.ErrIncorrectUserID
+	}
+	if uid == actor.UserID {
+		return model.ErrSelfModification
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'DeleteUser': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'DeleteUser': %v", rid, err)
+			}
+		}
+	}()
+
+	if _, err := eb.checkSoleOrg(ctx, tx, actor.OrgID, uid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound), errors.Is(err, model.ErrUserInOtherOrgs):
+			return err
+		default:
+			log.Printf("RID %q Failed to get user organizations from DB in 'DeleteUser': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	// брони, держащие места, блокируются до удаления пользователя
+	books, err := eb.repo.GetActiveBooksByUser(ctx, tx, uid)
+	if err != nil {
+		log.Printf("RID %q Failed to get user bookings from DB in 'DeleteUser': %v", rid, err)
+		return model.ErrCommon500
+	}
+
+	seats := make(map[int]int)
+	orgs := make(map[int]int) // организация ивента - для очереди ожидания
+	for _, b := range books {
+		seats[b.EventID] += b.Quantity
+		orgs[b.EventID] = b.OrgID
+	}
+	eventIDs := make([]int, 0, len(seats))
+	for eid := range seats {
+		eventIDs = append(eventIDs, eid)
+	}
+	sort.Ints(eventIDs) // единый порядок блокировки ивентов
+
+	for _, eid := range eventIDs {
+		if err := eb.repo.IncreaseAvailSeatsByEventID(ctx, tx, eid, seats[eid]); err != nil {
+			log.Printf("RID %q Failed to increase event avail.seats in 'DeleteUser': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.DeleteUser(ctx, tx, uid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to delete user from DB in 'DeleteUser': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	// освободившиеся места - очередям ожидания; сам пользователь из очередей уже удален каскадом
+	promoted := make([]*model.BookWithUser, 0)
+	for _, eid := range eventIDs {
+		p, err := eb.promoteWaitlist(ctx, tx, orgs[eid], eid)
+		if err != nil {
+			log.Printf("RID %q Failed to promote waitlist in 'DeleteUser': %v", rid, err)
+			return model.ErrCommon500
+		}
+		promoted = append(promoted, p...)
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'DeleteUser': %v", rid, err)
+		return model.ErrCommon500
+	}
+	committed = true
+
+	eb.announcePromotions(ctx, promoted)
+	return nil
+}
+
+// orgMemberRole - роль пользователя в организации; не участник - ErrUserNotFound, чужие пользователи админу не видны
+func (eb EBService) orgMemberRole(ctx context.Context, exec repository.Executor, orgID int, uid int) (string, error) {
+	role, err := eb.repo.GetOrgMemberRole(ctx, exec, orgID, uid)
+	if errors.Is(err, model.ErrNotOrgMember) {
+		return "", model.ErrUserNotFound
+	}
+	return role, err
+}
+
+// checkSoleOrg - пользователь состоит в организации orgID и только в ней; возвращает его роль
+func (eb EBService) checkSoleOrg(ctx context.Context, exec repository.Executor, orgID int, uid int) (string, error) {
+	orgs, err := eb.repo.GetUserOrganizations(ctx, exec, uid)
+	if err != nil {
+		return "", err
+	}
+	role := ""
+	for _, o := range orgs {
+		if o.ID == orgID {
+			role = o.Role
+		}
+	}
+	switch {
+	case role == "":
+		return "", model.ErrUserNotFound
+	case len(orgs) > 1:
+		return "", model.ErrUserInOtherOrgs
+	}
+	return role, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/UnendingLoop/EventBooker/internal/repository"
+)
+
+// JoinWaitlist ставит пользователя в очередь ожидания ивента, на который сейчас не хватает мест
+func (eb EBService) JoinWaitlist(ctx context.Context, eid int, actor model.Actor, quantity int) (*model.WaitlistEntry, error) {
+	rid := model.RequestIDFromCtx(ctx)
+	uid := actor.UserID
+
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+	if uid < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+	if quantity == 0 { // по умолчанию - одно место
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, model.ErrIncorrectQuantity
+	}
+	if err := eb.checkEmailVerified(ctx, uid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrEmailNotVerified):
+			return nil, err // 403
+		default:
+			log.Printf("RID %q Failed to check email verification in 'JoinWaitlist': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	// бегин транзакции
+	tx, err := eb.txm.BeginTx(ctx)
+	if err != nil {
+		log.Printf("RID %q Failed to begin transaction in 'JoinWaitlist': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				log.Printf("RID %q Failed to rollback transaction in 'JoinWaitlist': %v", rid, err)
+			}
+		}
+	}()
+
+	// получаем ивент с блокировкой - свободные места не должны появиться между проверкой и записью в очередь
+	event, err := eb.repo.GetEventByID(ctx, tx, actor.OrgID, eid)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'JoinWaitlist': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+	if event.Status != model.EventStatusActual {
+		return nil, model.ErrExpiredEvent
+	}
+	if !time.Now().Before(eb.bookingClosesAt(event)) {
+		return nil, model.ErrBookingClosed
+	}
+	if quantity > event.MaxPerBook {
+		return nil, model.ErrTooManySeatsPerBook
+	}
+	if event.AvailSeats >= quantity {
+		return nil, model.ErrSeatsAvailable
+	}
+
+	entry := &model.WaitlistEntry{EventID: eid, UserID: uid, Quantity: quantity}
+	if err := eb.repo.CreateWaitlistEntry(ctx, tx, entry); err != nil {
+		switch {
+		case errors.Is(err, model.ErrAlreadyWaitlisted):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to create waitlist entry in DB in 'JoinWaitlist': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	entry, err = eb.repo.GetWaitlistEntry(ctx, tx, eid, uid)
+	if err != nil {
+		log.Printf("RID %q Failed to get waitlist position from DB in 'JoinWaitlist': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+
+	// коммит транзакции
+	if err := tx.Commit(); err != nil {
+		log.Printf("RID %q Failed to commit transaction in 'JoinWaitlist': %v", rid, err)
+		return nil, model.ErrCommon500
+	}
+	committed = true
+
+	return entry, nil
+}
+
+func (eb EBService) GetWaitlistPosition(ctx context.Context, eid int, actor model.Actor) (*model.WaitlistEntry, error) {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if eid < 1 {
+		return nil, model.ErrIncorrectEventID
+	}
+	if actor.UserID < 1 {
+		return nil, model.ErrIncorrectUserID
+	}
+
+	// очередь не знает об организациях - ивент должен быть из текущей
+	if _, err := eb.repo.GetEventByIDNoLock(ctx, eb.txm.Executor(), actor.OrgID, eid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'GetWaitlistPosition': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	entry, err := eb.repo.GetWaitlistEntry(ctx, eb.txm.Executor(), eid, actor.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotWaitlisted):
+			return nil, err
+		default:
+			log.Printf("RID %q Failed to get waitlist entry from DB in 'GetWaitlistPosition': %v", rid, err)
+			return nil, model.ErrCommon500
+		}
+	}
+
+	return entry, nil
+}
+
+func (eb EBService) LeaveWaitlist(ctx context.Context, eid int, actor model.Actor) error {
+	rid := model.RequestIDFromCtx(ctx)
+
+	if eid < 1 {
+		return model.ErrIncorrectEventID
+	}
+	if actor.UserID < 1 {
+		return model.ErrIncorrectUserID
+	}
+
+	// очередь не знает об организациях - ивент должен быть из текущей
+	if _, err := eb.repo.GetEventByIDNoLock(ctx, eb.txm.Executor(), actor.OrgID, eid); err != nil {
+		switch {
+		case errors.Is(err, model.ErrEventNotFound):
+			return err
+		default:
+			log.Printf("RID %q Failed to get event from DB in 'LeaveWaitlist': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	if err := eb.repo.DeleteWaitlistEntry(ctx, eb.txm.Executor(), eid, actor.UserID); err != nil {
+		switch {
+		case errors.Is(err, model.ErrNotWaitlisted):
+			return err
+		default:
+			log.Printf("RID %q Failed to delete waitlist entry from DB in 'LeaveWaitlist': %v", rid, err)
+			return model.ErrCommon500
+		}
+	}
+
+	return nil
+}
+
+// promoteWaitlist превращает начало очереди ивента в новые брони, пока свободных мест хватает первому в очереди.
+// Вызывается в транзакции, освободившей места; возвращает созданные брони для announcePromotions после коммита.
+func (eb EBService) promoteWaitlist(ctx context.Context, tx repository.Tx, orgID int, eventID int) ([]*model.BookWithUser, error) {
+	event, err := eb.repo.GetEventByID(ctx, tx, orgID, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != model.EventStatusActual || !time.Now().Before(eb.bookingClosesAt(event)) {
+		return nil, nil
+	}
+
+	promoted := make([]*model.BookWithUser, 0)
+	for {
+		head, err := eb.repo.GetWaitlistHead(ctx, tx, eventID)
+		if err != nil {
+			if errors.Is(err, model.ErrNotWaitlisted) { // очередь пуста
+				break
+			}
+			return nil, err
+		}
+		if head.Quantity > event.AvailSeats { // строгий FIFO: первый в очереди ждет, пока освободится нужное ему число мест
+			break
+		}
+
+		deadline := time.Now().UTC().Add(time.Duration(event.BookWindow) * time.Second)
+		book := &model.Book{
+			OrgID:           orgID,
+			EventID:         eventID,
+			UserID:          head.UserID,
+			Status:          model.BookStatusCreated,
+			Quantity:        head.Quantity,
+			ConfirmDeadline: &deadline,
+		}
+		if err := eb.repo.CreateBook(ctx, tx, book); err != nil {
+			return nil, err
+		}
+		if err := eb.repo.DecreaseAvailSeatsByEventID(ctx, tx, eventID, book.Quantity); err != nil {
+			return nil, err
+		}
+		if err := eb.repo.DeleteWaitlistEntry(ctx, tx, eventID, head.UserID); err != nil {
+			return nil, err
+		}
+
+		event.AvailSeats -= book.Quantity
+		promoted = append(promoted, &model.BookWithUser{Book: *book, Email: head.Email})
+	}
+
+	return promoted, nil
+}
+
+// announcePromotions ставит брони из очереди в планировщик и уведомляет пользователей; вызывать только после коммита
+func (eb EBService) announcePromotions(ctx context.Context, promoted []*model.BookWithUser) {
+	for _, b := range promoted {
+		if eb.scheduler != nil {
+			eb.scheduler.Schedule(b.OrgID, b.ID, *b.ConfirmDeadline)
+		}
+		eb.notify(ctx, &model.Notification{
+			UserID:  b.UserID,
+			Email:   b.Email,
+			Subject: "Seats available",
+			Text: fmt.Sprintf("Seats for event #%d became available: booking #%d for %d seat(s) was created for you. Confirm it before %s.",
+				b.EventID, b.ID, b.Quantity, b.ConfirmDeadline.Format(time.RFC3339)),
+		})
+	}
+}
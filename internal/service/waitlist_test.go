@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+)
+
+func TestPromoteWaitlistSkipsIneligibleUsers(t *testing.T) {
+	e := newTestEnv(t)
+	admin := e.user("admin@test.io", model.RoleAdmin)
+	holder := e.user("holder@test.io", model.RoleUser)
+	disabled := e.user("disabled@test.io", model.RoleUser)
+	removed := e.user("removed@test.io", model.RoleUser)
+	waiting := e.user("waiting@test.io", model.RoleUser)
+	ev := e.event(1, 1, admin.UserID)
+	book := e.book(ev.ID, holder.UserID, 1, model.BookStatusConfirmed, time.Now().Add(time.Hour))
+
+	// очередь в порядке: заблокированный, исключенный, активный
+	for _, u := range []model.Actor{disabled, removed, waiting} {
+		if err := e.repo.CreateWaitlistEntry(e.ctx, e.store, &model.WaitlistEntry{EventID: ev.ID, UserID: u.UserID, Quantity: 1}); err != nil {
+			t.Fatalf("create waitlist entry: %v", err)
+		}
+	}
+
+	if _, err := e.svc.SetUserDisabled(e.ctx, disabled.UserID, true, admin); err != nil {
+		t.Fatalf("SetUserDisabled() error = %v", err)
+	}
+	if err := e.svc.RemoveOrgMember(e.ctx, removed.UserID, admin); err != nil {
+		t.Fatalf("RemoveOrgMember() error = %v", err)
+	}
+	for _, u := range []model.Actor{disabled, removed} {
+		if _, err := e.repo.GetWaitlistEntry(e.ctx, e.store, ev.ID, u.UserID); !errors.Is(err, model.ErrNotWaitlisted) {
+			t.Errorf("user %d waitlist entry error = %v, want ErrNotWaitlisted", u.UserID, err)
+		}
+	}
+
+	// освободившееся место достается первому, кто может подтвердить бронь
+	if err := e.svc.CancelBook(e.ctx, book.ID, holder); err != nil {
+		t.Fatalf("CancelBook() error = %v", err)
+	}
+	if _, err := e.repo.GetWaitlistEntry(e.ctx, e.store, ev.ID, waiting.UserID); !errors.Is(err, model.ErrNotWaitlisted) {
+		t.Errorf("active user is still waitlisted: %v", err)
+	}
+	books, err := e.repo.GetActiveBooksByUser(e.ctx, e.store, waiting.UserID)
+	if err != nil {
+		t.Fatalf("get active books: %v", err)
+	}
+	if len(books) != 1 || books[0].EventID != ev.ID || books[0].Status != model.BookStatusCreated {
+		t.Errorf("active user books = %+v, want one created booking for event %d", books, ev.ID)
+	}
+	if got := e.availSeats(ev.ID); got != 0 {
+		t.Errorf("avail seats = %d, want 0", got)
+	}
+}
@@ -0,0 +1,104 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// секрет из RFC 6238, приложение B: ASCII "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateRFC6238(t *testing.T) {
+	// значения SHA1 из RFC 6238, приложение B, усеченные до 6 цифр
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		if got := generate(key, Step(now)); got != tt.want {
+			t.Errorf("generate(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+		step, ok := Validate(rfcSecret, tt.want, now)
+		if !ok || step != Step(now) {
+			t.Errorf("Validate(%d) = %d, %v, want %d, true", tt.unix, step, ok, Step(now))
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key, err := encoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	codeAt := func(step int64) string { return generate(key, step) }
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{name: "current step", secret: rfcSecret, code: codeAt(current), wantOK: true, wantStep: current},
+		{name: "previous step within skew", secret: rfcSecret, code: codeAt(current - 1), wantOK: true, wantStep: current - 1},
+		{name: "next step within skew", secret: rfcSecret, code: codeAt(current + 1), wantOK: true, wantStep: current + 1},
+		{name: "too old", secret: rfcSecret, code: codeAt(current - 2)},
+		{name: "too new", secret: rfcSecret, code: codeAt(current + 2)},
+		{name: "spaces around code", secret: rfcSecret, code: " " + codeAt(current) + " ", wantOK: true, wantStep: current},
+		{name: "lowercase secret", secret: strings.ToLower(rfcSecret), code: codeAt(current), wantOK: true, wantStep: current},
+		{name: "wrong length", secret: rfcSecret, code: codeAt(current)[:5]},
+		{name: "empty code", secret: rfcSecret},
+		{name: "broken secret", secret: "not base32!", code: codeAt(current)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	b, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret() error = %v", err)
+	}
+	key, err := encoding.DecodeString(a)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q decodes to %d bytes, err %v; want 20 bytes", a, len(key), err)
+	}
+	if a == b {
+		t.Errorf("two secrets are equal: %q", a)
+	}
+}
+
+func TestURI(t *testing.T) {
+	got := URI("Event Booker", "user@test.io", rfcSecret)
+	want := "otpauth://totp/Event%20Booker:user@test.io?algorithm=SHA1&digits=6&issuer=Event+Booker&period=30&secret=" + rfcSecret
+	if got != want {
+		t.Errorf("URI() = %q, want %q", got, want)
+	}
+}
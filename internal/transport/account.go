@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) VerifyEmail(ctx *gin.Context) {
+	var req tokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid token payload"})
+		return
+	}
+
+	if err := eh.svc.VerifyEmail(ctx.Request.Context(), req.Token); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (eh *EBHandlers) ResendVerification(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+
+	if err := eh.svc.ResendVerification(ctx.Request.Context(), uid); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// ForgotPassword отвечает одинаково независимо от того, зарегистрирован ли имейл
+func (eh *EBHandlers) ForgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid email payload"})
+		return
+	}
+
+	if err := eh.svc.ForgotPassword(ctx.Request.Context(), req.Email); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (eh *EBHandlers) ResetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid reset payload"})
+		return
+	}
+
+	if err := eh.svc.ResetPassword(ctx.Request.Context(), req.Token, req.Password); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	clearAuthCookies(ctx)
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
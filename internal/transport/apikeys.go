@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey - сам ключ есть только в этом ответе, дальше хранится лишь его хэш
+func (eh *EBHandlers) CreateAPIKey(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+	mfa, _ := ctx.Value("mfa").(bool)
+
+	var req apiKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key payload"})
+		return
+	}
+
+	key := model.APIKey{Name: req.Name, Scopes: req.Scopes}
+	if err := eh.svc.CreateAPIKey(ctx.Request.Context(), uid, intFromCtx(ctx, "org_id"), mfa, &key, time.Duration(req.ExpiresInDays)*24*time.Hour); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+func (eh *EBHandlers) GetAPIKeys(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+
+	keys, err := eh.svc.GetAPIKeys(ctx.Request.Context(), uid)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+func (eh *EBHandlers) RevokeAPIKey(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+	kid, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty API key id"})
+		return
+	}
+
+	if err := eh.svc.RevokeAPIKey(ctx.Request.Context(), uid, stringToInt(kid)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
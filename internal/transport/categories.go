@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) GetCategories(ctx *gin.Context) {
+	categories, err := eh.svc.GetCategories(ctx.Request.Context(), actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, categories)
+}
+
+func (eh *EBHandlers) CreateCategory(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q creating category", rid, uid, mail, role)
+
+	// обычный флоу
+	var req categoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid category payload"})
+		return
+	}
+
+	category := model.Category{Name: req.Name, Slug: req.Slug}
+	if err := eh.svc.CreateCategory(ctx.Request.Context(), &category, actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+func (eh *EBHandlers) UpdateCategory(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q updating category", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty category id"})
+		return
+	}
+	var req categoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid category payload"})
+		return
+	}
+
+	category := model.Category{ID: stringToInt(rawID), Name: req.Name, Slug: req.Slug}
+	if err := eh.svc.UpdateCategory(ctx.Request.Context(), &category, actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+func (eh *EBHandlers) DeleteCategory(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q deleting category", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty category id"})
+		return
+	}
+
+	if err := eh.svc.DeleteCategory(ctx.Request.Context(), stringToInt(rawID), actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
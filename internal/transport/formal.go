@@ -3,7 +3,9 @@ package transport
 
 import (
 	"context"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
 	"github.com/gin-gonic/gin"
@@ -15,14 +17,58 @@ type EBHandlers struct {
 
 type HService interface {
 	BookEvent(ctx context.Context, book *model.Book) error
-	CancelBook(ctx context.Context, bid int, uid int) error
-	ConfirmBook(ctx context.Context, bid int, uid int) error
-	CreateEvent(ctx context.Context, event *model.Event) error
-	CreateUser(ctx context.Context, user *model.User) (string, error)
-	DeleteEvent(ctx context.Context, eid int, role string) error
-	GetBooksListByUserID(ctx context.Context, uid int) ([]*model.Book, error)
-	LoginUser(ctx context.Context, email string, password string) (string, *model.User, error)
-	GetEventsList(ctx context.Context, role string) ([]*model.Event, error)
+	CancelBook(ctx context.Context, bid int, actor model.Actor) error
+	ConfirmBook(ctx context.Context, bid int, actor model.Actor) error
+	CreateEvent(ctx context.Context, event *model.Event, actor model.Actor) error
+	CreateUser(ctx context.Context, user *model.User, inviteToken string, orgSlug string) (*model.AuthTokens, error)
+	CreateInvite(ctx context.Context, invite *model.Invite, ttl time.Duration, role string) error
+	DeleteEvent(ctx context.Context, eid int, actor model.Actor) error
+	CancelEvent(ctx context.Context, eid int, reason string, actor model.Actor) (*model.Event, error)
+	UpdateEvent(ctx context.Context, eid int, upd *model.EventUpdate, actor model.Actor) (*model.Event, error)
+	UpdateEventSeries(ctx context.Context, eid int, upd *model.EventUpdate, actor model.Actor) (*model.Event, error)
+	CreateSeries(ctx context.Context, series *model.EventSeries, actor model.Actor) (*model.SeriesInfo, error)
+	GetSeriesInfo(ctx context.Context, id int, actor model.Actor) (*model.SeriesInfo, error)
+	AddEventOrganizer(ctx context.Context, eid int, uid int, actor model.Actor) ([]*model.EventOrganizer, error)
+	RemoveEventOrganizer(ctx context.Context, eid int, uid int, actor model.Actor) error
+	GetBooksListByUserID(ctx context.Context, filter *model.BookFilter, actor model.Actor) ([]*model.Book, string, error)
+	LoginUser(ctx context.Context, email string, password string) (*model.AuthTokens, *model.User, error)
+	RefreshSession(ctx context.Context, refresh string) (*model.AuthTokens, *model.User, error)
+	SwitchOrganization(ctx context.Context, refresh string, orgID int) (*model.AuthTokens, *model.User, error)
+	Logout(ctx context.Context, refresh string) error
+	LogoutAll(ctx context.Context, uid int) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, uid int) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+	SetupTOTP(ctx context.Context, uid int) (*model.TOTPSetup, error)
+	EnableTOTP(ctx context.Context, uid int, orgID int, code string) (*model.AuthTokens, []string, error)
+	DisableTOTP(ctx context.Context, uid int, code string, recoveryCode string) error
+	VerifyMFA(ctx context.Context, mfaToken string, code string, recoveryCode string) (*model.AuthTokens, *model.User, error)
+	StartOIDCLogin(ctx context.Context) (string, string, error)
+	CompleteOIDCLogin(ctx context.Context, stateToken string, state string, code string) (*model.AuthTokens, *model.User, error)
+	GetEventsList(ctx context.Context, filter *model.EventFilter, actor model.Actor) ([]*model.Event, string, error)
+	SearchEvents(ctx context.Context, search *model.EventSearch, actor model.Actor) ([]*model.EventSearchResult, error)
+	GetCategories(ctx context.Context, actor model.Actor) ([]*model.Category, error)
+	CreateCategory(ctx context.Context, category *model.Category, actor model.Actor) error
+	UpdateCategory(ctx context.Context, category *model.Category, actor model.Actor) error
+	DeleteCategory(ctx context.Context, id int, actor model.Actor) error
+	GetEventInfo(ctx context.Context, eid int, actor model.Actor) (*model.EventInfo, error)
+	JoinWaitlist(ctx context.Context, eid int, actor model.Actor, quantity int) (*model.WaitlistEntry, error)
+	GetWaitlistPosition(ctx context.Context, eid int, actor model.Actor) (*model.WaitlistEntry, error)
+	LeaveWaitlist(ctx context.Context, eid int, actor model.Actor) error
+	GetUsersList(ctx context.Context, filter *model.UserFilter, actor model.Actor) ([]*model.User, int, error)
+	GetUserInfo(ctx context.Context, uid int, actor model.Actor) (*model.UserInfo, error)
+	ChangeUserRole(ctx context.Context, uid int, newRole string, actor model.Actor) (*model.User, error)
+	SetUserDisabled(ctx context.Context, uid int, disabled bool, actor model.Actor) (*model.User, error)
+	DeleteUser(ctx context.Context, uid int, actor model.Actor) error
+	GetOrganizations(ctx context.Context, uid int) ([]*model.Organization, error)
+	CreateOrganization(ctx context.Context, org *model.Organization, actor model.Actor) error
+	InviteOrgMember(ctx context.Context, email string, role string, actor model.Actor) (*model.Invite, error)
+	JoinOrganization(ctx context.Context, inviteToken string, actor model.Actor) (*model.Organization, error)
+	RemoveOrgMember(ctx context.Context, uid int, actor model.Actor) error
+	CreateAPIKey(ctx context.Context, uid int, orgID int, mfa bool, key *model.APIKey, ttl time.Duration) error
+	GetAPIKeys(ctx context.Context, uid int) ([]*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, uid int, keyID int) error
 }
 
 func NewEBHandlers(svc HService) *EBHandlers {
@@ -35,21 +81,262 @@ type authRequest struct {
 	Password string `json:"password"`
 }
 
+// signUpRequest - роль клиент не выбирает: она берется из приглашения, без него - user;
+// организация (slug) тоже берется из приглашения, без него - указанная или организация по умолчанию
+type signUpRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Surname  string `json:"surname"`
+	Tel      string `json:"tel"`
+	Invite   string `json:"invite"`
+	Org      string `json:"org"`
+}
+
+type tokenRequest struct {
+	Token string `json:"token"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// mfaCodeRequest - код из аутентификатора либо, при его потере, код восстановления
+type mfaCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type inviteRequest struct {
+	Role     string `json:"role"`
+	Email    string `json:"email"`
+	TTLHours int    `json:"ttl_hours"`
+}
+
+type userRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type organizerRequest struct {
+	UserID int `json:"user_id"`
+}
+
+type cancelEventRequest struct {
+	Reason string `json:"reason"`
+}
+
+type waitlistRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// apiKeyRequest - без expires_in_days ключ действует 90 дней
+type orgRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type categoryRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type orgMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type joinOrgRequest struct {
+	Invite string `json:"invite"`
+}
+
+type switchOrgRequest struct {
+	OrgID int `json:"org_id"`
+}
+
+type apiKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
 type authResponse struct {
 	User userPublic `json:"user"`
 }
 
 type userPublic struct {
-	ID    int    `json:"id"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	EmailVerified bool   `json:"email_verified"`
+	MFAEnabled    bool   `json:"mfa_enabled"`
+}
+
+// mfaRequiredResponse - пароль верный, для входа нужен код 2FA (POST /auth/2fa/verify)
+type mfaRequiredResponse struct {
+	MFARequired bool `json:"mfa_required"`
+}
+
+type totpSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// userDetails - пользователь для админских ответов, без хэша пароля
+type userDetails struct {
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	Name            string     `json:"name,omitempty"`
+	Surname         string     `json:"surname,omitempty"`
+	Tel             string     `json:"tel,omitempty"`
+	Created         *time.Time `json:"created,omitempty"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	LockedUntil     *time.Time `json:"locked_until,omitempty"`
+	TOTPEnabledAt   *time.Time `json:"totp_enabled_at,omitempty"`
+}
+
+type usersListResponse struct {
+	Users  []userDetails `json:"users"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// eventsPageResponse - страница ивентов; next_cursor передается в ?cursor= за следующей, на последней странице его нет
+type eventsPageResponse struct {
+	Events     []*model.Event `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Limit      int            `json:"limit"`
+}
+
+type eventsSearchResponse struct {
+	Results []*model.EventSearchResult `json:"results"`
+	Limit   int                        `json:"limit"`
+	Offset  int                        `json:"offset"`
+}
+
+type booksPageResponse struct {
+	Bookings   []*model.Book `json:"bookings"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	Limit      int           `json:"limit"`
+}
+
+type userInfoResponse struct {
+	User  userDetails   `json:"user"`
+	Books []*model.Book `json:"bookings"`
 }
 
 func convertUserAuthToResponse(user *model.User) *authResponse {
-	return &authResponse{User: userPublic{ID: user.ID, Email: user.Email, Role: user.Role}}
+	return &authResponse{User: userPublic{ID: user.ID, Email: user.Email, Role: user.Role, EmailVerified: user.EmailVerifiedAt != nil, MFAEnabled: user.TOTPEnabledAt != nil}}
+}
+
+func convertUserToDetails(user *model.User) userDetails {
+	return userDetails{
+		ID:              user.ID,
+		Email:           user.Email,
+		Role:            user.Role,
+		Name:            user.Name,
+		Surname:         user.Surname,
+		Tel:             user.Tel,
+		Created:         user.Created,
+		DisabledAt:      user.DisabledAt,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		LockedUntil:     user.LockedUntil,
+		TOTPEnabledAt:   user.TOTPEnabledAt,
+	}
 }
 
 // ----------------------------------------------------------
+// setAuthCookies кладет пару токенов в HTTP-only cookie; refresh-токен отправляется браузером только на /auth
+func setAuthCookies(ctx *gin.Context, tokens *model.AuthTokens) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     "access_token",
+		Value:    tokens.Access,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   3600,
+	})
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    tokens.Refresh,
+		Path:     "/auth",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(time.Until(tokens.RefreshExpires).Seconds()),
+	})
+}
+
+// setMFACookie - промежуточный токен 2FA живет 5 минут и отправляется только на /auth/2fa
+func setMFACookie(ctx *gin.Context, token string) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     "mfa_token",
+		Value:    token,
+		Path:     "/auth/2fa",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   300,
+	})
+}
+
+// setOIDCStateCookie - параметры входа через OIDC на 10 минут; Lax, чтобы cookie пришла при возврате с сайта провайдера
+func setOIDCStateCookie(ctx *gin.Context, token string) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     "oidc_state",
+		Value:    token,
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   600,
+	})
+}
+
+func clearOIDCStateCookie(ctx *gin.Context) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     "oidc_state",
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		Secure:   true,
+		MaxAge:   -1,
+	})
+}
+
+func clearMFACookie(ctx *gin.Context) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     "mfa_token",
+		Path:     "/auth/2fa",
+		HttpOnly: true,
+		Secure:   true,
+		MaxAge:   -1,
+	})
+}
+
+func clearAuthCookies(ctx *gin.Context) {
+	for name, path := range map[string]string{"access_token": "/", "refresh_token": "/auth"} {
+		http.SetCookie(ctx.Writer, &http.Cookie{
+			Name:     name,
+			Path:     path,
+			HttpOnly: true,
+			Secure:   true,
+			MaxAge:   -1,
+		})
+	}
+}
+
 func stringFromCtx(ctx *gin.Context, key string) string {
 	if v := ctx.Value(key); v != nil {
 		return v.(string)
@@ -64,6 +351,33 @@ func intFromCtx(ctx *gin.Context, key string) int {
 	return 0
 }
 
+// actorFromCtx - пользователь запроса, от имени которого сервис проверяет права на ресурс
+func actorFromCtx(ctx *gin.Context) model.Actor {
+	return model.Actor{UserID: intFromCtx(ctx, "user_id"), OrgID: intFromCtx(ctx, "org_id"), Role: stringFromCtx(ctx, "role")}
+}
+
+// queryInt - числовой query-параметр: отсутствующий дает 0 (значение по умолчанию), некорректный - -1
+func queryInt(ctx *gin.Context, key string) int {
+	raw := ctx.Query(key)
+	if raw == "" {
+		return 0
+	}
+	return stringToInt(raw)
+}
+
+// queryDate - дата из query-параметра в формате YYYY-MM-DD (начало дня UTC); отсутствующая дает nil
+func queryDate(ctx *gin.Context, key string) (*time.Time, error) {
+	raw := ctx.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, model.ErrIncorrectListFilter
+	}
+	return &t, nil
+}
+
 func stringToInt(input string) int {
 	output, err := strconv.Atoi(input)
 	if err != nil {
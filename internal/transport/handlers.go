@@ -1,8 +1,10 @@
 package transport
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/UnendingLoop/EventBooker/internal/model"
 	"github.com/gin-gonic/gin"
@@ -15,35 +17,28 @@ func (eh *EBHandlers) SimplePinger(ctx *ginext.Context) {
 }
 
 func (eh *EBHandlers) SignUpUser(ctx *gin.Context) {
-	var newUser model.User
+	var req signUpRequest
 
-	if err := ctx.ShouldBindJSON(&newUser); err != nil {
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user payload"})
 		return
 	}
+	newUser := model.User{Email: req.Email, PassHash: req.Password, Name: req.Name, Surname: req.Surname, Tel: req.Tel}
 
-	token, err := eh.svc.CreateUser(ctx.Request.Context(), &newUser)
+	tokens, err := eh.svc.CreateUser(ctx.Request.Context(), &newUser, req.Invite, req.Org)
 	if err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
 	}
 	resp := convertUserAuthToResponse(&newUser)
 
-	http.SetCookie(ctx.Writer, &http.Cookie{
-		Name:     "access_token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   3600,
-	})
+	setAuthCookies(ctx, tokens)
 
 	ctx.JSON(http.StatusCreated, resp)
 }
 
 func (eh *EBHandlers) CreateEvent(ctx *gin.Context) {
-	// логируем админовые ивенты
+	// логируем действия организаторов и админов
 	rid := stringFromCtx(ctx, "request_id")
 	uid := intFromCtx(ctx, "user_id")
 	mail := stringFromCtx(ctx, "email")
@@ -52,18 +47,13 @@ func (eh *EBHandlers) CreateEvent(ctx *gin.Context) {
 	log.Printf("rid=%q userID=%d userEmail=%q role=%q creating event", rid, uid, mail, role)
 
 	// дальше обычная логика
-	if role != "admin" {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
-
 	var event model.Event
 	if err := ctx.ShouldBindJSON(&event); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event payload"})
 		return
 	}
 
-	if err := eh.svc.CreateEvent(ctx.Request.Context(), &event); err != nil {
+	if err := eh.svc.CreateEvent(ctx.Request.Context(), &event, actorFromCtx(ctx)); err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
 	}
@@ -79,29 +69,143 @@ func (eh *EBHandlers) LoginUser(ctx *gin.Context) {
 		return
 	}
 
-	token, user, err := eh.svc.LoginUser(ctx.Request.Context(), req.Email, req.Password)
+	tokens, user, err := eh.svc.LoginUser(ctx.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	if tokens.MFAToken != "" {
+		setMFACookie(ctx, tokens.MFAToken)
+		ctx.JSON(http.StatusOK, mfaRequiredResponse{MFARequired: true})
+		return
+	}
+	resp := convertUserAuthToResponse(user)
+
+	setAuthCookies(ctx, tokens)
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (eh *EBHandlers) RefreshSession(ctx *gin.Context) {
+	refresh, _ := ctx.Cookie("refresh_token")
+
+	tokens, user, err := eh.svc.RefreshSession(ctx.Request.Context(), refresh)
 	if err != nil {
+		if errors.Is(err, model.ErrInvalidRefreshToken) {
+			clearAuthCookies(ctx)
+		}
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
 	}
 	resp := convertUserAuthToResponse(user)
 
-	http.SetCookie(ctx.Writer, &http.Cookie{
-		Name:     "access_token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   3600,
-	})
+	setAuthCookies(ctx, tokens)
 
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// SwitchOrganization - как и refresh, работает по refresh-токену: сессия переходит в другую организацию, cookie заменяются
+func (eh *EBHandlers) SwitchOrganization(ctx *gin.Context) {
+	refresh, _ := ctx.Cookie("refresh_token")
+
+	var req switchOrgRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization payload"})
+		return
+	}
+
+	tokens, user, err := eh.svc.SwitchOrganization(ctx.Request.Context(), refresh, req.OrgID)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidRefreshToken) {
+			clearAuthCookies(ctx)
+		}
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	resp := convertUserAuthToResponse(user)
+
+	setAuthCookies(ctx, tokens)
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (eh *EBHandlers) Logout(ctx *gin.Context) {
+	refresh, _ := ctx.Cookie("refresh_token")
+
+	if err := eh.svc.Logout(ctx.Request.Context(), refresh); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	clearAuthCookies(ctx)
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+func (eh *EBHandlers) LogoutAll(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+
+	if err := eh.svc.LogoutAll(ctx.Request.Context(), uid); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	clearAuthCookies(ctx)
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
 func (eh *EBHandlers) GetEvents(ctx *gin.Context) {
-	role := stringFromCtx(ctx, "role")
-	res, err := eh.svc.GetEventsList(ctx.Request.Context(), role)
+	filter := model.EventFilter{
+		Status:   ctx.Query("status"),
+		Query:    ctx.Query("q"),
+		HasSeats: ctx.Query("available") == "true",
+		Category: ctx.Query("category"),
+		Tag:      ctx.Query("tag"),
+		Sort:     ctx.Query("sort"),
+		Cursor:   ctx.Query("cursor"),
+		Limit:    queryInt(ctx, "limit"),
+	}
+	var err error
+	if filter.From, err = queryDate(ctx, "from"); err == nil {
+		filter.To, err = queryDate(ctx, "to")
+	}
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	res, next, err := eh.svc.GetEventsList(ctx.Request.Context(), &filter, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, eventsPageResponse{Events: res, NextCursor: next, Limit: filter.Limit})
+}
+
+func (eh *EBHandlers) SearchEvents(ctx *gin.Context) {
+	search := model.EventSearch{
+		Query:  ctx.Query("q"),
+		Limit:  queryInt(ctx, "limit"),
+		Offset: queryInt(ctx, "offset"),
+	}
+
+	res, err := eh.svc.SearchEvents(ctx.Request.Context(), &search, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, eventsSearchResponse{Results: res, Limit: search.Limit, Offset: search.Offset})
+}
+
+func (eh *EBHandlers) GetEvent(ctx *gin.Context) {
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	res, err := eh.svc.GetEventInfo(ctx.Request.Context(), stringToInt(rawID), actorFromCtx(ctx))
 	if err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
@@ -110,8 +214,80 @@ func (eh *EBHandlers) GetEvents(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, res)
 }
 
+func (eh *EBHandlers) UpdateEvent(ctx *gin.Context) {
+	// логируем действия организаторов и админов
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q updating event", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	var upd model.EventUpdate
+	if err := ctx.ShouldBindJSON(&upd); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event payload"})
+		return
+	}
+
+	// вхождение серии меняется само по себе или вместе со всеми следующими
+	var event *model.Event
+	var err error
+	switch ctx.Query("scope") {
+	case "", model.UpdateScopeThis:
+		event, err = eh.svc.UpdateEvent(ctx.Request.Context(), stringToInt(rawID), &upd, actorFromCtx(ctx))
+	case model.UpdateScopeFuture:
+		event, err = eh.svc.UpdateEventSeries(ctx.Request.Context(), stringToInt(rawID), &upd, actorFromCtx(ctx))
+	default:
+		err = model.ErrIncorrectScope
+	}
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, event)
+}
+
+func (eh *EBHandlers) CancelEvent(ctx *gin.Context) {
+	// логируем действия организаторов и админов
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q cancelling event", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	var req cancelEventRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cancellation payload"})
+		return
+	}
+
+	event, err := eh.svc.CancelEvent(ctx.Request.Context(), stringToInt(rawID), req.Reason, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, event)
+}
+
 func (eh *EBHandlers) DeleteEvent(ctx *gin.Context) {
-	// логируем админовые ивенты
+	// логируем действия организаторов и админов
 	rid := stringFromCtx(ctx, "request_id")
 	uid := intFromCtx(ctx, "user_id")
 	mail := stringFromCtx(ctx, "email")
@@ -127,7 +303,7 @@ func (eh *EBHandlers) DeleteEvent(ctx *gin.Context) {
 	}
 
 	eventID := stringToInt(rawID)
-	err := eh.svc.DeleteEvent(ctx.Request.Context(), eventID, role)
+	err := eh.svc.DeleteEvent(ctx.Request.Context(), eventID, actorFromCtx(ctx))
 	if err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
@@ -144,6 +320,7 @@ func (eh *EBHandlers) BookEvent(ctx *gin.Context) {
 		return
 	}
 	book.UserID = intFromCtx(ctx, "user_id")
+	book.OrgID = intFromCtx(ctx, "org_id")
 
 	err := eh.svc.BookEvent(ctx.Request.Context(), &book)
 	if err != nil {
@@ -155,14 +332,13 @@ func (eh *EBHandlers) BookEvent(ctx *gin.Context) {
 }
 
 func (eh *EBHandlers) ConfirmBook(ctx *gin.Context) {
-	uid := intFromCtx(ctx, "user_id")
 	bid, ok := ctx.Params.Get("id")
 	if !ok {
 		ctx.JSON(400, gin.H{"error": "empty book id"})
 		return
 	}
 
-	if err := eh.svc.ConfirmBook(ctx.Request.Context(), stringToInt(bid), uid); err != nil {
+	if err := eh.svc.ConfirmBook(ctx.Request.Context(), stringToInt(bid), actorFromCtx(ctx)); err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
 	}
@@ -171,28 +347,65 @@ func (eh *EBHandlers) ConfirmBook(ctx *gin.Context) {
 }
 
 func (eh *EBHandlers) GetUserBooks(ctx *gin.Context) {
-	uid := intFromCtx(ctx, "user_id")
+	filter := model.BookFilter{
+		Status: ctx.Query("status"),
+		Sort:   ctx.Query("sort"),
+		Cursor: ctx.Query("cursor"),
+		Limit:  queryInt(ctx, "limit"),
+	}
+	var err error
+	if filter.From, err = queryDate(ctx, "from"); err == nil {
+		filter.To, err = queryDate(ctx, "to")
+	}
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
 
-	res, err := eh.svc.GetBooksListByUserID(ctx.Request.Context(), uid)
+	res, next, err := eh.svc.GetBooksListByUserID(ctx.Request.Context(), &filter, actorFromCtx(ctx))
 	if err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, res)
+	ctx.JSON(http.StatusOK, booksPageResponse{Bookings: res, NextCursor: next, Limit: filter.Limit})
 }
 
 func (eh *EBHandlers) CancelBook(ctx *gin.Context) {
-	uid := intFromCtx(ctx, "user_id")
 	bid, ok := ctx.Params.Get("id")
 	if !ok {
 		ctx.JSON(400, gin.H{"error": "empty book id"})
 		return
 	}
-	if err := eh.svc.CancelBook(ctx.Request.Context(), stringToInt(bid), uid); err != nil {
+	if err := eh.svc.CancelBook(ctx.Request.Context(), stringToInt(bid), actorFromCtx(ctx)); err != nil {
 		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+func (eh *EBHandlers) CreateInvite(ctx *gin.Context) {
+	// логируем действия организаторов и админов
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q creating invite", rid, uid, mail, role)
+
+	// обычный флоу
+	var req inviteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid invite payload"})
+		return
+	}
+
+	invite := model.Invite{OrgID: intFromCtx(ctx, "org_id"), Role: req.Role, Email: req.Email, CreatedBy: uid}
+	if err := eh.svc.CreateInvite(ctx.Request.Context(), &invite, time.Duration(req.TTLHours)*time.Hour, role); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invite)
+}
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) SetupTOTP(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+
+	setup, err := eh.svc.SetupTOTP(ctx.Request.Context(), uid)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, totpSetupResponse{Secret: setup.Secret, URI: setup.URI})
+}
+
+// EnableTOTP отвечает кодами восстановления - они показываются один раз; текущая сессия заменяется новой, с пройденной 2FA
+func (eh *EBHandlers) EnableTOTP(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+
+	var req mfaCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code payload"})
+		return
+	}
+
+	tokens, codes, err := eh.svc.EnableTOTP(ctx.Request.Context(), uid, intFromCtx(ctx, "org_id"), req.Code)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setAuthCookies(ctx, tokens)
+
+	ctx.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (eh *EBHandlers) DisableTOTP(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+
+	var req mfaCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code payload"})
+		return
+	}
+
+	if err := eh.svc.DisableTOTP(ctx.Request.Context(), uid, req.Code, req.RecoveryCode); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// VerifyMFA - второй шаг входа по cookie mfa_token, выставленной при логине
+func (eh *EBHandlers) VerifyMFA(ctx *gin.Context) {
+	mfaToken, _ := ctx.Cookie("mfa_token")
+
+	var req mfaCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid code payload"})
+		return
+	}
+
+	tokens, user, err := eh.svc.VerifyMFA(ctx.Request.Context(), mfaToken, req.Code, req.RecoveryCode)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	resp := convertUserAuthToResponse(user)
+
+	clearMFACookie(ctx)
+	setAuthCookies(ctx, tokens)
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,56 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLogin отправляет браузер к провайдеру; state, nonce и PKCE verifier ждут возврата в подписанной cookie oidc_state
+func (eh *EBHandlers) OIDCLogin(ctx *gin.Context) {
+	authURL, stateToken, err := eh.svc.StartOIDCLogin(ctx.Request.Context())
+	if err != nil {
+		redirectSSOError(ctx, err)
+		return
+	}
+
+	setOIDCStateCookie(ctx, stateToken)
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback - возврат от провайдера. Браузер уходит обратно в UI: #sso=ok (сессия открыта, данные пользователя
+// UI берет из /auth/refresh), #sso=mfa (нужен код 2FA, cookie mfa_token выставлена) или #sso_error=<текст ошибки>
+func (eh *EBHandlers) OIDCCallback(ctx *gin.Context) {
+	rid := stringFromCtx(ctx, "request_id")
+	stateToken, _ := ctx.Cookie("oidc_state")
+	clearOIDCStateCookie(ctx)
+
+	// пользователь отказался от входа или провайдер вернул ошибку
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		log.Printf("rid=%q OIDC provider returned error %q: %q", rid, providerErr, ctx.Query("error_description"))
+		ctx.Redirect(http.StatusFound, "/ui/#sso_error="+url.QueryEscape("identity provider login failed"))
+		return
+	}
+
+	tokens, _, err := eh.svc.CompleteOIDCLogin(ctx.Request.Context(), stateToken, ctx.Query("state"), ctx.Query("code"))
+	if err != nil {
+		redirectSSOError(ctx, err)
+		return
+	}
+	if tokens.MFAToken != "" {
+		setMFACookie(ctx, tokens.MFAToken)
+		ctx.Redirect(http.StatusFound, "/ui/#sso=mfa")
+		return
+	}
+
+	setAuthCookies(ctx, tokens)
+
+	ctx.Redirect(http.StatusFound, "/ui/#sso=ok")
+}
+
+func redirectSSOError(ctx *gin.Context, err error) {
+	ctx.Redirect(http.StatusFound, "/ui/#sso_error="+url.QueryEscape(err.Error()))
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// GetOrganizations - организации пользователя; текущая (организация сессии) помечена current
+func (eh *EBHandlers) GetOrganizations(ctx *gin.Context) {
+	uid := intFromCtx(ctx, "user_id")
+	orgID := intFromCtx(ctx, "org_id")
+
+	orgs, err := eh.svc.GetOrganizations(ctx.Request.Context(), uid)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+	for _, o := range orgs {
+		o.Current = o.ID == orgID
+	}
+
+	ctx.JSON(http.StatusOK, orgs)
+}
+
+func (eh *EBHandlers) CreateOrganization(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q creating organization", rid, uid, mail, role)
+
+	// обычный флоу
+	var req orgRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization payload"})
+		return
+	}
+
+	org := model.Organization{Name: req.Name, Slug: req.Slug}
+	if err := eh.svc.CreateOrganization(ctx.Request.Context(), &org, actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, org)
+}
+
+func (eh *EBHandlers) InviteOrgMember(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q inviting organization member", rid, uid, mail, role)
+
+	// обычный флоу
+	var req orgMemberRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid member payload"})
+		return
+	}
+
+	invite, err := eh.svc.InviteOrgMember(ctx.Request.Context(), req.Email, req.Role, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invite)
+}
+
+// JoinOrganization - принятие приглашения в организацию уже зарегистрированным пользователем
+func (eh *EBHandlers) JoinOrganization(ctx *gin.Context) {
+	var req joinOrgRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid invite payload"})
+		return
+	}
+
+	org, err := eh.svc.JoinOrganization(ctx.Request.Context(), req.Invite, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, org)
+}
+
+func (eh *EBHandlers) RemoveOrgMember(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q removing organization member", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("userId")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty user id"})
+		return
+	}
+
+	if err := eh.svc.RemoveOrgMember(ctx.Request.Context(), stringToInt(rawID), actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
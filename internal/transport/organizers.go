@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) AddEventOrganizer(ctx *gin.Context) {
+	// логируем действия организаторов и админов
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q adding event organizer", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	var req organizerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid organizer payload"})
+		return
+	}
+
+	organizers, err := eh.svc.AddEventOrganizer(ctx.Request.Context(), stringToInt(rawID), req.UserID, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, organizers)
+}
+
+func (eh *EBHandlers) RemoveEventOrganizer(ctx *gin.Context) {
+	// логируем действия организаторов и админов
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q removing event organizer", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+	rawUserID, ok := ctx.Params.Get("userId")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty user id"})
+		return
+	}
+
+	if err := eh.svc.RemoveEventOrganizer(ctx.Request.Context(), stringToInt(rawID), stringToInt(rawUserID), actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,50 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) CreateSeries(ctx *gin.Context) {
+	// логируем действия организаторов и админов
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q creating event series", rid, uid, mail, role)
+
+	// обычный флоу
+	var series model.EventSeries
+	if err := ctx.ShouldBindJSON(&series); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid event series payload"})
+		return
+	}
+
+	res, err := eh.svc.CreateSeries(ctx.Request.Context(), &series, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, res)
+}
+
+func (eh *EBHandlers) GetSeries(ctx *gin.Context) {
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event series id"})
+		return
+	}
+
+	res, err := eh.svc.GetSeriesInfo(ctx.Request.Context(), stringToInt(rawID), actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
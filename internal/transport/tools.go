@@ -19,22 +19,89 @@ func errorCodeDefiner(err error) int {
 		errors.Is(err, model.ErrIncorrectEventTime),
 		errors.Is(err, model.ErrEmptyEventInfo),
 		errors.Is(err, model.ErrEmptyBookInfo),
-		errors.Is(err, model.ErrEmptyEmail):
+		errors.Is(err, model.ErrEmptyEmail),
+		errors.Is(err, model.ErrEmptyEventUpdate),
+		errors.Is(err, model.ErrEmptyCancelReason),
+		errors.Is(err, model.ErrIncorrectQuantity),
+		errors.Is(err, model.ErrTooManySeatsPerBook),
+		errors.Is(err, model.ErrInvalidInvite),
+		errors.Is(err, model.ErrIncorrectInviteTTL),
+		errors.Is(err, model.ErrIncorrectPagination),
+		errors.Is(err, model.ErrIncorrectCursor),
+		errors.Is(err, model.ErrIncorrectListFilter),
+		errors.Is(err, model.ErrIncorrectSearch),
+		errors.Is(err, model.ErrIncorrectEventMeta),
+		errors.Is(err, model.ErrIncorrectCategory),
+		errors.Is(err, model.ErrIncorrectTimeZone),
+		errors.Is(err, model.ErrIncorrectRRule),
+		errors.Is(err, model.ErrIncorrectScope),
+		errors.Is(err, model.ErrSeriesTimeUpdate),
+		errors.Is(err, model.ErrInvalidUserToken),
+		errors.Is(err, model.ErrEmptyPassword),
+		errors.Is(err, model.ErrPasswordTooLong),
+		errors.Is(err, model.ErrInvalidMFACode),
+		errors.Is(err, model.ErrIncorrectAPIKey),
+		errors.Is(err, model.ErrIncorrectAPIKeyTTL),
+		errors.Is(err, model.ErrOIDCEmailMissing),
+		errors.Is(err, model.ErrIncorrectOrg):
 		return 400
-	case errors.Is(err, model.ErrAccessDenied):
+	case errors.Is(err, model.ErrInvalidRefreshToken),
+		errors.Is(err, model.ErrSessionRevoked),
+		errors.Is(err, model.ErrInvalidMFAToken),
+		errors.Is(err, model.ErrInvalidAPIKey),
+		errors.Is(err, model.ErrInvalidOIDCState),
+		errors.Is(err, model.ErrOIDCLoginFailed):
+		return 401
+	case errors.Is(err, model.ErrAccessDenied),
+		errors.Is(err, model.ErrUserDisabled),
+		errors.Is(err, model.ErrEmailNotVerified),
+		errors.Is(err, model.ErrMFARequired),
+		errors.Is(err, model.ErrScopeDenied),
+		errors.Is(err, model.ErrNoOrgMembership):
 		return 403
 	case errors.Is(err, model.ErrUserNotFound),
 		errors.Is(err, model.ErrBookNotFound),
-		errors.Is(err, model.ErrEventNotFound):
+		errors.Is(err, model.ErrEventNotFound),
+		errors.Is(err, model.ErrNotWaitlisted),
+		errors.Is(err, model.ErrSessionNotFound),
+		errors.Is(err, model.ErrInviteNotFound),
+		errors.Is(err, model.ErrUserTokenNotFound),
+		errors.Is(err, model.ErrAPIKeyNotFound),
+		errors.Is(err, model.ErrOIDCDisabled),
+		errors.Is(err, model.ErrOrganizerNotFound),
+		errors.Is(err, model.ErrOrgNotFound),
+		errors.Is(err, model.ErrNotOrgMember),
+		errors.Is(err, model.ErrCategoryNotFound),
+		errors.Is(err, model.ErrSeriesNotFound):
 		return 404
 	case errors.Is(err, model.ErrBookIsConfirmed),
 		errors.Is(err, model.ErrNoSeatsAvailable),
 		errors.Is(err, model.ErrExpiredEvent),
+		errors.Is(err, model.ErrBookingClosed),
+		errors.Is(err, model.ErrNotInSeries),
 		errors.Is(err, model.ErrExpiredBook),
 		errors.Is(err, model.ErrBookIsCancelled),
 		errors.Is(err, model.ErrEventBusy),
-		errors.Is(err, model.ErrUserAlreadyExists):
+		errors.Is(err, model.ErrUserAlreadyExists),
+		errors.Is(err, model.ErrEventNotEditable),
+		errors.Is(err, model.ErrSeatsBelowBooked),
+		errors.Is(err, model.ErrEventIsCancelled),
+		errors.Is(err, model.ErrAlreadyWaitlisted),
+		errors.Is(err, model.ErrSeatsAvailable),
+		errors.Is(err, model.ErrInviteExists),
+		errors.Is(err, model.ErrSelfModification),
+		errors.Is(err, model.ErrMFAAlreadyEnabled),
+		errors.Is(err, model.ErrMFANotSetUp),
+		errors.Is(err, model.ErrOIDCEmailNotVerified),
+		errors.Is(err, model.ErrAlreadyOrganizer),
+		errors.Is(err, model.ErrNotOrganizerRole),
+		errors.Is(err, model.ErrOrgSlugTaken),
+		errors.Is(err, model.ErrAlreadyOrgMember),
+		errors.Is(err, model.ErrUserInOtherOrgs),
+		errors.Is(err, model.ErrCategoryExists):
 		return 409
+	case errors.Is(err, model.ErrTooManyAttempts):
+		return 429
 	default:
 		return 500
 	}
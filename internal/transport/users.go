@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/UnendingLoop/EventBooker/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) GetUsers(ctx *gin.Context) {
+	filter := model.UserFilter{
+		Query:  ctx.Query("q"),
+		Limit:  queryInt(ctx, "limit"),
+		Offset: queryInt(ctx, "offset"),
+	}
+
+	users, total, err := eh.svc.GetUsersList(ctx.Request.Context(), &filter, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := usersListResponse{Users: make([]userDetails, 0, len(users)), Total: total, Limit: filter.Limit, Offset: filter.Offset}
+	for _, u := range users {
+		resp.Users = append(resp.Users, convertUserToDetails(u))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (eh *EBHandlers) GetUser(ctx *gin.Context) {
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty user id"})
+		return
+	}
+
+	info, err := eh.svc.GetUserInfo(ctx.Request.Context(), stringToInt(rawID), actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userInfoResponse{User: convertUserToDetails(info.User), Books: info.Books})
+}
+
+func (eh *EBHandlers) ChangeUserRole(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q changing user role", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty user id"})
+		return
+	}
+
+	var req userRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid role payload"})
+		return
+	}
+
+	user, err := eh.svc.ChangeUserRole(ctx.Request.Context(), stringToInt(rawID), req.Role, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convertUserToDetails(user))
+}
+
+func (eh *EBHandlers) DisableUser(ctx *gin.Context) {
+	eh.setUserDisabled(ctx, true)
+}
+
+func (eh *EBHandlers) EnableUser(ctx *gin.Context) {
+	eh.setUserDisabled(ctx, false)
+}
+
+func (eh *EBHandlers) setUserDisabled(ctx *gin.Context, disabled bool) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q setting user disabled=%t", rid, uid, mail, role, disabled)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty user id"})
+		return
+	}
+
+	user, err := eh.svc.SetUserDisabled(ctx.Request.Context(), stringToInt(rawID), disabled, actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convertUserToDetails(user))
+}
+
+func (eh *EBHandlers) DeleteUser(ctx *gin.Context) {
+	// логируем админовые ивенты
+	rid := stringFromCtx(ctx, "request_id")
+	uid := intFromCtx(ctx, "user_id")
+	mail := stringFromCtx(ctx, "email")
+	role := stringFromCtx(ctx, "role")
+
+	log.Printf("rid=%q userID=%d userEmail=%q role=%q deleting user", rid, uid, mail, role)
+
+	// обычный флоу
+	rawID, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty user id"})
+		return
+	}
+
+	if err := eh.svc.DeleteUser(ctx.Request.Context(), stringToInt(rawID), actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (eh *EBHandlers) JoinWaitlist(ctx *gin.Context) {
+	eid, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	// тело необязательно - без него встаем в очередь на одно место
+	var req waitlistRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("Failed to parse JSON: %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid waitlist payload"})
+		return
+	}
+
+	entry, err := eh.svc.JoinWaitlist(ctx.Request.Context(), stringToInt(eid), actorFromCtx(ctx), req.Quantity)
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, entry)
+}
+
+func (eh *EBHandlers) GetWaitlistPosition(ctx *gin.Context) {
+	eid, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	entry, err := eh.svc.GetWaitlistPosition(ctx.Request.Context(), stringToInt(eid), actorFromCtx(ctx))
+	if err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entry)
+}
+
+func (eh *EBHandlers) LeaveWaitlist(ctx *gin.Context) {
+	eid, ok := ctx.Params.Get("id")
+	if !ok {
+		ctx.JSON(400, gin.H{"error": "empty event id"})
+		return
+	}
+
+	if err := eh.svc.LeaveWaitlist(ctx.Request.Context(), stringToInt(eid), actorFromCtx(ctx)); err != nil {
+		ctx.JSON(errorCodeDefiner(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}